@@ -6,9 +6,32 @@ import (
 )
 
 type DSNConfig struct {
-	Driver            string `json:"driver"`
-	ExportDatabaseDSN string `json:"export_database_dsn"`
-	ImportDatabaseDSN string `json:"import_database_dsn"`
+	Driver            string `json:"driver" yaml:"driver"`
+	ExportDatabaseDSN string `json:"export_database_dsn" yaml:"export_database_dsn"`
+	ImportDatabaseDSN string `json:"import_database_dsn" yaml:"import_database_dsn"`
+
+	// Export and Import describe the databases field by field when the DSN
+	// strings above are not set.
+	Export DatabaseConfig `json:"export" yaml:"export"`
+	Import DatabaseConfig `json:"import" yaml:"import"`
+
+	// Include and Exclude select tables by glob, or regular expression with
+	// the re: prefix, in addition to the --include and --exclude flags.
+	Include []string `json:"include,omitempty" yaml:"include"`
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude"`
+
+	// Tables holds per-table settings, keyed by table name.
+	Tables map[string]TableConfig `json:"tables,omitempty" yaml:"tables"`
+}
+
+// TableConfig overrides command flags for a single table.
+type TableConfig struct {
+	// Skip leaves the table out of exports and imports.
+	Skip bool `json:"skip,omitempty" yaml:"skip"`
+	// BatchSize overrides --batch-size when exporting the table.
+	BatchSize int `json:"batch_size,omitempty" yaml:"batch_size"`
+	// IfExists overrides --if-exists when importing the table.
+	IfExists string `json:"if_exists,omitempty" yaml:"if_exists"`
 }
 
 // LoadDSNConfig loads DSNConfig from the specified JSON file.
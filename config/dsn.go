@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DatabaseConfig describes a database with structured fields, as an
+// alternative to a full DSN string. The password is taken from the first of
+// Password, PasswordEnv, PasswordFile, and ~/.pgpass (PostgreSQL) or
+// ~/.my.cnf (MySQL) that provides one.
+type DatabaseConfig struct {
+	Host         string            `json:"host,omitempty" yaml:"host"`
+	Port         int               `json:"port,omitempty" yaml:"port"`
+	User         string            `json:"user,omitempty" yaml:"user"`
+	Password     string            `json:"password,omitempty" yaml:"password"`
+	PasswordEnv  string            `json:"password_env,omitempty" yaml:"password_env"`
+	PasswordFile string            `json:"password_file,omitempty" yaml:"password_file"`
+	Database     string            `json:"database,omitempty" yaml:"database"`
+	SSLMode      string            `json:"sslmode,omitempty" yaml:"sslmode"`
+	Options      map[string]string `json:"options,omitempty" yaml:"options"`
+}
+
+// IsZero reports whether no field is set.
+func (d DatabaseConfig) IsZero() bool {
+	return d.Host == "" && d.Port == 0 && d.User == "" && d.Password == "" && d.PasswordEnv == "" &&
+		d.PasswordFile == "" && d.Database == "" && d.SSLMode == "" && len(d.Options) == 0
+}
+
+// ExportDSN returns the DSN of the export database: export_database_dsn, or
+// the DSN assembled from the export fields.
+func (c *DSNConfig) ExportDSN() (string, error) {
+	return c.dsn("export", c.ExportDatabaseDSN, c.Export)
+}
+
+// ImportDSN returns the DSN of the import database: import_database_dsn, or
+// the DSN assembled from the import fields.
+func (c *DSNConfig) ImportDSN() (string, error) {
+	return c.dsn("import", c.ImportDatabaseDSN, c.Import)
+}
+
+func (c *DSNConfig) dsn(name, raw string, db DatabaseConfig) (string, error) {
+	switch {
+	case raw != "" && !db.IsZero():
+		return "", fmt.Errorf("both %s_database_dsn and %s fields are set, use one of them", name, name)
+	case raw != "":
+		return raw, nil
+	case db.IsZero():
+		return "", fmt.Errorf("no %s database configured (set %s_database_dsn or %s)", name, name, name)
+	}
+	dsn, err := BuildDSN(c.Driver, db)
+	if err != nil {
+		return "", fmt.Errorf("%s database: %w", name, err)
+	}
+	return dsn, nil
+}
+
+// BuildDSN assembles the driver-specific DSN of db.
+func BuildDSN(driver string, db DatabaseConfig) (string, error) {
+	switch driver {
+	case "postgres":
+		password, err := db.password(driver)
+		if err != nil {
+			return "", err
+		}
+		host := db.Host
+		if host == "" {
+			host = "localhost"
+		}
+		port := db.Port
+		if port == 0 {
+			port = 5432
+		}
+		u := url.URL{Scheme: "postgres", Host: net.JoinHostPort(host, strconv.Itoa(port)), Path: "/" + db.Database}
+		if password != "" {
+			u.User = url.UserPassword(db.User, password)
+		} else if db.User != "" {
+			u.User = url.User(db.User)
+		}
+		query := url.Values{}
+		for key, value := range db.Options {
+			query.Set(key, value)
+		}
+		if db.SSLMode != "" {
+			query.Set("sslmode", db.SSLMode)
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	case "mysql":
+		password, err := db.password(driver)
+		if err != nil {
+			return "", err
+		}
+		host := db.Host
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		port := db.Port
+		if port == 0 {
+			port = 3306
+		}
+		cfg := mysql.NewConfig()
+		cfg.User = db.User
+		cfg.Passwd = password
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+		cfg.DBName = db.Database
+		cfg.Params = make(map[string]string)
+		for key, value := range db.Options {
+			cfg.Params[key] = value
+		}
+		if db.SSLMode != "" {
+			tls, ok := mysqlTLSModes[db.SSLMode]
+			if !ok {
+				return "", fmt.Errorf("unsupported sslmode %q for mysql", db.SSLMode)
+			}
+			cfg.Params["tls"] = tls
+		}
+		return cfg.FormatDSN(), nil
+	case "sqlite", "duckdb":
+		if db.Database == "" {
+			return "", fmt.Errorf("database (the %s file) is required", driver)
+		}
+		if len(db.Options) == 0 {
+			return db.Database, nil
+		}
+		query := url.Values{}
+		for key, value := range db.Options {
+			query.Set(key, value)
+		}
+		return db.Database + "?" + query.Encode(), nil
+	default:
+		return "", fmt.Errorf("unsupported driver: %s", driver)
+	}
+}
+
+// mysqlTLSModes maps PostgreSQL-style sslmode values to the tls parameter of
+// the MySQL driver.
+var mysqlTLSModes = map[string]string{
+	"disable":     "false",
+	"prefer":      "preferred",
+	"require":     "skip-verify",
+	"verify-ca":   "true",
+	"verify-full": "true",
+}
+
+// password resolves the password of db, returning "" when none is configured.
+func (d DatabaseConfig) password(driver string) (string, error) {
+	if d.Password != "" {
+		return d.Password, nil
+	}
+	if d.PasswordEnv != "" {
+		value, ok := os.LookupEnv(d.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("password environment variable %s is not set", d.PasswordEnv)
+		}
+		return value, nil
+	}
+	if d.PasswordFile != "" {
+		content, err := os.ReadFile(d.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	switch driver {
+	case "postgres":
+		return pgpassPassword(d)
+	case "mysql":
+		return myCnfPassword()
+	}
+	return "", nil
+}
+
+// pgpassPassword looks up db in $PGPASSFILE or ~/.pgpass, whose lines are
+// hostname:port:database:username:password with * matching anything.
+func pgpassPassword(db DatabaseConfig) (string, error) {
+	path := os.Getenv("PGPASSFILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		path = filepath.Join(home, ".pgpass")
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	host := db.Host
+	if host == "" {
+		host = "localhost"
+	}
+	port := db.Port
+	if port == 0 {
+		port = 5432
+	}
+	want := []string{host, strconv.Itoa(port), db.Database, db.User}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPgpassLine(line)
+		if len(fields) != 5 {
+			continue
+		}
+		matches := true
+		for i, value := range want {
+			if fields[i] != "*" && fields[i] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return fields[4], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// splitPgpassLine splits a .pgpass line on unescaped colons.
+func splitPgpassLine(line string) []string {
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case line[i] == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(line[i])
+		}
+	}
+	return append(fields, field.String())
+}
+
+// myCnfPassword reads the password of the [client] section of ~/.my.cnf.
+func myCnfPassword() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nil
+	}
+	file, err := os.Open(filepath.Join(home, ".my.cnf"))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if section != "client" || !ok || strings.TrimSpace(key) != "password" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value, nil
+	}
+	return "", scanner.Err()
+}
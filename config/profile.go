@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the YAML config looked up when no file is given.
+const DefaultConfigFile = "sql-migration.yaml"
+
+// profileFile is the layout of the YAML config: settings shared by every
+// profile, and named profiles overriding them.
+type profileFile struct {
+	DefaultProfile string               `yaml:"default_profile"`
+	Defaults       yaml.Node            `yaml:"defaults"`
+	Profiles       map[string]yaml.Node `yaml:"profiles"`
+}
+
+// envPattern matches ${VAR} and ${VAR:-fallback}.
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// LoadProfileConfig loads profile from a YAML config file. The profile's
+// settings override the defaults; per-table settings are merged field by
+// field. An empty profile selects default_profile, or the defaults alone.
+// ${VAR} references in the defaults and the selected profile are replaced by
+// environment variables, failing when a variable is unset and has no
+// ${VAR:-fallback}.
+func LoadProfileConfig(filename, profile string) (*DSNConfig, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var file profileFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	if profile == "" {
+		profile = file.DefaultProfile
+	}
+
+	var config DSNConfig
+	if !file.Defaults.IsZero() {
+		if err := interpolateEnv(&file.Defaults); err != nil {
+			return nil, err
+		}
+		if err := file.Defaults.Decode(&config); err != nil {
+			return nil, fmt.Errorf("invalid defaults in %s: %w", filename, err)
+		}
+	}
+	if profile == "" {
+		return &config, nil
+	}
+
+	node, ok := file.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for name := range file.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q not found in %s (available: %s)", profile, filename, strings.Join(names, ", "))
+	}
+	if err := interpolateEnv(&node); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profile, err)
+	}
+
+	// Decoding replaces whole table entries, so keep the defaults and decode
+	// each of the profile's tables on top of them afterwards.
+	defaultTables := make(map[string]TableConfig, len(config.Tables))
+	for name, table := range config.Tables {
+		defaultTables[name] = table
+	}
+	if err := node.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid profile %s in %s: %w", profile, filename, err)
+	}
+	var overrides struct {
+		Tables map[string]yaml.Node `yaml:"tables"`
+	}
+	if err := node.Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("invalid profile %s in %s: %w", profile, filename, err)
+	}
+	for name, tableNode := range overrides.Tables {
+		table := defaultTables[name]
+		if err := tableNode.Decode(&table); err != nil {
+			return nil, fmt.Errorf("invalid settings for table %s in profile %s: %w", name, profile, err)
+		}
+		config.Tables[name] = table
+	}
+	return &config, nil
+}
+
+// interpolateEnv replaces ${VAR} references in the scalar values of node.
+func interpolateEnv(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var missing []string
+		node.Value = envPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			match := envPattern.FindStringSubmatch(ref)
+			// As in the shell, the fallback also replaces an empty value
+			if value, ok := os.LookupEnv(match[1]); ok && (value != "" || match[2] == "") {
+				return value
+			}
+			if match[2] != "" {
+				return match[3]
+			}
+			missing = append(missing, match[1])
+			return ref
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("environment variable %s is not set (line %d)", strings.Join(missing, ", "), node.Line)
+		}
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := interpolateEnv(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfig writes content to a YAML config file and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const profileYAML = `
+default_profile: staging
+defaults:
+  driver: postgres
+  export_database_dsn: postgres://${SQL_MIGRATION_TEST_HOST:-localhost}/shop
+  tables:
+    orders:
+      batch_size: 500
+      if_exists: skip
+    audit_log:
+      skip: true
+profiles:
+  staging:
+    import_database_dsn: postgres://${SQL_MIGRATION_TEST_USER}@staging/shop
+  production:
+    driver: mysql
+    import_database_dsn: app@tcp(prod)/shop
+    exclude: [tmp_*]
+    tables:
+      orders:
+        if_exists: truncate
+      items:
+        batch_size: 10
+`
+
+func TestLoadProfileConfig(t *testing.T) {
+	path := writeConfig(t, profileYAML)
+	t.Setenv("SQL_MIGRATION_TEST_USER", "deploy")
+
+	t.Run("default profile", func(t *testing.T) {
+		config, err := LoadProfileConfig(path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.ImportDatabaseDSN != "postgres://deploy@staging/shop" {
+			t.Errorf("import DSN = %q, want the staging one", config.ImportDatabaseDSN)
+		}
+		if config.ExportDatabaseDSN != "postgres://localhost/shop" {
+			t.Errorf("export DSN = %q, want the fallback host", config.ExportDatabaseDSN)
+		}
+		if config.Driver != "postgres" {
+			t.Errorf("driver = %q, want the default postgres", config.Driver)
+		}
+	})
+
+	t.Run("per-table merge", func(t *testing.T) {
+		t.Setenv("SQL_MIGRATION_TEST_HOST", "replica")
+		config, err := LoadProfileConfig(path, "production")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Driver != "mysql" || config.ExportDatabaseDSN != "postgres://replica/shop" {
+			t.Errorf("driver %q, export DSN %q, want mysql and the replica host", config.Driver, config.ExportDatabaseDSN)
+		}
+		if !reflect.DeepEqual(config.Exclude, []string{"tmp_*"}) {
+			t.Errorf("exclude = %q", config.Exclude)
+		}
+		want := map[string]TableConfig{
+			"orders":    {BatchSize: 500, IfExists: "truncate"},
+			"audit_log": {Skip: true},
+			"items":     {BatchSize: 10},
+		}
+		if !reflect.DeepEqual(config.Tables, want) {
+			t.Errorf("tables = %+v, want %+v", config.Tables, want)
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, err := LoadProfileConfig(path, "qa")
+		if err == nil || !strings.Contains(err.Error(), "available: production, staging") {
+			t.Errorf("error = %v, want the available profiles listed", err)
+		}
+	})
+
+	t.Run("unset variable", func(t *testing.T) {
+		os.Unsetenv("SQL_MIGRATION_TEST_USER")
+		_, err := LoadProfileConfig(path, "staging")
+		if err == nil || !strings.Contains(err.Error(), "SQL_MIGRATION_TEST_USER is not set") {
+			t.Errorf("error = %v, want the unset variable reported", err)
+		}
+	})
+
+	t.Run("unused profiles are not interpolated", func(t *testing.T) {
+		os.Unsetenv("SQL_MIGRATION_TEST_USER")
+		if _, err := LoadProfileConfig(path, "production"); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestLoadProfileConfigDefaultsOnly(t *testing.T) {
+	path := writeConfig(t, "defaults:\n  driver: sqlite\nprofiles:\n  other:\n    driver: mysql\n")
+	config, err := LoadProfileConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Driver != "sqlite" {
+		t.Errorf("driver = %q, want the defaults alone", config.Driver)
+	}
+}
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Setenv("SQL_MIGRATION_TEST_HOST", "db.example")
+	t.Setenv("SQL_MIGRATION_TEST_EMPTY", "")
+	tests := []struct {
+		value, want string
+	}{
+		{"${SQL_MIGRATION_TEST_HOST}", "db.example"},
+		{"host=${SQL_MIGRATION_TEST_HOST}:${SQL_MIGRATION_TEST_PORT:-5432}", "host=db.example:5432"},
+		{"${SQL_MIGRATION_TEST_HOST:-fallback}", "db.example"},
+		{"${SQL_MIGRATION_TEST_EMPTY:-fallback}", "fallback"},
+		{"${SQL_MIGRATION_TEST_EMPTY}", ""},
+		{"${SQL_MIGRATION_TEST_UNSET:-}", ""},
+		{"$SQL_MIGRATION_TEST_HOST", "$SQL_MIGRATION_TEST_HOST"},
+	}
+	for _, tt := range tests {
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: tt.value}
+		if err := interpolateEnv(node); err != nil {
+			t.Errorf("interpolateEnv(%q): %v", tt.value, err)
+			continue
+		}
+		if node.Value != tt.want {
+			t.Errorf("interpolateEnv(%q) = %q, want %q", tt.value, node.Value, tt.want)
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("a:\n  - ok\n  - ${SQL_MIGRATION_TEST_UNSET}${SQL_MIGRATION_TEST_OTHER}\n"), &doc); err != nil {
+		t.Fatal(err)
+	}
+	err := interpolateEnv(&doc)
+	if err == nil || !strings.Contains(err.Error(), "SQL_MIGRATION_TEST_UNSET, SQL_MIGRATION_TEST_OTHER is not set (line 3)") {
+		t.Errorf("error = %v, want both unset variables and their line", err)
+	}
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqliteAttachPrefix marks the SQLite DSN parameters naming databases to
+// attach to every connection: attach.<alias>=<path>.
+const sqliteAttachPrefix = "attach."
+
+var sqliteAliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sqliteAttachDrivers caches the drivers registered per set of attachments,
+// as database/sql drivers cannot be registered twice.
+var (
+	sqliteAttachMu      sync.Mutex
+	sqliteAttachDrivers = map[string]string{}
+)
+
+type sqliteAttachment struct {
+	alias string
+	path  string
+}
+
+// sqliteDialector opens dsn with the databases of its attach.<alias>
+// parameters attached under their alias, so their tables are reachable as
+// alias.table. The parameters are removed from the DSN passed to the driver.
+func sqliteDialector(dsn string) (gorm.Dialector, error) {
+	path, rawQuery, found := strings.Cut(dsn, "?")
+	if !found {
+		return sqlite.Open(dsn), nil
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQLite DSN parameters: %w", err)
+	}
+
+	var attachments []sqliteAttachment
+	for key, values := range query {
+		alias, ok := strings.CutPrefix(key, sqliteAttachPrefix)
+		if !ok {
+			continue
+		}
+		if !sqliteAliasPattern.MatchString(alias) || alias == "main" || alias == "temp" {
+			return nil, fmt.Errorf("invalid SQLite attach alias %q", alias)
+		}
+		attachments = append(attachments, sqliteAttachment{alias: alias, path: values[len(values)-1]})
+		query.Del(key)
+	}
+	if len(attachments) == 0 {
+		return sqlite.Open(dsn), nil
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].alias < attachments[j].alias })
+
+	if encoded := query.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+	return sqlite.Dialector{DriverName: sqliteAttachDriver(attachments), DSN: path}, nil
+}
+
+// sqliteAttachDriver returns the name of a go-sqlite3 driver attaching
+// attachments to each new connection, registering it on first use.
+func sqliteAttachDriver(attachments []sqliteAttachment) string {
+	var key strings.Builder
+	for _, a := range attachments {
+		fmt.Fprintf(&key, "%s=%s;", a.alias, a.path)
+	}
+
+	sqliteAttachMu.Lock()
+	defer sqliteAttachMu.Unlock()
+	if name, ok := sqliteAttachDrivers[key.String()]; ok {
+		return name
+	}
+	name := fmt.Sprintf("sqlite3_attach_%d", len(sqliteAttachDrivers))
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			for _, a := range attachments {
+				if _, err := conn.Exec("ATTACH DATABASE ? AS "+StandardSQL{}.QuoteIdentifier(a.alias), []driver.Value{a.path}); err != nil {
+					return fmt.Errorf("failed to attach %s as %s: %w", a.path, a.alias, err)
+				}
+			}
+			return nil
+		},
+	})
+	sqliteAttachDrivers[key.String()] = name
+	return name
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint records how far an import or export got so an interrupted run can
+// resume where it stopped instead of starting over.
+type Checkpoint struct {
+	path   string
+	Tables map[string]*TableCheckpoint `json:"tables"`
+}
+
+// TableCheckpoint is the committed progress of a single table.
+type TableCheckpoint struct {
+	SchemaDone bool `json:"schema_done,omitempty"`
+	DataDone   bool `json:"data_done,omitempty"`
+	// Statements is the number of statements of the data file already committed.
+	Statements int `json:"statements,omitempty"`
+
+	// Export progress: the primary key of the last row written, the number of
+	// rows written and the size of the data file once they were flushed.
+	LastKey []string `json:"last_key,omitempty"`
+	Rows    int64    `json:"rows,omitempty"`
+	Offset  int64    `json:"offset,omitempty"`
+}
+
+// NewCheckpoint returns an empty checkpoint that will be saved to path.
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{path: path, Tables: make(map[string]*TableCheckpoint)}
+}
+
+// LoadCheckpoint reads the checkpoint saved at path. A missing file yields an
+// empty checkpoint.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	cp := NewCheckpoint(path)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %w", path, err)
+	}
+	if cp.Tables == nil {
+		cp.Tables = make(map[string]*TableCheckpoint)
+	}
+	return cp, nil
+}
+
+// Table returns the progress of tableName, creating an empty entry if needed.
+func (c *Checkpoint) Table(tableName string) *TableCheckpoint {
+	tc, ok := c.Tables[tableName]
+	if !ok {
+		tc = &TableCheckpoint{}
+		c.Tables[tableName] = tc
+	}
+	return tc
+}
+
+// Save writes the checkpoint atomically, so a crash while saving never leaves
+// a truncated file behind.
+func (c *Checkpoint) Save() error {
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Remove deletes the checkpoint file once the work it tracks is complete.
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Path returns the file the checkpoint is saved to.
+func (c *Checkpoint) Path() string {
+	return c.path
+}
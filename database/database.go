@@ -6,9 +6,6 @@ import (
 	"os"
 	"time"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/postgres"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/plugin/opentelemetry/tracing"
@@ -48,85 +45,22 @@ func ReturnSession(app_name, db_type, dsn string) (*gorm.DB, error) {
 		},
 	)
 
-	var DBSession *gorm.DB
-
-	switch db_type {
-	case "postgres":
-		db, err := gorm.Open(postgres.New(postgres.Config{
-			DSN:                  dsn,
-			PreferSimpleProtocol: true, // disables implicit prepared statement usage,
-
-		}), &gorm.Config{
-			DisableForeignKeyConstraintWhenMigrating: true,
-			Logger:                                   newLogger,
-			SkipDefaultTransaction:                   true,
-		})
-		if err != nil {
-			panic(err)
-		}
-
-		sqlDB, err := db.DB()
-		if err != nil {
-			fmt.Printf("Error during connecting to database: %v\n", err)
-			return nil, err
-		}
-		sqlDB.SetMaxOpenConns(100)
-		sqlDB.SetMaxIdleConns(20)
-		sqlDB.SetConnMaxLifetime(30 * time.Minute)
-
-		DBSession = db
-	case "sqlite":
-		//  this is sqlite connection
-		db, _ := gorm.Open(sqlite.Open(dsn), &gorm.Config{
-
-			DisableForeignKeyConstraintWhenMigrating: true,
-			Logger:                                   newLogger,
-			SkipDefaultTransaction:                   true,
-		})
-
-		sqlDB, err := db.DB()
-		if err != nil {
-			fmt.Printf("Error during connecting to database: %v\n", err)
-			return nil, err
-		}
-		sqlDB.SetMaxOpenConns(10)
-		sqlDB.SetConnMaxLifetime(5 * time.Second)
-		DBSession = db
-	case "mysql":
-		db, err := gorm.Open(mysql.New(mysql.Config{
-			DSN:                       dsn,   // data source name
-			DefaultStringSize:         256,   // default size for string fields
-			DisableDatetimePrecision:  true,  // disable datetime precision, which not supported before MySQL 5.6
-			DontSupportRenameIndex:    true,  // drop & create when rename index, rename index not supported before MySQL 5.7, MariaDB
-			DontSupportRenameColumn:   true,  //  when rename column, rename column not supported before MySQL 8, MariaDB
-			SkipInitializeWithVersion: false, // auto configure based on currently MySQL version
-		}), &gorm.Config{
-			DisableForeignKeyConstraintWhenMigrating: true,
-			Logger:                                   newLogger,
-			SkipDefaultTransaction:                   true,
-		})
-		if err != nil {
-			fmt.Printf("Error during connecting to database: %v\n", err)
-			return nil, err
-		}
-
-		sqlDB, err := db.DB()
-		if err != nil {
-			fmt.Printf("Error during connecting to database: %v\n", err)
-			return nil, err
-		}
-		sqlDB.SetMaxOpenConns(100)
-		sqlDB.SetMaxIdleConns(20)
-		sqlDB.SetConnMaxLifetime(30 * time.Minute)
-		DBSession = db
-	default:
-		return nil, fmt.Errorf("database type not supported")
-
+	dialect, err := LookupDialect(db_type)
+	if err != nil {
+		return nil, err
+	}
+	DBSession, err := dialect.Open(dsn, &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+		Logger:                                   newLogger,
+		SkipDefaultTransaction:                   true,
+	})
+	if err != nil {
+		fmt.Printf("Error during connecting to database: %v\n", err)
+		return nil, err
 	}
 
 	// Mouting Otel tracer plugin on gorm Session
-	err := DBSession.Use(tracing.NewPlugin())
-	if err != nil {
+	if err := DBSession.Use(tracing.NewPlugin()); err != nil {
 		fmt.Printf("Error during connecting to otel plugin: %v\n", err)
 
 	}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DataDiff counts the statements written to reconcile a table.
+type DataDiff struct {
+	Table   string
+	Inserts int
+	Updates int
+	Deletes int
+	// Chunks is the number of key ranges whose checksums differed.
+	Chunks int
+}
+
+// Empty reports whether the table holds the same rows on both sides.
+func (d *DataDiff) Empty() bool {
+	return d.Inserts == 0 && d.Updates == 0 && d.Deletes == 0
+}
+
+// keyedRow is a row of a chunk, indexed by its primary key.
+type keyedRow struct {
+	cols   []string
+	types  []string
+	values []any
+}
+
+// literal renders the value of the i-th column as a literal of d.
+func (r keyedRow) literal(d Dialect, i int) string {
+	return d.Literal(r.values[i], r.types[i])
+}
+
+// value returns the value of column col and whether the row has it.
+func (r keyedRow) value(col string) (any, bool) {
+	for i, c := range r.cols {
+		if strings.EqualFold(c, col) {
+			return r.values[i], true
+		}
+	}
+	return nil, false
+}
+
+func loadKeyedRows(db *gorm.DB, tableName string, keys []string, r KeyRange) ([]string, map[string]keyedRow, error) {
+	var order []string
+	rows := make(map[string]keyedRow)
+	err := scanTypedKeyRange(db, tableName, keys, r, func(cols, types []string, values []any) error {
+		keyParts := make([]string, 0, len(keys))
+		for _, idx := range columnIndexes(cols, keys) {
+			keyParts = append(keyParts, NormalizeValue(values[idx]))
+		}
+		key := strings.Join(keyParts, "\x1f")
+		order = append(order, key)
+		rows[key] = keyedRow{cols: cols, types: types, values: values}
+		return nil
+	})
+	return order, rows, err
+}
+
+// keyWhere renders the WHERE clause matching row by its primary key.
+func keyWhere(d Dialect, keys []string, row keyedRow) string {
+	var conds []string
+	for _, k := range keys {
+		for i, c := range row.cols {
+			if strings.EqualFold(c, k) {
+				conds = append(conds, fmt.Sprintf("%s = %s", d.QuoteIdentifier(c), row.literal(d, i)))
+			}
+		}
+	}
+	return strings.Join(conds, " AND ")
+}
+
+func isKeyColumn(keys []string, col string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffTableData finds the rows of tableName that differ between source and
+// target, matched by primary key, and writes the INSERT and UPDATE statements
+// that make the target match the source to upserts, and the DELETE statements
+// to deletes, so that a patch covering several tables can insert into
+// referenced tables first and delete from them last. Both sides are first
+// compared by chunk checksums, so only the rows of differing chunks are read
+// in full. Updates only set the columns whose values differ. Names and values
+// are written for the target.
+func DiffTableData(source, target *gorm.DB, tableName string, chunkSize int, upserts, deletes io.Writer) (*DataDiff, error) {
+	keys, err := PrimaryKeyColumns(source, tableName)
+	if err != nil {
+		return nil, err
+	}
+	d, err := DialectOf(target)
+	if err != nil {
+		return nil, err
+	}
+	table := quoteTableName(d, tableName)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key to match rows by", tableName)
+	}
+
+	result := &DataDiff{Table: tableName}
+	err = ChecksumChunks(source, tableName, keys, chunkSize, func(src ChunkChecksum) error {
+		dst, err := ChecksumKeyRange(target, tableName, keys, src.Range)
+		if err != nil {
+			return err
+		}
+		if src.Rows == dst.Rows && src.Checksum == dst.Checksum {
+			return nil
+		}
+		result.Chunks++
+
+		sourceOrder, sourceRows, err := loadKeyedRows(source, tableName, keys, src.Range)
+		if err != nil {
+			return err
+		}
+		targetOrder, targetRows, err := loadKeyedRows(target, tableName, keys, src.Range)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range targetOrder {
+			if _, ok := sourceRows[key]; !ok {
+				row := targetRows[key]
+				fmt.Fprintf(deletes, "DELETE FROM %s WHERE %s;\n", table, keyWhere(d, keys, row))
+				result.Deletes++
+			}
+		}
+		for _, key := range sourceOrder {
+			row := sourceRows[key]
+			other, ok := targetRows[key]
+			switch {
+			case !ok:
+				values := make([]string, len(row.values))
+				for i := range row.values {
+					values[i] = row.literal(d, i)
+				}
+				fmt.Fprintf(upserts, "INSERT INTO %s (%s) VALUES (%s);\n", table, quoteNames(d, row.cols), strings.Join(values, ", "))
+				result.Inserts++
+			default:
+				// Only columns present on both sides are compared and set
+				var sets []string
+				for i, c := range row.cols {
+					if isKeyColumn(keys, c) {
+						continue
+					}
+					if v, ok := other.value(c); ok && NormalizeValue(v) != NormalizeValue(row.values[i]) {
+						sets = append(sets, fmt.Sprintf("%s = %s", d.QuoteIdentifier(c), row.literal(d, i)))
+					}
+				}
+				if len(sets) == 0 {
+					continue
+				}
+				fmt.Fprintf(upserts, "UPDATE %s SET %s WHERE %s;\n", table, strings.Join(sets, ", "), keyWhere(d, keys, row))
+				result.Updates++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// openTestSQLite opens a new SQLite database with foreign keys enforced and
+// runs stmts on it.
+func openTestSQLite(t *testing.T, stmts ...string) *gorm.DB {
+	t.Helper()
+	d, err := LookupDialect("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := d.Open(filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=1", &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+func TestDiffTableDataReferenceOrder(t *testing.T) {
+	schema := []string{
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id), note TEXT)",
+	}
+	source := openTestSQLite(t, append(schema,
+		"INSERT INTO customers VALUES (1, 'Ann'), (2, 'O''Brien')",
+		"INSERT INTO orders VALUES (10, 2, 'moved'), (12, 2, NULL)",
+	)...)
+	target := openTestSQLite(t, append(schema,
+		"INSERT INTO customers VALUES (1, 'Ann'), (3, 'Gone')",
+		"INSERT INTO orders VALUES (10, 3, 'moved'), (11, 3, 'gone')",
+	)...)
+
+	tables, err := LoadSchema(source, []string{"orders", "customers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := tables.ReferenceOrder()
+	if strings.Join(order, ",") != "customers,orders" {
+		t.Fatalf("reference order %v, want customers before orders", order)
+	}
+
+	var patch bytes.Buffer
+	deletes := make([]bytes.Buffer, len(order))
+	for i, tbl := range order {
+		if _, err := DiffTableData(source, target, tbl, 2, &patch, &deletes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := len(deletes) - 1; i >= 0; i-- {
+		deletes[i].WriteTo(&patch)
+	}
+
+	err = target.Connection(func(conn *gorm.DB) error {
+		return SplitSQLStatements(&patch, func(stmt string) error {
+			return conn.Exec(stmt).Error
+		})
+	})
+	if err != nil {
+		t.Fatalf("applying the patch: %v", err)
+	}
+	for _, tbl := range order {
+		result, err := DiffTableData(source, target, tbl, 2, &patch, &patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Empty() {
+			t.Errorf("%s still differs after the patch: %+v", tbl, result)
+		}
+	}
+}
@@ -0,0 +1,568 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// ddlToken is a lexical token of a DDL statement. Quoted identifiers keep
+// their quotes in text and are unquoted in ident.
+type ddlToken struct {
+	text  string
+	ident string
+	// comment holds the -- comment lines ahead of the token and trailing
+	// the -- comment following it on its line, without the dashes. They are
+	// how SQLite schemas carry table and column comments.
+	comment  string
+	trailing string
+}
+
+func (t ddlToken) is(keyword string) bool {
+	return strings.EqualFold(t.text, keyword)
+}
+
+// stringValue returns the value of a string literal token, and false for
+// other tokens.
+func (t ddlToken) stringValue() (string, bool) {
+	if len(t.text) < 2 || t.text[0] != '\'' {
+		return "", false
+	}
+	return strings.ReplaceAll(t.text[1:len(t.text)-1], "''", "'"), true
+}
+
+// tokenizeDDL splits a statement into identifiers, literals and punctuation.
+func tokenizeDDL(stmt string) ([]ddlToken, error) {
+	var tokens []ddlToken
+	var comments []string
+	newLine := true
+	add := func(tok ddlToken) {
+		tok.comment = strings.Join(comments, "\n")
+		comments, newLine = nil, false
+		tokens = append(tokens, tok)
+	}
+	runes := []rune(stmt)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			newLine = newLine || c == '\n'
+			i++
+		case c == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			start := i + 2
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			text := strings.TrimSpace(string(runes[start:i]))
+			if n := len(tokens); n > 0 && !newLine {
+				tokens[n-1].trailing = text
+			} else {
+				comments = append(comments, text)
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			j := i + 2
+			for j+1 < len(runes) && !(runes[j] == '*' && runes[j+1] == '/') {
+				j++
+			}
+			if j+1 >= len(runes) {
+				return nil, fmt.Errorf("unterminated block comment")
+			}
+			i = j + 2
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == closing {
+					if j+1 < len(runes) && runes[j+1] == closing && closing != ']' {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated quoted text")
+			}
+			text := string(runes[i : j+1])
+			tok := ddlToken{text: text}
+			if c != '\'' {
+				inner := string(runes[i+1 : j])
+				tok.ident = strings.ReplaceAll(inner, string(closing)+string(closing), string(closing))
+			}
+			add(tok)
+			i = j + 1
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '$':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '$' || runes[j] == '.' && j+1 < len(runes) && unicode.IsDigit(runes[j+1])) {
+				j++
+			}
+			text := string(runes[i:j])
+			add(ddlToken{text: text, ident: text})
+			i = j
+		case i+1 < len(runes) && isDDLOperator(string(runes[i:i+2])):
+			add(ddlToken{text: string(runes[i : i+2])})
+			i += 2
+		default:
+			add(ddlToken{text: string(c)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// isDDLOperator reports the two-character operators kept as one token.
+func isDDLOperator(s string) bool {
+	switch s {
+	case "::", "<=", ">=", "<>", "!=", "||":
+		return true
+	}
+	return false
+}
+
+// joinDDLTokens rebuilds SQL text from tokens with conventional spacing.
+func joinDDLTokens(tokens []ddlToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 {
+			prev := tokens[i-1].text
+			noSpace := t.text == ")" || t.text == "," || t.text == "." || t.text == "::" ||
+				prev == "(" || prev == "." || prev == "::" ||
+				(t.text == "(" && prev != "," && !isDDLKeyword(prev) && prev != "=")
+			if !noSpace {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// isDDLKeyword reports operator-like keywords that keep a space before "(".
+func isDDLKeyword(s string) bool {
+	switch strings.ToUpper(s) {
+	case "AND", "OR", "NOT", "IN", "CHECK", "DEFAULT", "AS", "KEY", "UNIQUE", "REFERENCES", "ON", "IS", "BETWEEN", "LIKE", "THEN", "ELSE", "WHEN":
+		return true
+	}
+	return false
+}
+
+// splitDDLList splits tokens on commas that are not nested in parentheses.
+func splitDDLList(tokens []ddlToken) [][]ddlToken {
+	var parts [][]ddlToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch t.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
+
+// matchingParen returns the index of the ")" closing the "(" at tokens[open].
+func matchingParen(tokens []ddlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseDDLName reads a possibly qualified name starting at tokens[i] and
+// returns it as QualifyTableName joins it, without quotes but around names
+// holding a dot, with the index that follows it.
+func parseDDLName(tokens []ddlToken, i int) (string, int) {
+	if i >= len(tokens) {
+		return "", i
+	}
+	name := QualifyTableName("", tokens[i].ident)
+	i++
+	for i+1 < len(tokens) && tokens[i].text == "." {
+		name = QualifyTableName(name, tokens[i+1].ident)
+		i += 2
+	}
+	return name, i
+}
+
+// parseColumnList reads "(a, b, ...)" at tokens[i], ignoring index lengths and
+// sort orders, and returns the names with the index that follows.
+func parseColumnList(tokens []ddlToken, i int) ([]string, int) {
+	if i >= len(tokens) || tokens[i].text != "(" {
+		return nil, i
+	}
+	end := matchingParen(tokens, i)
+	if end < 0 {
+		return nil, len(tokens)
+	}
+	var columns []string
+	for _, part := range splitDDLList(tokens[i+1 : end]) {
+		if len(part) > 0 {
+			columns = append(columns, part[0].ident)
+		}
+	}
+	return columns, end + 1
+}
+
+// columnStopWords end the type or default of a column definition.
+var columnStopWords = map[string]bool{
+	"NOT": true, "NULL": true, "DEFAULT": true, "PRIMARY": true, "UNIQUE": true, "REFERENCES": true,
+	"CHECK": true, "COLLATE": true, "AUTO_INCREMENT": true, "AUTOINCREMENT": true, "GENERATED": true,
+	"CONSTRAINT": true, "COMMENT": true, "IDENTITY": true, "AS": true,
+}
+
+// isColumnStop reports whether def[i] starts a column constraint. CHARACTER
+// and ON only do so as CHARACTER SET and ON UPDATE, as they also appear in
+// types such as "character varying".
+func isColumnStop(def []ddlToken, i int) bool {
+	word := strings.ToUpper(def[i].text)
+	switch word {
+	case "CHARACTER":
+		return i+1 < len(def) && def[i+1].is("SET")
+	case "ON":
+		return i+1 < len(def) && def[i+1].is("UPDATE")
+	}
+	return columnStopWords[word]
+}
+
+// ParseSchemaSQL builds a schema from CREATE TABLE, CREATE INDEX and COMMENT
+// ON statements such as those written by ExportSchemaSQL. Other statements
+// are ignored, and so are partitions, which belong to their partitioned
+// table.
+func ParseSchemaSQL(sql string) (*Schema, error) {
+	schema := NewSchema()
+	if err := parseSchemaSQL(schema, sql); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// parseSchemaSQL adds the tables of sql to schema, and its indexes and
+// comments to the tables of schema.
+func parseSchemaSQL(schema *Schema, sql string) error {
+	var stmts []string
+	if err := SplitSQLStatements(strings.NewReader(sql), func(stmt string) error {
+		stmts = append(stmts, stmt)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, stmt := range stmts {
+		tokens, err := tokenizeDDL(strings.TrimSuffix(stmt, ";"))
+		if err != nil {
+			return err
+		}
+		if len(tokens) > 3 && tokens[0].is("COMMENT") && tokens[1].is("ON") {
+			parseComment(schema, tokens[2:])
+			continue
+		}
+		if len(tokens) < 3 || !tokens[0].is("CREATE") {
+			continue
+		}
+
+		i := 1
+		for i < len(tokens) && (tokens[i].is("TEMP") || tokens[i].is("TEMPORARY") || tokens[i].is("UNLOGGED") || tokens[i].is("OR") || tokens[i].is("REPLACE")) {
+			i++
+		}
+		if i >= len(tokens) {
+			continue
+		}
+		switch {
+		case tokens[i].is("TABLE"):
+			table, err := parseCreateTable(tokens[i+1:])
+			if err != nil {
+				return err
+			}
+			if table != nil {
+				schema.Tables[table.Name] = table
+			}
+		case tokens[i].is("INDEX") || tokens[i].is("UNIQUE"):
+			tableName, index := parseCreateIndex(tokens[i:])
+			if table, ok := schema.Tables[tableName]; ok {
+				table.Indexes = append(table.Indexes, index)
+			}
+		}
+	}
+	return nil
+}
+
+// parseComment sets the comment of a table or column of schema from the
+// tokens of a COMMENT ON statement following ON.
+func parseComment(schema *Schema, tokens []ddlToken) {
+	name, i := parseDDLName(tokens, 1)
+	if i+1 >= len(tokens) || !tokens[i].is("IS") {
+		return
+	}
+	text, _ := tokens[i+1].stringValue()
+	switch {
+	case tokens[0].is("TABLE"):
+		if table, ok := schema.Tables[name]; ok {
+			table.Comment = text
+		}
+	case tokens[0].is("COLUMN"):
+		tableName, column := SplitTableName(name)
+		if table, ok := schema.Tables[tableName]; ok {
+			if col := table.Column(column); col != nil {
+				col.Comment = text
+			}
+		}
+	}
+}
+
+func parseCreateIndex(tokens []ddlToken) (string, Index) {
+	var index Index
+	i := 0
+	if tokens[i].is("UNIQUE") {
+		index.Unique = true
+		i++
+	}
+	i++ // INDEX
+	if i < len(tokens) && tokens[i].is("CONCURRENTLY") {
+		i++
+	}
+	if i+2 < len(tokens) && tokens[i].is("IF") && tokens[i+1].is("NOT") && tokens[i+2].is("EXISTS") {
+		i += 3
+	}
+	index.Name, i = parseDDLName(tokens, i)
+	if i < len(tokens) && tokens[i].is("ON") {
+		i++
+	}
+	var tableName string
+	tableName, i = parseDDLName(tokens, i)
+	if i < len(tokens) && tokens[i].is("USING") {
+		i += 2
+	}
+	index.Columns, _ = parseColumnList(tokens, i)
+	// SQLite qualifies the index instead of the table
+	if schema, name := SplitTableName(index.Name); schema != "" && !strings.Contains(tableName, ".") {
+		index.Name, tableName = name, QualifyTableName(schema, tableName)
+	}
+	return tableName, index
+}
+
+func parseCreateTable(tokens []ddlToken) (*Table, error) {
+	i := 0
+	if len(tokens) > 3 && tokens[0].is("IF") && tokens[1].is("NOT") && tokens[2].is("EXISTS") {
+		i = 3
+	}
+	name, i := parseDDLName(tokens, i)
+	if i+1 < len(tokens) && tokens[i].is("PARTITION") && tokens[i+1].is("OF") {
+		// Partitions are part of their partitioned table
+		return nil, nil
+	}
+	if i >= len(tokens) || tokens[i].text != "(" {
+		return nil, fmt.Errorf("unsupported CREATE TABLE statement for %s", name)
+	}
+	end := matchingParen(tokens, i)
+	if end < 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in CREATE TABLE %s", name)
+	}
+
+	// SQLite tables carry their comment after the opening parenthesis, MySQL
+	// tables as a table option
+	table := &Table{Name: name, Comment: tokens[i].trailing}
+	for _, def := range splitDDLList(tokens[i+1 : end]) {
+		if len(def) == 0 {
+			continue
+		}
+		if err := parseTableElement(table, def); err != nil {
+			return nil, err
+		}
+	}
+	for j := end + 1; j+1 < len(tokens); j++ {
+		if tokens[j].is("COMMENT") {
+			if tokens[j+1].text == "=" && j+2 < len(tokens) {
+				j++
+			}
+			table.Comment, _ = tokens[j+1].stringValue()
+		}
+	}
+	return table, nil
+}
+
+// parseTableElement adds a column definition or a table constraint to table.
+func parseTableElement(table *Table, def []ddlToken) error {
+	var constraintName string
+	if def[0].is("CONSTRAINT") && len(def) > 2 {
+		constraintName = def[1].ident
+		def = def[2:]
+	}
+
+	first := def[0]
+	switch {
+	case first.is("PRIMARY") && len(def) > 1 && def[1].is("KEY"):
+		table.PrimaryKey, _ = parseColumnList(def, 2)
+	case first.is("UNIQUE") || first.is("KEY") || first.is("INDEX") || first.is("FULLTEXT") || first.is("SPATIAL"):
+		index := Index{Name: constraintName, Unique: first.is("UNIQUE")}
+		i := 1
+		for i < len(def) && (def[i].is("KEY") || def[i].is("INDEX")) {
+			i++
+		}
+		if i < len(def) && def[i].text != "(" {
+			index.Name = def[i].ident
+			i++
+		}
+		index.Columns, _ = parseColumnList(def, i)
+		if index.Name == "" {
+			index.Name = fmt.Sprintf("%s_%s_key", table.Name, strings.Join(index.Columns, "_"))
+		}
+		table.Indexes = append(table.Indexes, index)
+	case first.is("FOREIGN") && len(def) > 1 && def[1].is("KEY"):
+		fk := ForeignKey{Name: constraintName}
+		var i int
+		fk.Columns, i = parseColumnList(def, 2)
+		if i < len(def) && def[i].is("REFERENCES") {
+			fk.RefTable, i = parseDDLName(def, i+1)
+			fk.RefColumns, _ = parseColumnList(def, i)
+		}
+		if fk.Name == "" {
+			fk.Name = fmt.Sprintf("%s_%s_fkey", table.Name, strings.Join(fk.Columns, "_"))
+		}
+		table.ForeignKeys = append(table.ForeignKeys, fk)
+	case first.is("CHECK"):
+		table.Checks = append(table.Checks, Check{Name: constraintName, Expression: joinDDLTokens(def[1:])})
+	case first.is("EXCLUDE") || first.is("PERIOD"):
+		// Not represented in the schema model
+	default:
+		return parseColumnDefinition(table, def)
+	}
+	return nil
+}
+
+func parseColumnDefinition(table *Table, def []ddlToken) error {
+	col := Column{Name: def[0].ident, Nullable: true, Comment: def[0].comment}
+
+	// The type runs until the first constraint keyword outside parentheses
+	i := 1
+	for i < len(def) && !isColumnStop(def, i) {
+		if def[i].text == "(" {
+			end := matchingParen(def, i)
+			if end < 0 {
+				return fmt.Errorf("unbalanced parentheses in column %s", col.Name)
+			}
+			i = end + 1
+			continue
+		}
+		i++
+	}
+	col.Type = joinDDLTokens(def[1:i])
+	col.Enum = inlineEnumLabels(col.Type)
+
+	for i < len(def) {
+		t := def[i]
+		switch {
+		case t.is("NOT") && i+1 < len(def) && def[i+1].is("NULL"):
+			col.Nullable = false
+			i += 2
+		case t.is("NULL"):
+			i++
+		case t.is("DEFAULT"):
+			start := i + 1
+			i = start
+			for i < len(def) && (i == start || !isColumnStop(def, i)) {
+				if def[i].text == "(" {
+					end := matchingParen(def, i)
+					if end < 0 {
+						return fmt.Errorf("unbalanced parentheses in column %s", col.Name)
+					}
+					i = end
+				}
+				i++
+			}
+			value := joinDDLTokens(def[start:i])
+			col.Default = &value
+		case t.is("PRIMARY") && i+1 < len(def) && def[i+1].is("KEY"):
+			table.PrimaryKey = []string{col.Name}
+			i += 2
+		case t.is("UNIQUE"):
+			table.Indexes = append(table.Indexes, Index{Name: fmt.Sprintf("%s_%s_key", table.Name, col.Name), Columns: []string{col.Name}, Unique: true})
+			i++
+		case t.is("REFERENCES"):
+			fk := ForeignKey{Name: fmt.Sprintf("%s_%s_fkey", table.Name, col.Name), Columns: []string{col.Name}}
+			fk.RefTable, i = parseDDLName(def, i+1)
+			fk.RefColumns, i = parseColumnList(def, i)
+			table.ForeignKeys = append(table.ForeignKeys, fk)
+		case t.is("COMMENT") && i+1 < len(def):
+			col.Comment, _ = def[i+1].stringValue()
+			i += 2
+		case t.is("AUTO_INCREMENT") || t.is("AUTOINCREMENT"):
+			col.AutoIncrement = true
+			i++
+		case t.is("GENERATED"):
+			// GENERATED {ALWAYS | BY DEFAULT} AS IDENTITY [(options)], or
+			// AS (expression) for computed columns
+			for i < len(def) && !def[i].is("AS") {
+				i++
+			}
+			i++
+			if i < len(def) && def[i].is("IDENTITY") {
+				col.AutoIncrement = true
+				i++
+			}
+			if i < len(def) && def[i].text == "(" {
+				end := matchingParen(def, i)
+				if end < 0 {
+					return fmt.Errorf("unbalanced parentheses in column %s", col.Name)
+				}
+				i = end + 1
+			}
+		case t.is("CHECK") && i+1 < len(def) && def[i+1].text == "(":
+			end := matchingParen(def, i+1)
+			if end < 0 {
+				return fmt.Errorf("unbalanced parentheses in column %s", col.Name)
+			}
+			table.Checks = append(table.Checks, Check{Name: fmt.Sprintf("%s_%s_check", table.Name, col.Name), Expression: joinDDLTokens(def[i+1 : end+1])})
+			i = end + 1
+		default:
+			i++
+		}
+	}
+
+	table.Columns = append(table.Columns, col)
+	return nil
+}
+
+// LoadSchemaDir builds a schema from the *_schema.sql files of dir, adding
+// the standalone indexes of its *_index.sql object files to their tables.
+func LoadSchemaDir(dir string) (*Schema, error) {
+	schema := NewSchema()
+	for _, pattern := range []string{"*_schema.sql", "*_" + KindIndex + ".sql"} {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if err := parseSchemaSQL(schema, string(content)); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+			}
+		}
+	}
+	return schema, nil
+}
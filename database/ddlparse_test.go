@@ -0,0 +1,343 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func stringPtr(s string) *string { return &s }
+
+// TestLoadSchemaDirPostgresRoundTrip loads the files a PostgreSQL export
+// writes and diffs them against the tables as introspected from the
+// database they were exported from.
+func TestLoadSchemaDirPostgresRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"customers_schema.sql": `CREATE TABLE "customers" (
+    "id" integer GENERATED BY DEFAULT AS IDENTITY NOT NULL,
+    "email" character varying(255) NOT NULL,
+    CONSTRAINT "customers_pkey" PRIMARY KEY (id),
+    CONSTRAINT "customers_email_key" UNIQUE (email)
+);
+COMMENT ON TABLE "customers" IS 'People who order';
+`,
+		"orders_schema.sql": `CREATE TABLE "orders" (
+    "id" bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+    "customer_id" integer NOT NULL,
+    "total" numeric(10,2) DEFAULT 0 NOT NULL,
+    "tags" text[],
+    "status" character varying(20) DEFAULT 'new'::character varying,
+    "created_at" timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT "orders_pkey" PRIMARY KEY (id),
+    CONSTRAINT "orders_total_check" CHECK ((total >= (0)::numeric)),
+    CONSTRAINT "orders_customer_id_fkey" FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+) PARTITION BY RANGE (created_at);
+CREATE TABLE "orders_2026" PARTITION OF "orders" FOR VALUES FROM ('2026-01-01 00:00:00') TO ('2027-01-01 00:00:00');
+`,
+		"idx_orders_created_index.sql": "CREATE INDEX idx_orders_created ON orders USING btree (created_at);\n",
+		"active_orders_view.sql":       "CREATE VIEW active_orders AS\n SELECT id FROM orders;\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := LoadSchemaDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	introspected := NewSchema()
+	introspected.Tables["customers"] = &Table{
+		Name:    "customers",
+		Comment: "People who order",
+		Columns: []Column{
+			{Name: "id", Type: "integer", AutoIncrement: true},
+			{Name: "email", Type: "character varying(255)"},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes:    []Index{{Name: "customers_email_key", Columns: []string{"email"}, Unique: true}},
+	}
+	introspected.Tables["orders"] = &Table{
+		Name: "orders",
+		Columns: []Column{
+			{Name: "id", Type: "bigint", AutoIncrement: true},
+			{Name: "customer_id", Type: "integer"},
+			{Name: "total", Type: "numeric(10,2)", Default: stringPtr("0")},
+			{Name: "tags", Type: "text[]", Nullable: true},
+			{Name: "status", Type: "character varying(20)", Nullable: true, Default: stringPtr("'new'::character varying")},
+			{Name: "created_at", Type: "timestamp without time zone", Default: stringPtr("now()")},
+		},
+		PrimaryKey:  []string{"id"},
+		Indexes:     []Index{{Name: "idx_orders_created", Columns: []string{"created_at"}}},
+		ForeignKeys: []ForeignKey{{Name: "orders_customer_id_fkey", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}}},
+		Checks:      []Check{{Name: "orders_total_check", Expression: "((total >= (0)::numeric))"}},
+	}
+
+	if names := loaded.TableNames(); len(names) != 2 {
+		t.Fatalf("loaded tables %v, want customers and orders", names)
+	}
+	dialect, err := LookupDialect("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, diff := range []*SchemaDiff{DiffSchemas(loaded, introspected), DiffSchemas(introspected, loaded)} {
+		if stmts := diff.AlterSQL(dialect); len(stmts) > 0 {
+			t.Errorf("round-tripped export diffs to %q", stmts)
+		}
+	}
+}
+
+func TestTokenizeDDL(t *testing.T) {
+	tests := []struct {
+		name   string
+		stmt   string
+		texts  []string
+		idents []string
+	}{
+		{"quoted identifiers", "\"my \"\"col\"\"\" `t``x` [dbo].[a b]",
+			[]string{`"my ""col"""`, "`t``x`", "[dbo]", ".", "[a b]"},
+			[]string{`my "col"`, "t`x", "dbo", "", "a b"}},
+		{"operators", "a >= 1 AND b <> 'x''y' OR c || d != e <= f",
+			[]string{"a", ">=", "1", "AND", "b", "<>", "'x''y'", "OR", "c", "||", "d", "!=", "e", "<=", "f"},
+			[]string{"a", "", "1", "AND", "b", "", "", "OR", "c", "", "d", "", "e", "", "f"}},
+		{"casts and numbers", "0.5::numeric(10,2)",
+			[]string{"0.5", "::", "numeric", "(", "10", ",", "2", ")"},
+			[]string{"0.5", "", "numeric", "", "10", "", "2", ""}},
+		{"block comments", "a /* b; c */ d",
+			[]string{"a", "d"},
+			[]string{"a", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenizeDDL(tt.stmt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var texts, idents []string
+			for _, tok := range tokens {
+				texts = append(texts, tok.text)
+				idents = append(idents, tok.ident)
+			}
+			if !reflect.DeepEqual(texts, tt.texts) || !reflect.DeepEqual(idents, tt.idents) {
+				t.Errorf("got %q / %q, want %q / %q", texts, idents, tt.texts, tt.idents)
+			}
+		})
+	}
+}
+
+func TestTokenizeDDLComments(t *testing.T) {
+	tokens, err := tokenizeDDL("( -- table\n  -- leading\n  -- lines\n  id INT -- trailing\n)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[0].trailing != "table" {
+		t.Errorf("trailing comment of ( = %q", tokens[0].trailing)
+	}
+	if tokens[1].comment != "leading\nlines" || tokens[2].trailing != "trailing" {
+		t.Errorf("comments of id INT = %q, %q", tokens[1].comment, tokens[2].trailing)
+	}
+}
+
+func TestTokenizeDDLErrors(t *testing.T) {
+	for _, stmt := range []string{"'abc", `"abc`, "[abc", "a /* b"} {
+		if _, err := tokenizeDDL(stmt); err == nil {
+			t.Errorf("tokenizeDDL(%q) succeeded, want an error", stmt)
+		}
+	}
+}
+
+// TestParseSchemaSQL parses CREATE TABLE statements as each database
+// reports them.
+func TestParseSchemaSQL(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+		want    *Table
+	}{
+		{
+			dialect: "mysql",
+			sql: "CREATE TABLE `orders` (\n" +
+				"  `id` bigint NOT NULL AUTO_INCREMENT,\n" +
+				"  `customer_id` int NOT NULL,\n" +
+				"  `status` enum('new','paid') NOT NULL DEFAULT 'new' COMMENT 'Order state',\n" +
+				"  `total` decimal(10,2) DEFAULT NULL,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  UNIQUE KEY `orders_ref` (`customer_id`,`id`),\n" +
+				"  KEY `idx_status` (`status`),\n" +
+				"  CONSTRAINT `orders_customer_fk` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`) ON DELETE CASCADE,\n" +
+				"  CONSTRAINT `orders_total_check` CHECK ((`total` >= 0))\n" +
+				") ENGINE=InnoDB AUTO_INCREMENT=5 DEFAULT CHARSET=utf8mb4 COMMENT='Customer orders';",
+			want: &Table{
+				Name:    "orders",
+				Comment: "Customer orders",
+				Columns: []Column{
+					{Name: "id", Type: "bigint", AutoIncrement: true},
+					{Name: "customer_id", Type: "int"},
+					{Name: "status", Type: "enum('new', 'paid')", Default: stringPtr("'new'"), Enum: []string{"new", "paid"}, Comment: "Order state"},
+					{Name: "total", Type: "decimal(10, 2)", Nullable: true, Default: stringPtr("NULL")},
+				},
+				PrimaryKey: []string{"id"},
+				Indexes: []Index{
+					{Name: "orders_ref", Columns: []string{"customer_id", "id"}, Unique: true},
+					{Name: "idx_status", Columns: []string{"status"}},
+				},
+				ForeignKeys: []ForeignKey{{Name: "orders_customer_fk", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}}},
+				Checks:      []Check{{Name: "orders_total_check", Expression: "((`total` >= 0))"}},
+			},
+		},
+		{
+			dialect: "sqlite",
+			sql: "CREATE TABLE \"orders\" ( -- Customer orders\n" +
+				"    -- Surrogate key\n" +
+				"    \"id\" INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+				"    \"customer_id\" INTEGER NOT NULL REFERENCES \"customers\" (\"id\") ON DELETE CASCADE,\n" +
+				"    \"code\" TEXT UNIQUE,\n" +
+				"    \"total\" REAL CHECK (\"total\" >= 0) DEFAULT 0\n" +
+				");\n" +
+				"CREATE INDEX \"idx_orders_customer\" ON \"orders\" (\"customer_id\" DESC);",
+			want: &Table{
+				Name:    "orders",
+				Comment: "Customer orders",
+				Columns: []Column{
+					{Name: "id", Type: "INTEGER", Nullable: true, AutoIncrement: true, Comment: "Surrogate key"},
+					{Name: "customer_id", Type: "INTEGER"},
+					{Name: "code", Type: "TEXT", Nullable: true},
+					{Name: "total", Type: "REAL", Nullable: true, Default: stringPtr("0")},
+				},
+				PrimaryKey: []string{"id"},
+				Indexes: []Index{
+					{Name: "orders_code_key", Columns: []string{"code"}, Unique: true},
+					{Name: "idx_orders_customer", Columns: []string{"customer_id"}},
+				},
+				ForeignKeys: []ForeignKey{{Name: "orders_customer_id_fkey", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}}},
+				Checks:      []Check{{Name: "orders_total_check", Expression: `("total" >= 0)`}},
+			},
+		},
+		{
+			dialect: "duckdb",
+			sql: "CREATE TABLE readings(id BIGINT DEFAULT(nextval('readings_id_seq')) PRIMARY KEY, tags VARCHAR[], " +
+				"point STRUCT(x DOUBLE, y DOUBLE), attrs MAP(VARCHAR, INTEGER), big HUGEINT NOT NULL);\n" +
+				"COMMENT ON COLUMN readings.big IS 'Counter';",
+			want: &Table{
+				Name: "readings",
+				Columns: []Column{
+					{Name: "id", Type: "BIGINT", Nullable: true, Default: stringPtr("(nextval('readings_id_seq'))")},
+					{Name: "tags", Type: "VARCHAR []", Nullable: true},
+					{Name: "point", Type: "STRUCT(x DOUBLE, y DOUBLE)", Nullable: true},
+					{Name: "attrs", Type: "MAP(VARCHAR, INTEGER)", Nullable: true},
+					{Name: "big", Type: "HUGEINT", Comment: "Counter"},
+				},
+				PrimaryKey: []string{"id"},
+			},
+		},
+		{
+			dialect: "postgres",
+			sql: "CREATE TABLE \"sales\".\"q1.orders\" (\n" +
+				"    \"id\" integer GENERATED ALWAYS AS IDENTITY (START WITH 10) NOT NULL,\n" +
+				"    \"amount\" numeric GENERATED ALWAYS AS (price * 2) STORED,\n" +
+				"    CONSTRAINT \"q1.orders_pkey\" PRIMARY KEY (id),\n" +
+				"    CONSTRAINT \"q1_no_overlap\" EXCLUDE USING gist (id WITH =)\n" +
+				") PARTITION BY RANGE (id);\n" +
+				"CREATE TABLE \"sales\".\"q1.orders_1\" PARTITION OF \"sales\".\"q1.orders\" FOR VALUES FROM (1) TO (100);\n" +
+				"COMMENT ON TABLE \"sales\".\"q1.orders\" IS 'First quarter';\n" +
+				"COMMENT ON COLUMN \"sales\".\"q1.orders\".\"id\" IS 'Key';\n" +
+				"CREATE INDEX ix_amount ON sales.\"q1.orders\" USING btree (amount);",
+			want: &Table{
+				Name:    `sales."q1.orders"`,
+				Comment: "First quarter",
+				Columns: []Column{
+					{Name: "id", Type: "integer", AutoIncrement: true, Comment: "Key"},
+					{Name: "amount", Type: "numeric", Nullable: true},
+				},
+				PrimaryKey: []string{"id"},
+				Indexes:    []Index{{Name: "ix_amount", Columns: []string{"amount"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			schema, err := ParseSchemaSQL(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := schema.TableNames(); len(names) != 1 {
+				t.Fatalf("parsed tables %q, want only %s", names, tt.want.Name)
+			}
+			if got := schema.Tables[tt.want.Name]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsed\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchemaSQLErrors(t *testing.T) {
+	for _, sql := range []string{
+		"CREATE TABLE t AS SELECT 1",
+		"CREATE TABLE t (id INT",
+		"CREATE TABLE t (id INT DEFAULT 'x)",
+	} {
+		if _, err := ParseSchemaSQL(sql); err == nil {
+			t.Errorf("ParseSchemaSQL(%q) succeeded, want an error", sql)
+		}
+	}
+}
+
+// TestCreateTableSQLRoundTrip parses the statements CreateTableSQL renders
+// for each dialect that can be read back, and renders the parsed table again.
+func TestCreateTableSQLRoundTrip(t *testing.T) {
+	table := &Table{
+		Name:    "orders",
+		Comment: "Customer's orders",
+		Columns: []Column{
+			{Name: "id", Type: "BIGINT"},
+			{Name: "customer_id", Type: "INTEGER"},
+			{Name: "total", Type: "DECIMAL(10, 2)", Default: stringPtr("0"), Comment: "Gross"},
+			{Name: "note", Type: "VARCHAR(200)", Nullable: true, Default: stringPtr("'n/a'")},
+			{Name: "created_at", Type: "TIMESTAMP", Default: stringPtr("CURRENT_TIMESTAMP")},
+		},
+		PrimaryKey:  []string{"id"},
+		Indexes:     []Index{{Name: "idx_orders_created", Columns: []string{"created_at", "id"}}, {Name: "orders_note_key", Columns: []string{"note"}, Unique: true}},
+		ForeignKeys: []ForeignKey{{Name: "orders_customer_id_fkey", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}}},
+		Checks:      []Check{{Name: "orders_total_check", Expression: "(total >= 0 AND total <> 13)"}},
+	}
+	for _, name := range []string{"postgres", "mysql", "sqlite", "duckdb"} {
+		t.Run(name, func(t *testing.T) {
+			dialect, err := LookupDialect(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmts := CreateTableSQL(table, dialect)
+			schema, err := ParseSchemaSQL(strings.Join(stmts, "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed := schema.Tables["orders"]
+			if parsed == nil {
+				t.Fatalf("no table parsed from %q", stmts)
+			}
+			if again := CreateTableSQL(parsed, dialect); !reflect.DeepEqual(again, stmts) {
+				t.Errorf("rendered\n%s\nthen\n%s", strings.Join(stmts, "\n"), strings.Join(again, "\n"))
+			}
+			if parsed.Comment != table.Comment || parsed.Column("total").Comment != "Gross" {
+				t.Errorf("comments read back as %q, %q", parsed.Comment, parsed.Column("total").Comment)
+			}
+
+			// Foreign keys are added by ALTER TABLE where CREATE TABLE does
+			// not declare them
+			want := table
+			if !dialect.ForeignKeysInCreateTable() {
+				copied := *table
+				copied.ForeignKeys = nil
+				want = &copied
+			}
+			if diff := DiffTables(parsed, want); !diff.empty() {
+				t.Errorf("parsed table differs: %+v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dialect is everything sql-migration needs to know about one kind of
+// database. A database is supported by implementing Dialect and registering
+// it with RegisterDialect under the driver name used in the DSN config, which
+// must also be the name gorm reports for its connections.
+//
+// Table names passed to a Dialect may be schema-qualified (see
+// SplitTableName); an unqualified name refers to the current schema.
+// Most dialects embed StandardSQL for the parts that follow the SQL standard.
+type Dialect interface {
+	// Name is the driver name of the dialect.
+	Name() string
+
+	// Open connects to dsn.
+	Open(dsn string, config *gorm.Config) (*gorm.DB, error)
+
+	// CurrentSchema returns the schema unqualified table names refer to.
+	CurrentSchema(db *gorm.DB) (string, error)
+	// ListTables returns the tables of schemas, or of every user schema with
+	// all set, current schema first.
+	ListTables(db *gorm.DB, schemas []string, all bool) ([]SchemaTable, error)
+	// TableExists reports whether tableName exists.
+	TableExists(db *gorm.DB, tableName string) (bool, error)
+	// ListObjects returns the views, triggers, routines and standalone
+	// indexes of schemas, or of every user schema with all set, with names
+	// outside the current schema qualified, and the extensions, types and
+	// domains of databases that have them.
+	ListObjects(db *gorm.DB, schemas []string, all bool) ([]SchemaObject, error)
+
+	// CreateTableStatement returns the CREATE TABLE statement of tableName
+	// as the database reports it, naming the table as tableName, followed by
+	// the statements creating its partitions and setting its comments when
+	// CREATE TABLE cannot hold them.
+	CreateTableStatement(db *gorm.DB, tableName string) (string, error)
+	// PrimaryKeyColumns returns the primary key columns of tableName in key order.
+	PrimaryKeyColumns(db *gorm.DB, tableName string) ([]string, error)
+	// IntrospectTable reads the columns, keys, indexes and constraints of tableName.
+	IntrospectTable(db *gorm.DB, tableName string) (*Table, error)
+
+	// QuoteIdentifier quotes a single identifier.
+	QuoteIdentifier(name string) string
+	// CreateSchemaSQL returns the statement creating schema ahead of its
+	// tables, or "" when schemas cannot be created with SQL.
+	CreateSchemaSQL(schema string) string
+	// ForeignKeysInCreateTable reports whether foreign keys can only be
+	// declared in CREATE TABLE, rather than added once all tables exist.
+	ForeignKeysInCreateTable() bool
+	// The following render single DDL statements. Changes a dialect cannot
+	// express are rendered as SQL comments.
+	CreateIndexSQL(tableName string, idx Index) string
+	DropIndexSQL(tableName string, idx Index) string
+	AddForeignKeySQL(tableName string, fk ForeignKey) string
+	DropForeignKeySQL(tableName string, fk ForeignKey) string
+	AddCheckSQL(tableName string, c Check) string
+	DropCheckSQL(tableName string, c Check) string
+	AlterColumnSQL(tableName string, c ColumnChange) []string
+	AlterPrimaryKeySQL(tableName string, from, to []string) []string
+	// DropObjectSQL renders the statement dropping a listed object.
+	DropObjectSQL(obj SchemaObject) string
+	// CommentSQL renders the statement setting the comment of tableName, or
+	// of its column when column is not empty.
+	CommentSQL(tableName, column, comment string) string
+
+	// Literal renders a scanned column value as a SQL literal. dbType is the
+	// type name the driver reports for the column, for values whose Go type
+	// is ambiguous.
+	Literal(val any, dbType string) string
+	// InsertSQL renders one INSERT statement of rows, each a list of
+	// literals in columns order.
+	InsertSQL(tableName string, columns []string, rows [][]string) string
+	// TruncateTables removes every row of tableNames, given with the tables
+	// referencing others first. Foreign keys between them do not get in the
+	// way, and nothing outside tableNames is touched.
+	TruncateTables(db *gorm.DB, tableNames []string) error
+	// DropTables drops tableNames, given with the tables referencing others
+	// first. Nothing outside tableNames is dropped.
+	DropTables(db *gorm.DB, tableNames []string) error
+
+	// ResetSequences moves the sequences of tableName past its highest key.
+	ResetSequences(db *gorm.DB, tableName string) error
+	// SequencesSQL returns the statements restoring the sequence values of
+	// tableName, to run before and after its CREATE TABLE statement.
+	SequencesSQL(db *gorm.DB, tableName string) (before, after []string, err error)
+
+	// AcquireLock takes the named lock on the database, waiting up to timeout.
+	AcquireLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error)
+}
+
+// SchemaTable is a table listed by Dialect.ListTables.
+type SchemaTable struct {
+	Schema string
+	Table  string
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{}
+)
+
+// RegisterDialect makes d available under d.Name(). It panics when the name
+// is already taken, like database/sql.Register.
+func RegisterDialect(d Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	if _, dup := dialects[d.Name()]; dup {
+		panic("database: RegisterDialect called twice for dialect " + d.Name())
+	}
+	dialects[d.Name()] = d
+}
+
+// LookupDialect returns the dialect registered under name.
+func LookupDialect(name string) (Dialect, error) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	d, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported dialect: %s", name)
+	}
+	return d, nil
+}
+
+// DialectOf returns the dialect of the connection db.
+func DialectOf(db *gorm.DB) (Dialect, error) {
+	return LookupDialect(db.Dialector.Name())
+}
+
+// Dialects returns the names of the registered dialects in sorted order.
+func Dialects() []string {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StandardSQL implements the parts of Dialect that follow the SQL standard,
+// for dialects to embed and override where they differ.
+type StandardSQL struct {
+	// Quote quotes identifiers for dialects that do not use double quotes.
+	// The statements StandardSQL renders quote every name with it.
+	Quote func(name string) string
+}
+
+func (s StandardSQL) QuoteIdentifier(name string) string {
+	if s.Quote != nil {
+		return s.Quote(name)
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// identifierQuoter is the part of Dialect that quotes names.
+type identifierQuoter interface {
+	QuoteIdentifier(name string) string
+}
+
+// quoteTableName quotes each part of a possibly schema-qualified name.
+func quoteTableName(q identifierQuoter, name string) string {
+	schema, table := SplitTableName(name)
+	if schema == "" {
+		return q.QuoteIdentifier(table)
+	}
+	return quoteTableName(q, schema) + "." + q.QuoteIdentifier(table)
+}
+
+// quoteNames quotes names and joins them into a list.
+func quoteNames(q identifierQuoter, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = q.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quoteTableNames quotes table names and joins them into a list.
+func quoteTableNames(q identifierQuoter, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteTableName(q, name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// eachTable renders format, holding one %s, for each of tableNames quoted.
+func eachTable(q identifierQuoter, format string, tableNames []string) []string {
+	stmts := make([]string, len(tableNames))
+	for i, name := range tableNames {
+		stmts[i] = fmt.Sprintf(format, quoteTableName(q, name))
+	}
+	return stmts
+}
+
+// requoteIdentifiers quotes the names an expression quotes with double quotes
+// or backticks with q instead, leaving string literals alone, so a CHECK read
+// from one database can be written for another.
+func requoteIdentifiers(q identifierQuoter, expr string) string {
+	var b strings.Builder
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if c != '\'' && c != '"' && c != '`' {
+			b.WriteByte(c)
+			continue
+		}
+		// Read up to the closing quote, which is escaped by doubling it
+		var name strings.Builder
+		j := i + 1
+		for ; j < len(expr); j++ {
+			if expr[j] == c {
+				if j+1 < len(expr) && expr[j+1] == c {
+					name.WriteByte(c)
+					j++
+					continue
+				}
+				break
+			}
+			name.WriteByte(expr[j])
+		}
+		if j == len(expr) {
+			// Unterminated; keep the rest as it is
+			b.WriteString(expr[i:])
+			break
+		}
+		if c == '\'' {
+			b.WriteString(expr[i : j+1])
+		} else {
+			b.WriteString(q.QuoteIdentifier(name.String()))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func (s StandardSQL) CreateSchemaSQL(schema string) string {
+	return fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", s.QuoteIdentifier(schema))
+}
+
+func (StandardSQL) ForeignKeysInCreateTable() bool {
+	return false
+}
+
+func (s StandardSQL) CreateIndexSQL(tableName string, idx Index) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, s.QuoteIdentifier(idx.Name), quoteTableName(s, tableName), quoteNames(s, idx.Columns))
+}
+
+// DropIndexSQL drops idx from the schema of its table, where indexes live.
+func (s StandardSQL) DropIndexSQL(tableName string, idx Index) string {
+	schema, _ := SplitTableName(tableName)
+	return fmt.Sprintf("DROP INDEX %s;", quoteTableName(s, QualifyTableName(schema, idx.Name)))
+}
+
+func (s StandardSQL) AddForeignKeySQL(tableName string, fk ForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", quoteTableName(s, tableName), s.QuoteIdentifier(fk.Name), foreignKeyDefinition(s, fk))
+}
+
+func (s StandardSQL) DropForeignKeySQL(tableName string, fk ForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", quoteTableName(s, tableName), s.QuoteIdentifier(fk.Name))
+}
+
+func (s StandardSQL) AddCheckSQL(tableName string, c Check) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", quoteTableName(s, tableName), checkDefinition(s, c))
+}
+
+func (s StandardSQL) DropCheckSQL(tableName string, c Check) string {
+	if c.Name == "" {
+		return fmt.Sprintf("-- Cannot drop unnamed check %s on table %s", c.Expression, tableName)
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", quoteTableName(s, tableName), s.QuoteIdentifier(c.Name))
+}
+
+// DropObjectSQL drops obj by name, which is how most kinds of object are
+// dropped in the SQL standard.
+func (s StandardSQL) DropObjectSQL(obj SchemaObject) string {
+	return fmt.Sprintf("DROP %s IF EXISTS %s;", strings.ToUpper(obj.Kind), quoteTableName(s, obj.Name))
+}
+
+func (s StandardSQL) CommentSQL(tableName, column, comment string) string {
+	if column == "" {
+		return fmt.Sprintf("COMMENT ON TABLE %s IS '%s';", quoteTableName(s, tableName), escapeSQLString(comment))
+	}
+	return fmt.Sprintf("COMMENT ON COLUMN %s.%s IS '%s';", quoteTableName(s, tableName), s.QuoteIdentifier(column), escapeSQLString(comment))
+}
+
+func (StandardSQL) Literal(val any, dbType string) string {
+	return sqlLiteral(val)
+}
+
+func (s StandardSQL) InsertSQL(tableName string, columns []string, rows [][]string) string {
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = "(" + strings.Join(row, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES\n%s;\n", quoteTableName(s, tableName), quoteNames(s, columns), strings.Join(values, ",\n"))
+}
+
+// rebuildTableComment renders a change database can only make by rebuilding
+// the table.
+func rebuildTableComment(database, what, tableName string) string {
+	return fmt.Sprintf("-- %s cannot %s on table %s; rebuild the table", database, what, tableName)
+}
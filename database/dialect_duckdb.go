@@ -0,0 +1,502 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcboeker/go-duckdb"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterDialect(duckdbDialect{})
+}
+
+// duckdbDialect is DuckDB, an embedded database file. Schemas are DuckDB
+// schemas; unqualified names refer to main.
+type duckdbDialect struct {
+	StandardSQL
+}
+
+// duckdbDialector runs gorm on the duckdb database/sql driver with the
+// PostgreSQL dialector, whose SQL and $n placeholders DuckDB understands.
+// Only the name differs, so that DialectOf finds DuckDB.
+type duckdbDialector struct {
+	*postgres.Dialector
+}
+
+func (duckdbDialector) Name() string {
+	return "duckdb"
+}
+
+func (d duckdbDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return duckdbMigrator{d.Dialector.Migrator(db)}
+}
+
+// duckdbMigrator only creates missing tables in AutoMigrate, as comparing
+// existing ones relies on PostgreSQL catalog queries DuckDB cannot run.
+type duckdbMigrator struct {
+	gorm.Migrator
+}
+
+func (m duckdbMigrator) AutoMigrate(values ...interface{}) error {
+	for _, value := range values {
+		if m.HasTable(value) {
+			continue
+		}
+		if err := m.CreateTable(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (duckdbDialect) Name() string {
+	return "duckdb"
+}
+
+// Open opens the DuckDB file dsn, which may carry DuckDB settings as query
+// parameters (analytics.duckdb?threads=4).
+func (duckdbDialect) Open(dsn string, config *gorm.Config) (*gorm.DB, error) {
+	return gorm.Open(duckdbDialector{&postgres.Dialector{Config: &postgres.Config{
+		DriverName: "duckdb",
+		DSN:        dsn,
+	}}}, config)
+}
+
+func (duckdbDialect) CurrentSchema(db *gorm.DB) (string, error) {
+	var schema string
+	err := db.Raw("SELECT current_schema()").Row().Scan(&schema)
+	return schema, err
+}
+
+// ListTables lists the tables of the database file itself, leaving out the
+// lock table.
+func (d duckdbDialect) ListTables(db *gorm.DB, schemas []string, all bool) ([]SchemaTable, error) {
+	current, err := d.CurrentSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := informationSchemaTables(db, schemas, all, current,
+		"table_catalog = current_database() AND table_schema NOT IN ('information_schema', 'pg_catalog')", "")
+	if err != nil {
+		return nil, err
+	}
+	var tables []SchemaTable
+	for _, t := range listed {
+		if t.Table != SQLiteLockTable {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+func (duckdbDialect) TableExists(db *gorm.DB, tableName string) (bool, error) {
+	return informationSchemaTableExists(db, tableName, "current_schema()")
+}
+
+// ListObjects lists the views, indexes and macros of the database file,
+// which DuckDB reports as written except for macros. Their statements are
+// rebuilt from the parameters and body, losing parameter defaults.
+func (d duckdbDialect) ListObjects(db *gorm.DB, schemas []string, all bool) ([]SchemaObject, error) {
+	current, err := d.CurrentSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	where := "database_name = current_database() AND schema_name IN ?"
+	args := []any{schemas}
+	if all {
+		where = "database_name = current_database() AND schema_name NOT IN ('information_schema', 'pg_catalog')"
+		args = nil
+	}
+
+	objects, err := queryObjects(db, current, fmt.Sprintf(`
+            SELECT kind, schema_name, name, table_name, '', sql FROM (
+                SELECT 'view' AS kind, schema_name, view_name AS name, '' AS table_name, sql, view_oid AS oid
+                FROM duckdb_views() WHERE NOT internal AND NOT temporary AND %[1]s
+                UNION ALL
+                SELECT 'index', schema_name, index_name, table_name, sql, index_oid
+                FROM duckdb_indexes() WHERE sql IS NOT NULL AND %[1]s
+            ) ORDER BY oid`, where), append(args, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Raw(fmt.Sprintf(`
+            SELECT schema_name, function_name, function_type = 'table_macro', array_to_string(parameters, ', '), macro_definition
+            FROM duckdb_functions()
+            WHERE function_type IN ('macro', 'table_macro') AND NOT internal AND %s
+            ORDER BY function_oid`, where), args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, name, params, body string
+		var table bool
+		if err := rows.Scan(&schema, &name, &table, &params, &body); err != nil {
+			return nil, err
+		}
+		if schema != current {
+			name = QualifyTableName(schema, name)
+		}
+		if table {
+			body = "TABLE " + body
+		}
+		objects = append(objects, SchemaObject{
+			Kind:       KindFunction,
+			Name:       name,
+			Definition: fmt.Sprintf("CREATE MACRO %s(%s) AS %s", quoteTableName(d, name), params, body),
+		})
+	}
+	return objects, rows.Err()
+}
+
+// CreateTableStatement reads the statement DuckDB keeps for the table, which
+// leaves out its comments; COMMENT ON statements follow for them.
+func (d duckdbDialect) CreateTableStatement(db *gorm.DB, tableName string) (string, error) {
+	var stmt string
+	err := db.Raw(`SELECT sql FROM duckdb_tables()
+            WHERE database_name = current_database() AND schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?`,
+		schemaTableArgs(tableName)...).Row().Scan(&stmt)
+	if err != nil {
+		return "", err
+	}
+	schema, table := SplitTableName(tableName)
+	comments, err := duckdbCatalog.introspectComments(db, tableName)
+	if err != nil {
+		return "", err
+	}
+	stmts := append([]string{qualifyCreateStatement(d, stmt, schema, table)}, commentStatements(d, tableName, comments)...)
+	return strings.Join(stmts, "\n"), nil
+}
+
+// duckdbCatalog reads the duckdb_* table functions. Constraints keep their
+// columns as lists, which are unnested by position. UNIQUE constraints are
+// reported as unique indexes, as on PostgreSQL.
+var duckdbCatalog = catalogQueries{
+	args: schemaTableArgs,
+	columns: `
+            SELECT column_name, data_type, is_nullable, column_default, COALESCE(column_default, '') LIKE 'nextval(%'
+            FROM duckdb_columns()
+            WHERE database_name = current_database() AND schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+            ORDER BY column_index
+        `,
+	primaryKey: `
+            SELECT unnest(constraint_column_names)
+            FROM duckdb_constraints()
+            WHERE database_name = current_database() AND schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+              AND constraint_type = 'PRIMARY KEY'
+        `,
+	indexes: `
+            WITH keys AS (
+                SELECT constraint_name AS name, true AS is_unique, constraint_column_names AS columns, schema_name, table_name
+                FROM duckdb_constraints()
+                WHERE database_name = current_database() AND constraint_type = 'UNIQUE'
+                UNION ALL
+                SELECT index_name, is_unique, list_transform(string_split(trim(expressions, '[]'), ','), c -> trim(c, ' "')), schema_name, table_name
+                FROM duckdb_indexes()
+                WHERE database_name = current_database() AND NOT is_primary
+            )
+            SELECT name, is_unique, columns[i]
+            FROM (SELECT *, generate_subscripts(columns, 1) AS i FROM keys)
+            WHERE schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+            ORDER BY name, i
+        `,
+	foreignKeys: `
+            SELECT constraint_name, constraint_column_names[i], referenced_table, referenced_column_names[i]
+            FROM (
+                SELECT *, generate_subscripts(constraint_column_names, 1) AS i
+                FROM duckdb_constraints()
+                WHERE database_name = current_database() AND constraint_type = 'FOREIGN KEY'
+            )
+            WHERE schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+            ORDER BY constraint_name, i
+        `,
+	checks: `
+            SELECT constraint_name, expression
+            FROM duckdb_constraints()
+            WHERE database_name = current_database() AND schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+              AND constraint_type = 'CHECK'
+            ORDER BY constraint_name
+        `,
+	comments: `
+            SELECT comment_column, comment_text
+            FROM (
+                SELECT database_name, schema_name, table_name, '' AS comment_column, comment AS comment_text, -1 AS comment_position
+                FROM duckdb_tables()
+                UNION ALL
+                SELECT database_name, schema_name, table_name, column_name, comment, column_index
+                FROM duckdb_columns()
+            )
+            WHERE database_name = current_database() AND schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+              AND comment_text <> ''
+            ORDER BY comment_position
+        `,
+}
+
+func (duckdbDialect) PrimaryKeyColumns(db *gorm.DB, tableName string) ([]string, error) {
+	return duckdbCatalog.primaryKeyColumns(db, tableName)
+}
+
+func (duckdbDialect) IntrospectTable(db *gorm.DB, tableName string) (*Table, error) {
+	t, err := duckdbCatalog.introspect(db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	// DuckDB foreign keys cannot leave the schema of their table
+	schema, _ := SplitTableName(tableName)
+	for i, fk := range t.ForeignKeys {
+		t.ForeignKeys[i].RefTable = QualifyTableName(schema, fk.RefTable)
+	}
+	return t, nil
+}
+
+// ForeignKeysInCreateTable is true, as DuckDB cannot add foreign keys to an
+// existing table.
+func (duckdbDialect) ForeignKeysInCreateTable() bool {
+	return true
+}
+
+func (duckdbDialect) AddForeignKeySQL(tableName string, fk ForeignKey) string {
+	return fmt.Sprintf("-- DuckDB cannot add foreign key %s to existing table %s; rebuild the table", fk.Name, tableName)
+}
+
+func (duckdbDialect) DropForeignKeySQL(tableName string, fk ForeignKey) string {
+	return rebuildTableComment("DuckDB", "drop foreign key "+fk.Name, tableName)
+}
+
+func (duckdbDialect) AddCheckSQL(tableName string, c Check) string {
+	return rebuildTableComment("DuckDB", "add check "+c.Expression, tableName)
+}
+
+func (duckdbDialect) DropCheckSQL(tableName string, c Check) string {
+	return rebuildTableComment("DuckDB", "drop check "+c.Expression, tableName)
+}
+
+// AlterColumnSQL uses the PostgreSQL statements, which DuckDB supports.
+func (duckdbDialect) AlterColumnSQL(tableName string, c ColumnChange) []string {
+	return postgresDialect{}.AlterColumnSQL(tableName, c)
+}
+
+// EnumType types enum columns with an inline ENUM, which DuckDB also
+// reports for the columns of named enum types.
+func (duckdbDialect) EnumType(c Column) (string, bool) {
+	return inlineEnumType(c.Enum), true
+}
+
+func (duckdbDialect) AlterPrimaryKeySQL(tableName string, from, to []string) []string {
+	return []string{rebuildTableComment("DuckDB", "change the primary key", tableName)}
+}
+
+// Literal renders the nested DuckDB types (LIST, STRUCT, MAP) as DuckDB
+// literals, and uses dbType to tell UUIDs from BLOBs, both scanned as bytes,
+// and JSON from nested types. Nested values are rendered without a type.
+func (d duckdbDialect) Literal(val any, dbType string) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if dbType == "UUID" && len(v) == 16 {
+			return "'" + uuidString(v) + "'"
+		}
+		return duckdbBlobLiteral(v)
+	case time.Time:
+		switch dbType {
+		case "DATE":
+			return "'" + v.Format("2006-01-02") + "'"
+		case "TIME":
+			return "'" + v.Format("15:04:05.999999") + "'"
+		case "TIMESTAMPTZ":
+			return "'" + v.Format("2006-01-02 15:04:05.999999Z07:00") + "'"
+		}
+	case float64:
+		if special, ok := duckdbSpecialFloat(v); ok {
+			return special
+		}
+	case float32:
+		if special, ok := duckdbSpecialFloat(float64(v)); ok {
+			return special
+		}
+	case *big.Int:
+		return v.String()
+	case duckdb.Decimal:
+		return v.String()
+	case duckdb.Interval:
+		return fmt.Sprintf("INTERVAL '%d months %d days %d microseconds'", v.Months, v.Days, v.Micros)
+	}
+	switch v := val.(type) {
+	case []any, map[string]any:
+		// JSON objects and arrays are scanned like nested types, but the
+		// driver reports the type of their text
+		if dbType == "JSON" || dbType == "VARCHAR" {
+			if encoded, err := json.Marshal(v); err == nil {
+				return "'" + escapeSQLString(string(encoded)) + "'"
+			}
+		}
+	}
+
+	switch v := val.(type) {
+	case []any:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			elems[i] = d.Literal(elem, "")
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case map[string]any:
+		// Structs are cast by field name, so the order does not matter
+		fields := make([]string, 0, len(v))
+		for name, field := range v {
+			fields = append(fields, fmt.Sprintf("'%s': %s", escapeSQLString(name), d.Literal(field, "")))
+		}
+		sort.Strings(fields)
+		return "{" + strings.Join(fields, ", ") + "}"
+	case duckdb.Map:
+		entries := make([]string, 0, len(v))
+		for key, value := range v {
+			entries = append(entries, d.Literal(key, "")+": "+d.Literal(value, ""))
+		}
+		sort.Strings(entries)
+		return "MAP {" + strings.Join(entries, ", ") + "}"
+	}
+	return d.StandardSQL.Literal(val, dbType)
+}
+
+// duckdbSpecialFloat renders infinities and NaN, which have no numeric literal.
+func duckdbSpecialFloat(f float64) (string, bool) {
+	switch {
+	case math.IsNaN(f):
+		return "'nan'", true
+	case math.IsInf(f, 1):
+		return "'inf'", true
+	case math.IsInf(f, -1):
+		return "'-inf'", true
+	}
+	return "", false
+}
+
+// duckdbBlobLiteral renders b with \xNN escapes for the bytes that are not
+// printable ASCII.
+func duckdbBlobLiteral(b []byte) string {
+	var out strings.Builder
+	out.WriteString("'")
+	for _, c := range b {
+		switch {
+		case c == '\'':
+			out.WriteString("''")
+		case c == '\\' || c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&out, `\x%02X`, c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteString("'::BLOB")
+	return out.String()
+}
+
+// DropObjectSQL drops functions as macros, DuckDB's only routines. Table
+// macros, listed as CREATE MACRO ... AS TABLE, are dropped apart.
+func (d duckdbDialect) DropObjectSQL(obj SchemaObject) string {
+	if obj.Kind == KindFunction {
+		table := ""
+		if strings.Contains(obj.Definition, ") AS TABLE ") {
+			table = "TABLE "
+		}
+		return fmt.Sprintf("DROP MACRO %sIF EXISTS %s;", table, quoteTableName(d, obj.Name))
+	}
+	return d.StandardSQL.DropObjectSQL(obj)
+}
+
+// TruncateTables deletes every row, one table after the other. DuckDB
+// always enforces foreign keys, so tables still referenced by rows of tables
+// outside tableNames cannot be emptied.
+func (d duckdbDialect) TruncateTables(db *gorm.DB, tableNames []string) error {
+	for _, stmt := range eachTable(d, "DELETE FROM %s", tableNames) {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d duckdbDialect) DropTables(db *gorm.DB, tableNames []string) error {
+	for _, stmt := range eachTable(d, "DROP TABLE IF EXISTS %s", tableNames) {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duckdbSequencePattern matches the sequence of a nextval column default.
+var duckdbSequencePattern = regexp.MustCompile(`^nextval\('((?:[^']|'')+)'\)$`)
+
+// duckdbSequences returns the sequences the column defaults of tableName
+// draw from, unquoted.
+func duckdbSequences(db *gorm.DB, tableName string) ([]string, error) {
+	var defaults []string
+	err := db.Raw(`SELECT column_default FROM duckdb_columns()
+            WHERE database_name = current_database() AND schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+              AND column_default LIKE 'nextval(%'
+            ORDER BY column_index`, schemaTableArgs(tableName)...).Scan(&defaults).Error
+	if err != nil {
+		return nil, err
+	}
+	var sequences []string
+	for _, def := range defaults {
+		if m := duckdbSequencePattern.FindStringSubmatch(def); m != nil {
+			tokens, err := tokenizeDDL(strings.ReplaceAll(m[1], "''", "'"))
+			if err != nil {
+				return nil, err
+			}
+			name, _ := parseDDLName(tokens, 0)
+			sequences = append(sequences, name)
+		}
+	}
+	return sequences, nil
+}
+
+// ResetSequences does nothing, as a DuckDB sequence cannot be moved once
+// created. export --sequences recreates sequences at their current value.
+func (duckdbDialect) ResetSequences(db *gorm.DB, tableName string) error {
+	return nil
+}
+
+// SequencesSQL creates the sequences of the column defaults before the
+// table, starting at their next value.
+func (d duckdbDialect) SequencesSQL(db *gorm.DB, tableName string) (before, after []string, err error) {
+	sequences, err := duckdbSequences(db, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	tableSchema, _ := SplitTableName(tableName)
+	for _, sequence := range sequences {
+		schema, name := SplitTableName(sequence)
+		if schema == "" {
+			schema = tableSchema
+		}
+		// The reported statement starts at the next value of the sequence,
+		// with the bare name unquoted
+		var stmt string
+		err := db.Raw(`SELECT sql FROM duckdb_sequences()
+                WHERE database_name = current_database() AND schema_name = COALESCE(NULLIF(?, ''), current_schema()) AND sequence_name = ?`,
+			schema, name).Row().Scan(&stmt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sequence %s: %w", sequence, err)
+		}
+		before = append(before, strings.Replace(stmt, "CREATE SEQUENCE "+name+" ",
+			"CREATE SEQUENCE IF NOT EXISTS "+quoteTableName(d, QualifyTableName(schema, name))+" ", 1))
+	}
+	return before, nil, nil
+}
+
+func (duckdbDialect) AcquireLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	return acquireTableLock(db, name, timeout)
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+)
+
+func init() {
+	RegisterDialect(mssqlDialect{
+		StandardSQL: StandardSQL{Quote: func(name string) string {
+			return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+		}},
+		outputOnly: outputOnly{"SQL Server"},
+	})
+}
+
+// mssqlDialect renders SQL Server scripts for export --target-dialect. It is
+// output-only; see outputOnly.
+type mssqlDialect struct {
+	StandardSQL
+	outputOnly
+}
+
+func (mssqlDialect) Name() string {
+	return "mssql"
+}
+
+func (d mssqlDialect) CreateSchemaSQL(schema string) string {
+	return fmt.Sprintf("IF SCHEMA_ID(N'%s') IS NULL EXEC(N'CREATE SCHEMA %s');",
+		escapeSQLString(schema), escapeSQLString(d.QuoteIdentifier(schema)))
+}
+
+// columnType maps a column type read from another database to SQL Server.
+// Unbounded text in keys becomes nvarchar(450), the longest that fits an
+// index key.
+func (mssqlDialect) columnType(t string, key bool) string {
+	base, params := baseType(t)
+	n := typeLength(params)
+	switch base {
+	case "boolean":
+		return "bit"
+	case "tinyint", "smallint", "year":
+		return "smallint"
+	case "mediumint", "integer":
+		return "int"
+	case "bigint":
+		return "bigint"
+	case "numeric":
+		if len(params) > 0 {
+			return "decimal(" + strings.Join(params, ", ") + ")"
+		}
+		return "decimal(38, 10)"
+	case "real":
+		return "real"
+	case "double precision":
+		return "float"
+	case "money":
+		return "money"
+	case "varchar", "nvarchar":
+		if n > 0 && n <= 4000 {
+			return fmt.Sprintf("nvarchar(%d)", n)
+		}
+	case "char", "nchar":
+		if n > 0 && n <= 4000 {
+			return fmt.Sprintf("nchar(%d)", n)
+		}
+		return "nchar(1)"
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob":
+		return "varbinary(max)"
+	case "binary":
+		if n > 0 && n <= 8000 {
+			return fmt.Sprintf("binary(%d)", n)
+		}
+		return "varbinary(max)"
+	case "varbinary":
+		if n > 0 && n <= 8000 {
+			return fmt.Sprintf("varbinary(%d)", n)
+		}
+		return "varbinary(max)"
+	case "uuid":
+		return "uniqueidentifier"
+	case "date":
+		return "date"
+	case "time", "timetz":
+		return "time"
+	case "timestamp", "datetime":
+		return "datetime2"
+	case "timestamptz":
+		return "datetimeoffset"
+	}
+	// text, json, enums, arrays and anything else SQL Server has no type for
+	if key {
+		return "nvarchar(450)"
+	}
+	return "nvarchar(max)"
+}
+
+// columnDefinition renders c with IDENTITY for auto-incremented columns. It
+// reports false when the default could not be translated.
+func (d mssqlDialect) columnDefinition(c Column, key bool) (string, bool) {
+	def := d.QuoteIdentifier(c.Name) + " " + d.columnType(c.Type, key)
+	ok := true
+	if c.AutoIncrement {
+		def += " IDENTITY(1,1)"
+	} else if c.Default != nil {
+		var expr string
+		if expr, ok = translateDefault(*c.Default, "SYSDATETIME()", "CAST(SYSDATETIME() AS date)"); ok {
+			if stringPattern.MatchString(expr) {
+				expr = "N" + expr
+			}
+			def += " DEFAULT " + expr
+		}
+	}
+	if c.Nullable {
+		def += " NULL"
+	} else {
+		def += " NOT NULL"
+	}
+	return def, ok
+}
+
+func (d mssqlDialect) CreateTableSQL(table *Table) []string {
+	return translatedCreateTable(d, table, d.columnDefinition)
+}
+
+// CommentSQL sets comments as MS_Description extended properties, which
+// SQL Server tools show as descriptions. Unqualified tables are taken to be
+// in dbo, the default schema.
+func (mssqlDialect) CommentSQL(tableName, column, comment string) string {
+	schema, table := SplitTableName(tableName)
+	if schema == "" {
+		schema = "dbo"
+	}
+	stmt := fmt.Sprintf("EXEC sp_addextendedproperty N'MS_Description', N'%s', N'SCHEMA', N'%s', N'TABLE', N'%s'",
+		escapeSQLString(comment), escapeSQLString(schema), escapeSQLString(table))
+	if column != "" {
+		stmt += fmt.Sprintf(", N'COLUMN', N'%s'", escapeSQLString(column))
+	}
+	return stmt + ";"
+}
+
+// DropIndexSQL drops idx by table, as SQL Server indexes belong to their table.
+func (d mssqlDialect) DropIndexSQL(tableName string, idx Index) string {
+	return fmt.Sprintf("DROP INDEX %s ON %s;", d.QuoteIdentifier(idx.Name), quoteTableName(d, tableName))
+}
+
+// AddColumnSQL adds c with a translated type, as ADD without COLUMN.
+func (d mssqlDialect) AddColumnSQL(tableName string, c Column) []string {
+	return addTranslatedColumn(d, tableName, c, d.columnDefinition, "ALTER TABLE %s ADD %s;")
+}
+
+// AlterColumnSQL changes type and nullability; SQL Server keeps defaults as
+// separate constraints.
+func (d mssqlDialect) AlterColumnSQL(tableName string, c ColumnChange) []string {
+	col := c.Source
+	col.AutoIncrement, col.Default = false, nil
+	def, _ := d.columnDefinition(col, false)
+	return []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s;", quoteTableName(d, tableName), def)}
+}
+
+// AlterPrimaryKeySQL cannot drop the old key, whose constraint name SQL
+// Server generates.
+func (d mssqlDialect) AlterPrimaryKeySQL(tableName string, from, to []string) []string {
+	var stmts []string
+	if len(from) > 0 {
+		stmts = append(stmts, fmt.Sprintf("-- Drop the primary key constraint of table %s first", tableName))
+	}
+	if len(to) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", quoteTableName(d, tableName), quoteNames(d, to)))
+	}
+	return stmts
+}
+
+// Literal renders strings as national (N-prefixed) literals, binary values
+// as 0x hex and dates and times with CONVERT.
+func (mssqlDialect) Literal(val any, dbType string) string {
+	kind := valueKind(dbType)
+	if kind >= valueDate && val != nil {
+		if t, ok := temporalValue(val); ok {
+			switch kind {
+			case valueDate:
+				return fmt.Sprintf("CONVERT(DATE, '%s', 23)", t.Format("2006-01-02"))
+			case valueTime:
+				return fmt.Sprintf("CONVERT(TIME, '%s')", t.Format("15:04:05.0000000"))
+			case valueTimestampTZ:
+				return fmt.Sprintf("CONVERT(DATETIMEOFFSET, '%s', 127)", t.Format("2006-01-02T15:04:05.0000000Z07:00"))
+			default:
+				return fmt.Sprintf("CONVERT(DATETIME2, '%s', 121)", t.Format("2006-01-02 15:04:05.0000000"))
+			}
+		}
+	}
+
+	switch v := val.(type) {
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		if dbType == "UUID" && len(v) == 16 {
+			return "'" + uuidString(v) + "'"
+		}
+		if kind == valueBinary {
+			return "0x" + strings.ToUpper(hex.EncodeToString(v))
+		}
+		return mssqlString(string(v))
+	case string:
+		return mssqlString(v)
+	case float32:
+		return mssqlFloat(float64(v))
+	case float64:
+		return mssqlFloat(v)
+	}
+	if lit, ok := portableLiteral(val, mssqlString); ok {
+		return lit
+	}
+	return sqlLiteral(val)
+}
+
+func mssqlString(s string) string {
+	return "N'" + escapeSQLString(s) + "'"
+}
+
+// mssqlFloat renders NULL for infinities and NaN, which SQL Server cannot
+// store.
+func mssqlFloat(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "NULL"
+	}
+	return sqlLiteral(f)
+}
+
+// InsertSQL writes statements of at most 1000 rows, the most SQL Server
+// accepts in one VALUES list.
+func (d mssqlDialect) InsertSQL(tableName string, columns []string, rows [][]string) string {
+	var b strings.Builder
+	for _, batch := range splitRows(rows, 1000) {
+		values := make([]string, len(batch))
+		for i, row := range batch {
+			values[i] = "(" + strings.Join(row, ", ") + ")"
+		}
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES\n%s;\n", quoteTableName(d, tableName), quoteNames(d, columns), strings.Join(values, ",\n"))
+	}
+	return b.String()
+}
+
+// DataFileSQL lets the data file insert the ids of IDENTITY columns.
+func (d mssqlDialect) DataFileSQL(table *Table) (before, after []string) {
+	for _, c := range table.Columns {
+		if c.AutoIncrement {
+			name := quoteTableName(d, table.Name)
+			return []string{fmt.Sprintf("SET IDENTITY_INSERT %s ON;", name)}, []string{fmt.Sprintf("SET IDENTITY_INSERT %s OFF;", name)}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMSSQLDialect returns the registered SQL Server dialect, which quotes
+// names with brackets.
+func testMSSQLDialect(t *testing.T) mssqlDialect {
+	t.Helper()
+	d, err := LookupDialect("mssql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(mssqlDialect)
+}
+
+func TestMSSQLColumnType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		key  bool
+		want string
+	}{
+		{"tinyint(1)", false, "bit"},
+		{"boolean", false, "bit"},
+		{"int(11)", false, "int"},
+		{"int(10) unsigned", false, "bigint"},
+		{"bigint(20) unsigned", false, "decimal(20)"},
+		{"hugeint", false, "decimal(38)"},
+		{"decimal(10,2)", false, "decimal(10, 2)"},
+		{"numeric", false, "decimal(38, 10)"},
+		{"float", false, "real"},
+		{"float(53)", false, "float"},
+		{"character varying(20)", false, "nvarchar(20)"},
+		{"varchar(5000)", false, "nvarchar(max)"},
+		{"char(3)", false, "nchar(3)"},
+		{"text", false, "nvarchar(max)"},
+		{"text", true, "nvarchar(450)"},
+		{"jsonb", false, "nvarchar(max)"},
+		{"integer[]", false, "nvarchar(max)"},
+		{"bytea", false, "varbinary(max)"},
+		{"binary(16)", false, "binary(16)"},
+		{"varbinary(9000)", false, "varbinary(max)"},
+		{"uuid", true, "uniqueidentifier"},
+		{"timestamp without time zone", false, "datetime2"},
+		{"TIMESTAMP(3) WITH TIME ZONE", false, "datetimeoffset"},
+		{"time", false, "time"},
+	}
+	d := testMSSQLDialect(t)
+	for _, tt := range tests {
+		if got := d.columnType(tt.typ, tt.key); got != tt.want {
+			t.Errorf("columnType(%q, %v) = %q, want %q", tt.typ, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMSSQLLiteral(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 6, 123456700, time.FixedZone("", 2*3600))
+	tests := []struct {
+		val    any
+		dbType string
+		want   string
+	}{
+		{nil, "TEXT", "NULL"},
+		{true, "BOOLEAN", "1"},
+		{false, "BOOLEAN", "0"},
+		{int64(42), "INTEGER", "42"},
+		{"O'Brien", "TEXT", "N'O''Brien'"},
+		{[]byte("plain"), "VARCHAR", "N'plain'"},
+		{[]byte{0x00, 0xab, 0xff}, "BLOB", "0x00ABFF"},
+		{[]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}, "UUID",
+			"'12345678-9abc-def0-1234-56789abcdef0'"},
+		{1.5, "DOUBLE", "1.5"},
+		{math.Inf(1), "DOUBLE", "NULL"},
+		{math.NaN(), "REAL", "NULL"},
+		{ts, "DATE", "CONVERT(DATE, '2024-03-09', 23)"},
+		{ts, "TIME", "CONVERT(TIME, '14:05:06.1234567')"},
+		{ts, "TIMESTAMP", "CONVERT(DATETIME2, '2024-03-09 14:05:06.1234567', 121)"},
+		{ts, "TIMESTAMPTZ", "CONVERT(DATETIMEOFFSET, '2024-03-09T14:05:06.1234567+02:00', 127)"},
+		{"2024-03-09 14:05:06", "DATETIME", "CONVERT(DATETIME2, '2024-03-09 14:05:06.0000000', 121)"},
+		{[]any{int64(1), "a"}, "INTEGER[]", `N'[1,"a"]'`},
+	}
+	d := testMSSQLDialect(t)
+	for _, tt := range tests {
+		if got := d.Literal(tt.val, tt.dbType); got != tt.want {
+			t.Errorf("Literal(%#v, %q) = %s, want %s", tt.val, tt.dbType, got, tt.want)
+		}
+	}
+}
+
+// numberedRows returns n rows of a single column numbered from 1.
+func numberedRows(n int) [][]string {
+	rows := make([][]string, n)
+	for i := range rows {
+		rows[i] = []string{sqlLiteral(int64(i + 1))}
+	}
+	return rows
+}
+
+func TestMSSQLInsertSQL(t *testing.T) {
+	d := testMSSQLDialect(t)
+	got := d.InsertSQL("sales.orders", []string{"id", "note"}, [][]string{{"1", "N'a'"}, {"2", "NULL"}})
+	want := "INSERT INTO [sales].[orders] ([id], [note]) VALUES\n(1, N'a'),\n(2, NULL);\n"
+	if got != want {
+		t.Errorf("InsertSQL = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		rows       int
+		statements int
+	}{
+		{1, 1},
+		{1000, 1},
+		{1001, 2},
+		{2500, 3},
+	}
+	for _, tt := range tests {
+		sql := d.InsertSQL("t", []string{"id"}, numberedRows(tt.rows))
+		if n := strings.Count(sql, "INSERT INTO"); n != tt.statements {
+			t.Errorf("%d rows: %d statements, want %d", tt.rows, n, tt.statements)
+		}
+		if n := strings.Count(sql, "("); n != tt.rows+tt.statements {
+			t.Errorf("%d rows: %d value lists, want %d", tt.rows, n-tt.statements, tt.rows)
+		}
+		if !strings.HasSuffix(sql, "("+sqlLiteral(int64(tt.rows))+");\n") {
+			t.Errorf("%d rows: last statement does not end with the last row", tt.rows)
+		}
+	}
+}
+
+func TestMSSQLDataFileSQL(t *testing.T) {
+	tests := []struct {
+		table         *Table
+		before, after string
+	}{
+		{
+			&Table{Name: "sales.orders", Columns: []Column{{Name: "id", AutoIncrement: true}, {Name: "note"}}},
+			"SET IDENTITY_INSERT [sales].[orders] ON;",
+			"SET IDENTITY_INSERT [sales].[orders] OFF;",
+		},
+		{&Table{Name: "tags", Columns: []Column{{Name: "name"}}}, "", ""},
+	}
+	d := testMSSQLDialect(t)
+	for _, tt := range tests {
+		before, after := d.DataFileSQL(tt.table)
+		if got := strings.Join(before, "\n"); got != tt.before {
+			t.Errorf("%s: before = %q, want %q", tt.table.Name, got, tt.before)
+		}
+		if got := strings.Join(after, "\n"); got != tt.after {
+			t.Errorf("%s: after = %q, want %q", tt.table.Name, got, tt.after)
+		}
+	}
+}
+
+func TestExportTranslatedForeignKeysSQL(t *testing.T) {
+	db := openTestSQLite(t,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers(id))",
+		"CREATE TABLE order_lines (order_id INTEGER REFERENCES orders(id), line INTEGER)",
+		"CREATE TABLE tags (name TEXT)",
+	)
+	dir := t.TempDir()
+
+	tests := []struct {
+		dialect string
+		tables  []string
+		want    []string
+	}{
+		{"mssql", []string{"order_lines", "orders", "customers"}, []string{
+			"ALTER TABLE [orders] ADD",
+			"ALTER TABLE [order_lines] ADD",
+		}},
+		{"oracle", []string{"orders", "customers"}, []string{`ALTER TABLE "orders" ADD`}},
+		{"mssql", []string{"tags"}, nil},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(dir, tt.dialect+"_"+strings.Join(tt.tables, "_")+".sql")
+		written, err := ExportTranslatedForeignKeysSQL(db, tt.tables, filename, tt.dialect)
+		if err != nil {
+			t.Fatalf("%s %v: %v", tt.dialect, tt.tables, err)
+		}
+		if written != (tt.want != nil) {
+			t.Errorf("%s %v: written = %v, want %v", tt.dialect, tt.tables, written, tt.want != nil)
+		}
+		if !written {
+			continue
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != len(tt.want) {
+			t.Fatalf("%s %v: got\n%s\nwant %d statements", tt.dialect, tt.tables, content, len(tt.want))
+		}
+		for i, prefix := range tt.want {
+			if !strings.HasPrefix(lines[i], prefix) {
+				t.Errorf("%s %v: statement %d = %q, want it to start with %q", tt.dialect, tt.tables, i, lines[i], prefix)
+			}
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterDialect(mysqlDialect{StandardSQL{Quote: func(name string) string {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}}})
+}
+
+// mysqlDialect is MySQL and MariaDB. Schemas are databases; unqualified names
+// refer to the database of the DSN.
+type mysqlDialect struct {
+	StandardSQL
+}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Open(dsn string, config *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       dsn,   // data source name
+		DefaultStringSize:         256,   // default size for string fields
+		DisableDatetimePrecision:  true,  // disable datetime precision, which not supported before MySQL 5.6
+		DontSupportRenameIndex:    true,  // drop & create when rename index, rename index not supported before MySQL 5.7, MariaDB
+		DontSupportRenameColumn:   true,  //  when rename column, rename column not supported before MySQL 8, MariaDB
+		SkipInitializeWithVersion: false, // auto configure based on currently MySQL version
+	}), config)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetMaxIdleConns(20)
+	sqlDB.SetConnMaxLifetime(30 * time.Minute)
+	return db, nil
+}
+
+func (mysqlDialect) CurrentSchema(db *gorm.DB) (string, error) {
+	var schema string
+	err := db.Raw("SELECT COALESCE(DATABASE(), '')").Row().Scan(&schema)
+	return schema, err
+}
+
+func (d mysqlDialect) ListTables(db *gorm.DB, schemas []string, all bool) ([]SchemaTable, error) {
+	current, err := d.CurrentSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	return informationSchemaTables(db, schemas, all, current,
+		"table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')", "")
+}
+
+func (mysqlDialect) TableExists(db *gorm.DB, tableName string) (bool, error) {
+	return informationSchemaTableExists(db, tableName, "DATABASE()")
+}
+
+// ListObjects lists views, triggers and routines from information_schema and
+// reads their statements with SHOW CREATE. Indexes are part of SHOW CREATE
+// TABLE, so none are standalone. DEFINER clauses are left out, so objects are
+// created for the importing user, and so are the qualifiers of the current
+// database, which SHOW CREATE VIEW adds to every name.
+func (d mysqlDialect) ListObjects(db *gorm.DB, schemas []string, all bool) ([]SchemaObject, error) {
+	current, err := d.CurrentSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	where := "schema_name IN ?"
+	args := []any{schemas}
+	if all {
+		where = "schema_name NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')"
+		args = nil
+	}
+
+	// Triggers of one table and event fire in the order they were created
+	objects, err := queryObjects(db, current, fmt.Sprintf(`
+            SELECT kind, schema_name, name, table_name, '', '' FROM (
+                SELECT 'view' AS kind, table_schema AS schema_name, table_name AS name, '' AS table_name, 0 AS seq
+                FROM information_schema.views
+                UNION ALL
+                SELECT LOWER(routine_type), routine_schema, routine_name, '', 0
+                FROM information_schema.routines
+                UNION ALL
+                SELECT 'trigger', trigger_schema, trigger_name, event_object_table, action_order
+                FROM information_schema.triggers
+            ) o
+            WHERE %s
+            ORDER BY schema_name, seq, name`, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		obj := &objects[i]
+		show, column := "SHOW CREATE "+strings.ToUpper(obj.Kind)+" ", 2
+		if obj.Kind == KindView {
+			column = 1
+		}
+		stmt, err := mysqlShowCreate(db, show+quoteTableName(d, obj.Name), column)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", obj.Kind, obj.Name, err)
+		}
+		stmt = mysqlDefinerPattern.ReplaceAllString(stmt, "")
+
+		schema, name := SplitTableName(obj.Name)
+		if schema == "" {
+			stmt = strings.ReplaceAll(stmt, d.QuoteIdentifier(current)+".", "")
+		} else {
+			stmt = qualifyCreateStatement(d, stmt, schema, name)
+			if obj.Kind == KindTrigger {
+				_, table := SplitTableName(obj.Table)
+				stmt = strings.Replace(stmt, " ON "+d.QuoteIdentifier(table), " ON "+quoteTableName(d, obj.Table), 1)
+			}
+		}
+		obj.Definition = stmt
+	}
+	return objects, nil
+}
+
+// mysqlDefinerPattern matches the DEFINER clause of a SHOW CREATE statement.
+var mysqlDefinerPattern = regexp.MustCompile("(?i)\\s+DEFINER\\s*=\\s*(?:`(?:[^`]|``)*`|'(?:[^']|'')*'|[^\\s@]+)@(?:`(?:[^`]|``)*`|'(?:[^']|'')*'|\\S+)")
+
+// mysqlShowCreate runs a SHOW CREATE statement and returns the statement it
+// reports in column, which is NULL without the privileges to read it.
+func mysqlShowCreate(db *gorm.DB, show string, column int) (string, error) {
+	rows, err := db.Raw(show).Rows()
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("not found")
+	}
+	values := make([]sql.NullString, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return "", err
+	}
+	if !values[column].Valid {
+		return "", fmt.Errorf("definition not readable; the user needs privileges on it")
+	}
+	return values[column].String, nil
+}
+
+func (d mysqlDialect) CreateTableStatement(db *gorm.DB, tableName string) (string, error) {
+	row := db.Raw(fmt.Sprintf("SHOW CREATE TABLE %s", quoteTableName(d, tableName))).Row()
+	var name, stmt string
+	if err := row.Scan(&name, &stmt); err != nil {
+		return "", err
+	}
+	schema, table := SplitTableName(tableName)
+	return qualifyCreateStatement(d, stmt, schema, table), nil
+}
+
+// mysqlPartitionPattern matches the partitioning SHOW CREATE TABLE reports
+// on the lines after the table options, in a version comment on MySQL.
+var mysqlPartitionPattern = regexp.MustCompile(`(?is)\s*\n\s*(?:/\*!\d+\s*)?PARTITION\s+BY\s.*$`)
+
+// FlatTableStatement is CreateTableStatement without the partitioning.
+func (d mysqlDialect) FlatTableStatement(db *gorm.DB, tableName string) (string, error) {
+	stmt, err := d.CreateTableStatement(db, tableName)
+	if err != nil {
+		return "", err
+	}
+	return mysqlPartitionPattern.ReplaceAllString(stmt, ""), nil
+}
+
+var mysqlCatalog = catalogQueries{
+	args: schemaTableArgs,
+	columns: `
+            SELECT column_name, column_type, is_nullable = 'YES', column_default, extra LIKE '%auto_increment%'
+            FROM information_schema.columns
+            WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?
+            ORDER BY ordinal_position
+        `,
+	primaryKey: `
+            SELECT column_name
+            FROM information_schema.key_column_usage
+            WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ? AND constraint_name = 'PRIMARY'
+            ORDER BY ordinal_position
+        `,
+	indexes: `
+            SELECT index_name, non_unique = 0, column_name
+            FROM information_schema.statistics
+            WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ? AND index_name <> 'PRIMARY'
+            ORDER BY index_name, seq_in_index
+        `,
+	foreignKeys: `
+            SELECT constraint_name, column_name,
+                CASE WHEN referenced_table_schema = DATABASE() THEN referenced_table_name
+                     ELSE CONCAT(referenced_table_schema, '.', referenced_table_name) END,
+                referenced_column_name
+            FROM information_schema.key_column_usage
+            WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ? AND referenced_table_name IS NOT NULL
+            ORDER BY constraint_name, ordinal_position
+        `,
+	checks: `
+            SELECT cc.constraint_name, cc.check_clause
+            FROM information_schema.table_constraints tc
+            JOIN information_schema.check_constraints cc
+              ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+            WHERE tc.table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND tc.table_name = ? AND tc.constraint_type = 'CHECK'
+            ORDER BY cc.constraint_name
+        `,
+	comments: `
+            SELECT comment_column, comment_text
+            FROM (
+                SELECT table_schema, table_name, '' AS comment_column, table_comment AS comment_text, 0 AS comment_position
+                FROM information_schema.tables
+                UNION ALL
+                SELECT table_schema, table_name, column_name, column_comment, ordinal_position
+                FROM information_schema.columns
+            ) c
+            WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ? AND comment_text <> ''
+            ORDER BY comment_position
+        `,
+}
+
+func (mysqlDialect) PrimaryKeyColumns(db *gorm.DB, tableName string) ([]string, error) {
+	return mysqlCatalog.primaryKeyColumns(db, tableName)
+}
+
+func (mysqlDialect) IntrospectTable(db *gorm.DB, tableName string) (*Table, error) {
+	return mysqlCatalog.introspect(db, tableName)
+}
+
+func (d mysqlDialect) CreateSchemaSQL(schema string) string {
+	return fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s;", d.QuoteIdentifier(schema))
+}
+
+// DropIndexSQL drops idx by table, as MySQL indexes belong to their table.
+func (d mysqlDialect) DropIndexSQL(tableName string, idx Index) string {
+	return fmt.Sprintf("DROP INDEX %s ON %s;", d.QuoteIdentifier(idx.Name), quoteTableName(d, tableName))
+}
+
+func (d mysqlDialect) DropForeignKeySQL(tableName string, fk ForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", quoteTableName(d, tableName), d.QuoteIdentifier(fk.Name))
+}
+
+func (d mysqlDialect) DropCheckSQL(tableName string, c Check) string {
+	if c.Name == "" {
+		return d.StandardSQL.DropCheckSQL(tableName, c)
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CHECK %s;", quoteTableName(d, tableName), d.QuoteIdentifier(c.Name))
+}
+
+// AlterColumnSQL redefines the whole column, as MySQL changes type,
+// nullability and default together.
+func (d mysqlDialect) AlterColumnSQL(tableName string, c ColumnChange) []string {
+	return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", quoteTableName(d, tableName), columnDefinition(d, c.Source))}
+}
+
+// CommentSQL sets table comments with ALTER TABLE. Column comments are part
+// of the column definition, which CreateTableSQL declares them in.
+func (d mysqlDialect) CommentSQL(tableName, column, comment string) string {
+	if column != "" {
+		return fmt.Sprintf("-- MySQL cannot set the comment of column %s on table %s apart from its definition", column, tableName)
+	}
+	return fmt.Sprintf("ALTER TABLE %s COMMENT = '%s';", quoteTableName(d, tableName), escapeSQLString(comment))
+}
+
+func (mysqlDialect) ColumnComment(def, comment string) string {
+	return def + " COMMENT '" + escapeSQLString(comment) + "'"
+}
+
+func (mysqlDialect) TableComment(stmt, comment string) string {
+	return stmt + " COMMENT='" + escapeSQLString(comment) + "'"
+}
+
+// EnumType types enum columns with an inline ENUM.
+func (mysqlDialect) EnumType(c Column) (string, bool) {
+	return inlineEnumType(c.Enum), true
+}
+
+func (d mysqlDialect) AlterPrimaryKeySQL(tableName string, from, to []string) []string {
+	var stmts []string
+	if len(from) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY;", quoteTableName(d, tableName)))
+	}
+	if len(to) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", quoteTableName(d, tableName), quoteNames(d, to)))
+	}
+	return stmts
+}
+
+// mysqlForeignKeys switches foreign key checks for one connection.
+var mysqlForeignKeys = foreignKeySwitch{
+	current: "SELECT @@FOREIGN_KEY_CHECKS",
+	disable: "SET FOREIGN_KEY_CHECKS = 0",
+	enable:  "SET FOREIGN_KEY_CHECKS = 1",
+}
+
+func (d mysqlDialect) TruncateTables(db *gorm.DB, tableNames []string) error {
+	return mysqlForeignKeys.run(db, eachTable(d, "TRUNCATE TABLE %s", tableNames)...)
+}
+
+func (d mysqlDialect) DropTables(db *gorm.DB, tableNames []string) error {
+	return mysqlForeignKeys.run(db, eachTable(d, "DROP TABLE IF EXISTS %s", tableNames)...)
+}
+
+func (d mysqlDialect) ResetSequences(db *gorm.DB, tableName string) error {
+	var column string
+	err := db.Raw(`
+            SELECT column_name
+            FROM information_schema.columns
+            WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ? AND extra LIKE '%auto_increment%'
+        `, schemaTableArgs(tableName)...).Row().Scan(&column)
+	if errors.Is(err, sql.ErrNoRows) {
+		// No auto-increment column
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var next, current int64
+	if err := db.Raw(fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) + 1 FROM %s", d.QuoteIdentifier(column), quoteTableName(d, tableName))).Row().Scan(&next); err != nil {
+		return err
+	}
+	err = db.Raw(`SELECT COALESCE(auto_increment, 0) FROM information_schema.tables WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?`,
+		schemaTableArgs(tableName)...).Row().Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if current > next {
+		next = current
+	}
+	return db.Exec(fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", quoteTableName(d, tableName), next)).Error
+}
+
+// SequencesSQL returns nothing, as SHOW CREATE TABLE already carries
+// AUTO_INCREMENT.
+func (mysqlDialect) SequencesSQL(db *gorm.DB, tableName string) (before, after []string, err error) {
+	return nil, nil, nil
+}
+
+func (mysqlDialect) AcquireLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	return acquireMySQLLock(db, name, timeout)
+}
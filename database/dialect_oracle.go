@@ -0,0 +1,262 @@
+package database
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterDialect(oracleDialect{outputOnly: outputOnly{"Oracle"}})
+}
+
+// oracleDialect renders Oracle scripts for export --target-dialect. It is
+// output-only; see outputOnly. Names are quoted as they are, so lowercase
+// names stay lowercase.
+type oracleDialect struct {
+	StandardSQL
+	outputOnly
+}
+
+func (oracleDialect) Name() string {
+	return "oracle"
+}
+
+// CreateSchemaSQL returns "", as Oracle schemas are users created by a DBA.
+func (oracleDialect) CreateSchemaSQL(schema string) string {
+	return ""
+}
+
+// columnType maps a column type read from another database to Oracle.
+// Unbounded text in keys becomes VARCHAR2(1000 CHAR), as CLOBs cannot be
+// indexed.
+func (oracleDialect) columnType(t string, key bool) string {
+	base, params := baseType(t)
+	n := typeLength(params)
+	switch base {
+	case "boolean":
+		return "NUMBER(1)"
+	case "tinyint", "smallint", "year":
+		return "NUMBER(5)"
+	case "mediumint", "integer":
+		return "NUMBER(10)"
+	case "bigint":
+		return "NUMBER(19)"
+	case "numeric":
+		if len(params) > 0 {
+			return "NUMBER(" + strings.Join(params, ", ") + ")"
+		}
+		return "NUMBER"
+	case "real":
+		return "BINARY_FLOAT"
+	case "double precision":
+		return "BINARY_DOUBLE"
+	case "money":
+		return "NUMBER(19, 4)"
+	case "varchar", "nvarchar":
+		if n > 0 && n <= 4000 {
+			return fmt.Sprintf("VARCHAR2(%d CHAR)", n)
+		}
+	case "char", "nchar":
+		if n > 0 && n <= 2000 {
+			return fmt.Sprintf("CHAR(%d CHAR)", n)
+		}
+		return "CHAR(1 CHAR)"
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob":
+		return "BLOB"
+	case "binary", "varbinary":
+		if n > 0 && n <= 2000 {
+			return fmt.Sprintf("RAW(%d)", n)
+		}
+		return "BLOB"
+	case "uuid":
+		return "CHAR(36)"
+	case "date":
+		return "DATE"
+	case "time", "timetz":
+		return "INTERVAL DAY(0) TO SECOND(6)"
+	case "timestamp", "datetime":
+		return "TIMESTAMP"
+	case "timestamptz":
+		return "TIMESTAMP WITH TIME ZONE"
+	}
+	// text, json, enums, arrays and anything else Oracle has no type for
+	if key {
+		return "VARCHAR2(1000 CHAR)"
+	}
+	return "CLOB"
+}
+
+// columnDefinition renders c as an identity column when it is
+// auto-incremented. It reports false when the default could not be
+// translated.
+func (d oracleDialect) columnDefinition(c Column, key bool) (string, bool) {
+	def := d.QuoteIdentifier(c.Name) + " " + d.columnType(c.Type, key)
+	ok := true
+	if c.AutoIncrement {
+		// BY DEFAULT, so the data files can insert the ids
+		def += " GENERATED BY DEFAULT AS IDENTITY"
+	} else if c.Default != nil {
+		var expr string
+		if expr, ok = translateDefault(*c.Default, "SYSTIMESTAMP", "TRUNC(SYSDATE)"); ok {
+			def += " DEFAULT " + expr
+		}
+	}
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	return def, ok
+}
+
+func (d oracleDialect) CreateTableSQL(table *Table) []string {
+	return translatedCreateTable(d, table, d.columnDefinition)
+}
+
+// CreateIndexSQL creates idx in the schema of its table, where Oracle indexes
+// live.
+func (d oracleDialect) CreateIndexSQL(tableName string, idx Index) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	schema, _ := SplitTableName(tableName)
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, quoteTableName(d, QualifyTableName(schema, idx.Name)),
+		quoteTableName(d, tableName), quoteNames(d, idx.Columns))
+}
+
+// AddColumnSQL adds c with a translated type, in Oracle's ADD (...) form.
+func (d oracleDialect) AddColumnSQL(tableName string, c Column) []string {
+	return addTranslatedColumn(d, tableName, c, d.columnDefinition, "ALTER TABLE %s ADD (%s);")
+}
+
+func (d oracleDialect) AlterColumnSQL(tableName string, c ColumnChange) []string {
+	col := c.Source
+	col.AutoIncrement = false
+	def, _ := d.columnDefinition(col, false)
+	if col.Nullable && !c.Target.Nullable {
+		def += " NULL"
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s MODIFY (%s);", quoteTableName(d, tableName), def)}
+}
+
+func (d oracleDialect) AlterPrimaryKeySQL(tableName string, from, to []string) []string {
+	var stmts []string
+	if len(from) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY;", quoteTableName(d, tableName)))
+	}
+	if len(to) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", quoteTableName(d, tableName), quoteNames(d, to)))
+	}
+	return stmts
+}
+
+// Literal renders booleans as 1 and 0, binary values with HEXTORAW and dates
+// and times with TO_DATE and TO_TIMESTAMP.
+func (oracleDialect) Literal(val any, dbType string) string {
+	kind := valueKind(dbType)
+	if kind >= valueDate && val != nil {
+		if t, ok := temporalValue(val); ok {
+			switch kind {
+			case valueDate:
+				return fmt.Sprintf("TO_DATE('%s', 'YYYY-MM-DD')", t.Format("2006-01-02"))
+			case valueTime:
+				return fmt.Sprintf("INTERVAL '0 %s' DAY(0) TO SECOND(6)", t.Format("15:04:05.000000"))
+			case valueTimestampTZ:
+				return fmt.Sprintf("TO_TIMESTAMP_TZ('%s', 'YYYY-MM-DD HH24:MI:SS.FF6 TZH:TZM')", t.Format("2006-01-02 15:04:05.000000 -07:00"))
+			default:
+				return fmt.Sprintf("TO_TIMESTAMP('%s', 'YYYY-MM-DD HH24:MI:SS.FF6')", t.Format("2006-01-02 15:04:05.000000"))
+			}
+		}
+	}
+
+	switch v := val.(type) {
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		if dbType == "UUID" && len(v) == 16 {
+			return "'" + uuidString(v) + "'"
+		}
+		if kind == valueBinary {
+			return "HEXTORAW('" + strings.ToUpper(hex.EncodeToString(v)) + "')"
+		}
+		return oracleString(string(v))
+	case string:
+		return oracleString(v)
+	case float32:
+		return oracleFloat(float64(v))
+	case float64:
+		return oracleFloat(v)
+	}
+	if lit, ok := portableLiteral(val, oracleString); ok {
+		return lit
+	}
+	return sqlLiteral(val)
+}
+
+// oracleStringChunk is the number of characters per literal in long strings,
+// keeping each within Oracle's 4000 byte limit for string literals.
+const oracleStringChunk = 1000
+
+// oracleString renders s as a string literal, or as concatenated CLOBs when
+// it is too long for one literal.
+func oracleString(s string) string {
+	if len(s) <= 4000 {
+		return "'" + escapeSQLString(s) + "'"
+	}
+	var parts []string
+	for len(s) > 0 {
+		end, n := 0, 0
+		for end < len(s) && n < oracleStringChunk {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+			n++
+		}
+		parts = append(parts, "TO_CLOB('"+escapeSQLString(s[:end])+"')")
+		s = s[end:]
+	}
+	return strings.Join(parts, " || ")
+}
+
+// oracleFloat renders infinities and NaN as the BINARY_DOUBLE constants.
+func oracleFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "BINARY_DOUBLE_NAN"
+	case math.IsInf(f, 1):
+		return "BINARY_DOUBLE_INFINITY"
+	case math.IsInf(f, -1):
+		return "-BINARY_DOUBLE_INFINITY"
+	}
+	return sqlLiteral(f)
+}
+
+// InsertSQL writes INSERT ALL statements of at most 1000 rows, as Oracle has
+// no multi-row VALUES.
+func (d oracleDialect) InsertSQL(tableName string, columns []string, rows [][]string) string {
+	into := fmt.Sprintf("    INTO %s (%s) VALUES (", quoteTableName(d, tableName), quoteNames(d, columns))
+	var b strings.Builder
+	for _, batch := range splitRows(rows, 1000) {
+		b.WriteString("INSERT ALL\n")
+		for _, row := range batch {
+			b.WriteString(into + strings.Join(row, ", ") + ")\n")
+		}
+		b.WriteString("SELECT 1 FROM DUAL;\n")
+	}
+	return b.String()
+}
+
+// DataFileSQL moves identity columns past the inserted ids.
+func (d oracleDialect) DataFileSQL(table *Table) (before, after []string) {
+	for _, c := range table.Columns {
+		if c.AutoIncrement {
+			after = append(after, fmt.Sprintf("ALTER TABLE %s MODIFY (%s GENERATED BY DEFAULT AS IDENTITY (START WITH LIMIT VALUE));",
+				quoteTableName(d, table.Name), d.QuoteIdentifier(c.Name)))
+		}
+	}
+	return nil, after
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOracleColumnType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		key  bool
+		want string
+	}{
+		{"tinyint(1)", false, "NUMBER(1)"},
+		{"smallint", false, "NUMBER(5)"},
+		{"int(11)", false, "NUMBER(10)"},
+		{"int(10) unsigned", false, "NUMBER(19)"},
+		{"bigint(20) unsigned", false, "NUMBER(20)"},
+		{"decimal(10,2)", false, "NUMBER(10, 2)"},
+		{"numeric", false, "NUMBER"},
+		{"float", false, "BINARY_FLOAT"},
+		{"double precision", false, "BINARY_DOUBLE"},
+		{"character varying(20)", false, "VARCHAR2(20 CHAR)"},
+		{"varchar(5000)", false, "CLOB"},
+		{"varchar(5000)", true, "VARCHAR2(1000 CHAR)"},
+		{"char(3)", false, "CHAR(3 CHAR)"},
+		{"text", false, "CLOB"},
+		{"text", true, "VARCHAR2(1000 CHAR)"},
+		{"bytea", false, "BLOB"},
+		{"varbinary(16)", false, "RAW(16)"},
+		{"uuid", false, "CHAR(36)"},
+		{"date", false, "DATE"},
+		{"time", false, "INTERVAL DAY(0) TO SECOND(6)"},
+		{"datetime", false, "TIMESTAMP"},
+		{"timestamptz", false, "TIMESTAMP WITH TIME ZONE"},
+	}
+	var d oracleDialect
+	for _, tt := range tests {
+		if got := d.columnType(tt.typ, tt.key); got != tt.want {
+			t.Errorf("columnType(%q, %v) = %q, want %q", tt.typ, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestOracleLiteral(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 6, 123456000, time.FixedZone("", -5*3600))
+	tests := []struct {
+		val    any
+		dbType string
+		want   string
+	}{
+		{nil, "TEXT", "NULL"},
+		{true, "BOOLEAN", "1"},
+		{false, "BOOLEAN", "0"},
+		{int64(-7), "INTEGER", "-7"},
+		{"O'Brien", "TEXT", "'O''Brien'"},
+		{[]byte{0x00, 0xab, 0xff}, "BYTEA", "HEXTORAW('00ABFF')"},
+		{2.25, "DOUBLE", "2.25"},
+		{math.NaN(), "DOUBLE", "BINARY_DOUBLE_NAN"},
+		{math.Inf(1), "DOUBLE", "BINARY_DOUBLE_INFINITY"},
+		{math.Inf(-1), "DOUBLE", "-BINARY_DOUBLE_INFINITY"},
+		{ts, "DATE", "TO_DATE('2024-03-09', 'YYYY-MM-DD')"},
+		{ts, "TIME", "INTERVAL '0 14:05:06.123456' DAY(0) TO SECOND(6)"},
+		{ts, "TIMESTAMP", "TO_TIMESTAMP('2024-03-09 14:05:06.123456', 'YYYY-MM-DD HH24:MI:SS.FF6')"},
+		{ts, "TIMESTAMPTZ", "TO_TIMESTAMP_TZ('2024-03-09 14:05:06.123456 -05:00', 'YYYY-MM-DD HH24:MI:SS.FF6 TZH:TZM')"},
+	}
+	var d oracleDialect
+	for _, tt := range tests {
+		if got := d.Literal(tt.val, tt.dbType); got != tt.want {
+			t.Errorf("Literal(%#v, %q) = %s, want %s", tt.val, tt.dbType, got, tt.want)
+		}
+	}
+}
+
+func TestOracleLiteralLongString(t *testing.T) {
+	var d oracleDialect
+	s := strings.Repeat("é", 2500)
+	got := d.Literal(s, "TEXT")
+	want := "TO_CLOB('" + strings.Repeat("é", 1000) + "') || TO_CLOB('" + strings.Repeat("é", 1000) + "') || TO_CLOB('" + strings.Repeat("é", 500) + "')"
+	if got != want {
+		t.Errorf("Literal of %d characters split into %d parts, want 3", len([]rune(s)), strings.Count(got, "TO_CLOB"))
+	}
+}
+
+func TestOracleInsertSQL(t *testing.T) {
+	var d oracleDialect
+	got := d.InsertSQL("orders", []string{"id", "note"}, [][]string{{"1", "'a'"}, {"2", "NULL"}})
+	want := "INSERT ALL\n" +
+		"    INTO \"orders\" (\"id\", \"note\") VALUES (1, 'a')\n" +
+		"    INTO \"orders\" (\"id\", \"note\") VALUES (2, NULL)\n" +
+		"SELECT 1 FROM DUAL;\n"
+	if got != want {
+		t.Errorf("InsertSQL = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		rows       int
+		statements int
+	}{
+		{1, 1},
+		{1000, 1},
+		{1001, 2},
+		{2500, 3},
+	}
+	for _, tt := range tests {
+		sql := d.InsertSQL("t", []string{"id"}, numberedRows(tt.rows))
+		if n := strings.Count(sql, "INSERT ALL"); n != tt.statements {
+			t.Errorf("%d rows: %d statements, want %d", tt.rows, n, tt.statements)
+		}
+		if n := strings.Count(sql, "SELECT 1 FROM DUAL;"); n != tt.statements {
+			t.Errorf("%d rows: %d statement ends, want %d", tt.rows, n, tt.statements)
+		}
+		if n := strings.Count(sql, "    INTO "); n != tt.rows {
+			t.Errorf("%d rows: %d INTO clauses, want %d", tt.rows, n, tt.rows)
+		}
+	}
+}
+
+func TestOracleDataFileSQL(t *testing.T) {
+	tests := []struct {
+		table *Table
+		after string
+	}{
+		{
+			&Table{Name: "hr.staff", Columns: []Column{{Name: "id", AutoIncrement: true}, {Name: "name"}}},
+			`ALTER TABLE "hr"."staff" MODIFY ("id" GENERATED BY DEFAULT AS IDENTITY (START WITH LIMIT VALUE));`,
+		},
+		{&Table{Name: "tags", Columns: []Column{{Name: "name"}}}, ""},
+	}
+	var d oracleDialect
+	for _, tt := range tests {
+		before, after := d.DataFileSQL(tt.table)
+		if len(before) != 0 {
+			t.Errorf("%s: before = %q, want none", tt.table.Name, before)
+		}
+		if got := strings.Join(after, "\n"); got != tt.after {
+			t.Errorf("%s: after = %q, want %q", tt.table.Name, got, tt.after)
+		}
+	}
+}
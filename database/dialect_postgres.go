@@ -0,0 +1,609 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterDialect(postgresDialect{})
+}
+
+// postgresDialect is PostgreSQL. Schemas are the search_path schemas;
+// unqualified names refer to current_schema().
+type postgresDialect struct {
+	StandardSQL
+}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) Open(dsn string, config *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.New(postgres.Config{
+		DSN:                  dsn,
+		PreferSimpleProtocol: true, // disables implicit prepared statement usage,
+	}), config)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetMaxIdleConns(20)
+	sqlDB.SetConnMaxLifetime(30 * time.Minute)
+	return db, nil
+}
+
+func (postgresDialect) CurrentSchema(db *gorm.DB) (string, error) {
+	var schema string
+	err := db.Raw("SELECT current_schema()").Row().Scan(&schema)
+	return schema, err
+}
+
+// ListTables leaves out partitions, whose rows are read through their
+// partitioned table and which its CREATE TABLE statement creates.
+func (d postgresDialect) ListTables(db *gorm.DB, schemas []string, all bool) ([]SchemaTable, error) {
+	current, err := d.CurrentSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	return informationSchemaTables(db, schemas, all, current,
+		`table_schema NOT IN ('pg_catalog', 'information_schema') AND table_schema NOT LIKE 'pg\_%'`,
+		`NOT EXISTS (SELECT 1 FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+                WHERE n.nspname = table_schema AND c.relname = table_name AND c.relispartition)`)
+}
+
+func (postgresDialect) TableExists(db *gorm.DB, tableName string) (bool, error) {
+	return informationSchemaTableExists(db, tableName, "current_schema()")
+}
+
+// ListObjects reads views, indexes, triggers, routines, enum and composite
+// types and domains from pg_catalog, leaving out the indexes of primary key,
+// unique and exclusion constraints, which CREATE TABLE declares, internal
+// triggers, the indexes and triggers partitions get from their partitioned
+// table, and objects that belong to extensions, which are listed instead:
+// all of them but plpgsql, whatever their schema, as tables of any schema
+// may use their types. Trigger names stay unqualified, as triggers belong to
+// their table. pg_catalog qualifies the names of routines and the tables of
+// indexes and triggers even in the current schema; that qualifier is
+// stripped, as it is from table names.
+func (d postgresDialect) ListObjects(db *gorm.DB, schemas []string, all bool) ([]SchemaObject, error) {
+	var current, prefix string
+	if err := db.Raw("SELECT current_schema(), quote_ident(current_schema()) || '.'").Row().Scan(&current, &prefix); err != nil {
+		return nil, err
+	}
+	where := "nspname IN ?"
+	args := []any{schemas}
+	if all {
+		where = `nspname NOT IN ('pg_catalog', 'information_schema') AND nspname NOT LIKE 'pg\_%'`
+		args = nil
+	}
+
+	objects, err := queryObjects(db, current, fmt.Sprintf(`
+            WITH n AS (SELECT oid, nspname FROM pg_namespace WHERE %s),
+            objects AS (
+                SELECT CASE c.relkind WHEN 'm' THEN 'materialized view' ELSE 'view' END AS kind,
+                    n.nspname AS schema, c.relname AS name, '' AS table_name, '' AS signature,
+                    'CREATE ' || CASE c.relkind WHEN 'm' THEN 'MATERIALIZED ' ELSE '' END || 'VIEW '
+                        || CASE WHEN n.nspname = current_schema() THEN '' ELSE quote_ident(n.nspname) || '.' END
+                        || quote_ident(c.relname) || ' AS' || chr(10) || pg_get_viewdef(c.oid, true) AS definition,
+                    'pg_class'::regclass AS classid, c.oid
+                FROM pg_class c JOIN n ON n.oid = c.relnamespace
+                WHERE c.relkind IN ('v', 'm')
+                UNION ALL
+                SELECT 'index', n.nspname, ic.relname, t.relname, '', pg_get_indexdef(ic.oid, 0, true), 'pg_class'::regclass, ic.oid
+                FROM pg_index i
+                JOIN pg_class ic ON ic.oid = i.indexrelid
+                JOIN pg_class t ON t.oid = i.indrelid
+                JOIN n ON n.oid = ic.relnamespace
+                WHERE NOT i.indisprimary AND NOT ic.relispartition AND NOT EXISTS (
+                    SELECT 1 FROM pg_constraint con WHERE con.conindid = ic.oid AND con.conrelid = t.oid AND con.contype IN ('u', 'x'))
+                UNION ALL
+                SELECT 'trigger', n.nspname, tg.tgname, c.relname, '', pg_get_triggerdef(tg.oid, true), 'pg_trigger'::regclass, tg.oid
+                FROM pg_trigger tg
+                JOIN pg_class c ON c.oid = tg.tgrelid
+                JOIN n ON n.oid = c.relnamespace
+                WHERE NOT tg.tgisinternal AND NOT EXISTS (
+                    SELECT 1 FROM pg_depend dep WHERE dep.classid = 'pg_trigger'::regclass AND dep.objid = tg.oid AND dep.deptype = 'P')
+                UNION ALL
+                SELECT CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END, n.nspname, p.proname, '',
+                    pg_get_function_identity_arguments(p.oid), pg_get_functiondef(p.oid), 'pg_proc'::regclass, p.oid
+                FROM pg_proc p JOIN n ON n.oid = p.pronamespace
+                WHERE p.prokind IN ('f', 'p')
+                UNION ALL
+                SELECT 'extension', en.nspname, e.extname, '', '',
+                    'CREATE EXTENSION IF NOT EXISTS ' || quote_ident(e.extname)
+                        || CASE WHEN en.nspname = current_schema() THEN '' ELSE ' WITH SCHEMA ' || quote_ident(en.nspname) END,
+                    'pg_extension'::regclass, e.oid
+                FROM pg_extension e JOIN pg_namespace en ON en.oid = e.extnamespace
+                WHERE e.extname <> 'plpgsql'
+                UNION ALL
+                SELECT CASE t.typtype WHEN 'd' THEN 'domain' ELSE 'type' END, n.nspname, t.typname, '', '',
+                    'CREATE ' || CASE t.typtype WHEN 'd' THEN 'DOMAIN ' ELSE 'TYPE ' END
+                        || CASE WHEN n.nspname = current_schema() THEN '' ELSE quote_ident(n.nspname) || '.' END || quote_ident(t.typname)
+                        || CASE t.typtype
+                            WHEN 'e' THEN ' AS ENUM (' || COALESCE((
+                                SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder)
+                                FROM pg_enum e WHERE e.enumtypid = t.oid), '') || ')'
+                            WHEN 'c' THEN ' AS (' || COALESCE((
+                                SELECT string_agg(quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod), ', ' ORDER BY a.attnum)
+                                FROM pg_attribute a WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped), '') || ')'
+                            ELSE ' AS ' || format_type(t.typbasetype, t.typtypmod)
+                                || CASE WHEN t.typdefault IS NULL THEN '' ELSE ' DEFAULT ' || t.typdefault END
+                                || CASE WHEN t.typnotnull THEN ' NOT NULL' ELSE '' END
+                                || COALESCE((
+                                    SELECT string_agg(' CONSTRAINT ' || quote_ident(con.conname) || ' ' || pg_get_constraintdef(con.oid), '' ORDER BY con.conname)
+                                    FROM pg_constraint con WHERE con.contypid = t.oid), '')
+                        END,
+                    'pg_type'::regclass, t.oid
+                FROM pg_type t
+                JOIN n ON n.oid = t.typnamespace
+                LEFT JOIN pg_class tc ON tc.oid = t.typrelid
+                WHERE t.typtype IN ('e', 'd') OR (t.typtype = 'c' AND tc.relkind = 'c')
+            )
+            SELECT kind, schema, name, table_name, signature, definition
+            FROM objects o
+            WHERE NOT EXISTS (SELECT 1 FROM pg_depend dep WHERE dep.classid = o.classid AND dep.objid = o.oid AND dep.deptype = 'e')
+            ORDER BY o.oid`, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		obj := &objects[i]
+		schema, name := SplitTableName(obj.Name)
+		if obj.Kind == KindTrigger {
+			schema, _ = SplitTableName(obj.Table)
+			obj.Name = name
+		}
+		if schema != "" {
+			continue
+		}
+		switch obj.Kind {
+		case KindIndex, KindTrigger:
+			obj.Definition = strings.Replace(obj.Definition, " ON "+prefix, " ON ", 1)
+		case KindFunction, KindProcedure:
+			head := " " + strings.ToUpper(obj.Kind) + " "
+			obj.Definition = strings.Replace(obj.Definition, head+prefix, head, 1)
+		}
+	}
+	return objects, nil
+}
+
+// CreateTableStatement builds the CREATE TABLE statement from pg_catalog, as
+// PostgreSQL has no SHOW CREATE TABLE: columns with their defaults and
+// identity, then the primary key, unique, check, exclusion and foreign key
+// constraints. Generated columns are plain columns holding the exported
+// values. Columns of domains, enums, composite and extension types are typed
+// by the name of their type, qualified outside the search path. A partitioned
+// table is declared with its PARTITION BY clause and followed by the CREATE
+// TABLE ... PARTITION OF statements of its partitions, and COMMENT ON
+// statements follow for the comments of the table and its columns.
+func (d postgresDialect) CreateTableStatement(db *gorm.DB, tableName string) (string, error) {
+	return d.createTableStatement(db, tableName, true)
+}
+
+// FlatTableStatement is CreateTableStatement without the partitioning.
+func (d postgresDialect) FlatTableStatement(db *gorm.DB, tableName string) (string, error) {
+	return d.createTableStatement(db, tableName, false)
+}
+
+func (d postgresDialect) createTableStatement(db *gorm.DB, tableName string, partitioned bool) (string, error) {
+	rows, err := db.Raw(`
+            SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull, pg_get_expr(d.adbin, d.adrelid),
+                a.attidentity::text, a.attgenerated::text
+            FROM pg_attribute a
+            JOIN pg_class c ON c.oid = a.attrelid
+            JOIN pg_namespace n ON n.oid = c.relnamespace
+            LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+            WHERE n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND c.relname = ? AND a.attnum > 0 AND NOT a.attisdropped
+            ORDER BY a.attnum
+        `, schemaTableArgs(tableName)...).Rows()
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name, dataType, identity, generated string
+		var notNull bool
+		var colDefault *string
+		if err := rows.Scan(&name, &dataType, &notNull, &colDefault, &identity, &generated); err != nil {
+			return "", err
+		}
+		colDef := fmt.Sprintf("%s %s", d.QuoteIdentifier(name), dataType)
+		switch {
+		case generated != "":
+			// Written as a plain column, so that its exported values load
+		case identity == "a":
+			colDef += " GENERATED ALWAYS AS IDENTITY"
+		case identity == "d":
+			colDef += " GENERATED BY DEFAULT AS IDENTITY"
+		case colDefault != nil:
+			colDef += fmt.Sprintf(" DEFAULT %s", *colDefault)
+		}
+		if notNull {
+			colDef += " NOT NULL"
+		}
+		columns = append(columns, colDef)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("no columns found for table %s", tableName)
+	}
+
+	// Primary and unique keys, checks, exclusions and foreign keys follow the
+	// columns; tables referencing one not created yet are retried by import.
+	constraints, err := db.Raw(`
+            SELECT con.conname, pg_get_constraintdef(con.oid)
+            FROM pg_constraint con
+            JOIN pg_class t ON t.oid = con.conrelid
+            JOIN pg_namespace n ON n.oid = t.relnamespace
+            WHERE con.contype IN ('p', 'u', 'c', 'x', 'f') AND n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ?
+            ORDER BY con.contype = 'f', con.contype <> 'p', con.conname
+        `, schemaTableArgs(tableName)...).Rows()
+	if err != nil {
+		return "", err
+	}
+	defer constraints.Close()
+	for constraints.Next() {
+		var name, def string
+		if err := constraints.Scan(&name, &def); err != nil {
+			return "", err
+		}
+		columns = append(columns, fmt.Sprintf("CONSTRAINT %s %s", d.QuoteIdentifier(name), def))
+	}
+	if err := constraints.Err(); err != nil {
+		return "", err
+	}
+
+	create := fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", quoteTableName(d, tableName), strings.Join(columns, ",\n    "))
+	var partitions []string
+	if partitioned {
+		var key string
+		if key, partitions, err = d.partitions(db, tableName); err != nil {
+			return "", err
+		}
+		if key != "" {
+			create += " PARTITION BY " + key
+		}
+	}
+	comments, err := postgresCatalog.introspectComments(db, tableName)
+	if err != nil {
+		return "", err
+	}
+	stmts := append([]string{create + ";"}, partitions...)
+	stmts = append(stmts, commentStatements(d, tableName, comments)...)
+	return strings.Join(stmts, "\n"), nil
+}
+
+// partitions returns the partition key of tableName, "" when it is not
+// partitioned, and the statements creating its partitions, partitioned
+// partitions before their own. Partitions outside the schema of tableName
+// are preceded by the statement creating their schema.
+func (d postgresDialect) partitions(db *gorm.DB, tableName string) (string, []string, error) {
+	var key string
+	if err := db.Raw(`
+            SELECT COALESCE(pg_get_partkeydef(c.oid), '')
+            FROM pg_class c
+            JOIN pg_namespace n ON n.oid = c.relnamespace
+            WHERE n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND c.relname = ?
+        `, schemaTableArgs(tableName)...).Row().Scan(&key); err != nil || key == "" {
+		return "", nil, err
+	}
+
+	rows, err := db.Raw(`
+            WITH RECURSIVE parts AS (
+                SELECT c.oid, 0 AS depth
+                FROM pg_class c
+                JOIN pg_namespace n ON n.oid = c.relnamespace
+                WHERE n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND c.relname = ?
+                UNION ALL
+                SELECT i.inhrelid, p.depth + 1
+                FROM pg_inherits i
+                JOIN parts p ON p.oid = i.inhparent
+                JOIN pg_class c ON c.oid = i.inhrelid
+                WHERE c.relispartition
+            )
+            SELECT CASE WHEN n.nspname = current_schema() THEN '' ELSE n.nspname END, c.relname,
+                CASE WHEN pn.nspname = current_schema() THEN '' ELSE pn.nspname END, pc.relname,
+                pg_get_expr(c.relpartbound, c.oid), COALESCE(pg_get_partkeydef(c.oid), '')
+            FROM parts p
+            JOIN pg_class c ON c.oid = p.oid
+            JOIN pg_namespace n ON n.oid = c.relnamespace
+            JOIN pg_inherits i ON i.inhrelid = c.oid
+            JOIN pg_class pc ON pc.oid = i.inhparent
+            JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+            WHERE p.depth > 0
+            ORDER BY p.depth, n.nspname, c.relname
+        `, schemaTableArgs(tableName)...).Rows()
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	schema, _ := SplitTableName(tableName)
+	created := map[string]bool{schema: true}
+	var stmts []string
+	for rows.Next() {
+		var partSchema, part, parentSchema, parent, bound, partKey string
+		if err := rows.Scan(&partSchema, &part, &parentSchema, &parent, &bound, &partKey); err != nil {
+			return "", nil, err
+		}
+		if partSchema != "" && !created[partSchema] {
+			created[partSchema] = true
+			stmts = append(stmts, d.CreateSchemaSQL(partSchema))
+		}
+		stmt := fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s",
+			quoteTableName(d, QualifyTableName(partSchema, part)), quoteTableName(d, QualifyTableName(parentSchema, parent)), bound)
+		if partKey != "" {
+			stmt += " PARTITION BY " + partKey
+		}
+		stmts = append(stmts, stmt+";")
+	}
+	return key, stmts, rows.Err()
+}
+
+var postgresCatalog = catalogQueries{
+	args: schemaTableArgs,
+	columns: `
+            SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+                CASE WHEN a.attgenerated = '' THEN pg_get_expr(d.adbin, d.adrelid) END,
+                a.attidentity <> '' OR COALESCE(pg_get_expr(d.adbin, d.adrelid), '') LIKE 'nextval(%'
+            FROM pg_attribute a
+            JOIN pg_class c ON c.oid = a.attrelid
+            JOIN pg_namespace n ON n.oid = c.relnamespace
+            LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+            WHERE n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND c.relname = ? AND a.attnum > 0 AND NOT a.attisdropped
+            ORDER BY a.attnum
+        `,
+	primaryKey: `
+            SELECT kcu.column_name
+            FROM information_schema.table_constraints tc
+            JOIN information_schema.key_column_usage kcu
+              ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+            WHERE tc.constraint_type = 'PRIMARY KEY'
+              AND tc.table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND tc.table_name = ?
+            ORDER BY kcu.ordinal_position
+        `,
+	indexes: `
+            SELECT ic.relname, ix.indisunique, a.attname
+            FROM pg_index ix
+            JOIN pg_class t ON t.oid = ix.indrelid
+            JOIN pg_class ic ON ic.oid = ix.indexrelid
+            JOIN pg_namespace n ON n.oid = t.relnamespace
+            JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+            JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+            WHERE n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ? AND NOT ix.indisprimary
+              AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = ix.indexrelid AND con.conrelid = t.oid AND con.contype = 'x')
+            ORDER BY ic.relname, k.ord
+        `,
+	foreignKeys: `
+            SELECT con.conname, a.attname,
+                CASE WHEN rn.nspname = current_schema() THEN rt.relname ELSE rn.nspname || '.' || rt.relname END,
+                ra.attname
+            FROM pg_constraint con
+            JOIN pg_class t ON t.oid = con.conrelid
+            JOIN pg_namespace n ON n.oid = t.relnamespace
+            JOIN pg_class rt ON rt.oid = con.confrelid
+            JOIN pg_namespace rn ON rn.oid = rt.relnamespace
+            JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(col, refcol, ord) ON true
+            JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.col
+            JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refcol
+            WHERE con.contype = 'f' AND n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ?
+            ORDER BY con.conname, k.ord
+        `,
+	checks: `
+            SELECT con.conname, pg_get_constraintdef(con.oid)
+            FROM pg_constraint con
+            JOIN pg_class t ON t.oid = con.conrelid
+            JOIN pg_namespace n ON n.oid = t.relnamespace
+            WHERE con.contype = 'c' AND n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ?
+            ORDER BY con.conname
+        `,
+	enums: `
+            SELECT a.attname, e.enumlabel
+            FROM pg_attribute a
+            JOIN pg_class c ON c.oid = a.attrelid
+            JOIN pg_namespace n ON n.oid = c.relnamespace
+            JOIN pg_enum e ON e.enumtypid = a.atttypid
+            WHERE n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND c.relname = ? AND a.attnum > 0 AND NOT a.attisdropped
+            ORDER BY a.attnum, e.enumsortorder
+        `,
+	comments: `
+            SELECT COALESCE(a.attname, ''), d.description
+            FROM pg_description d
+            JOIN pg_class c ON c.oid = d.objoid
+            JOIN pg_namespace n ON n.oid = c.relnamespace
+            LEFT JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = d.objsubid AND d.objsubid > 0
+            WHERE d.classoid = 'pg_class'::regclass AND n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND c.relname = ?
+              AND (d.objsubid = 0 OR a.attnum IS NOT NULL)
+            ORDER BY d.objsubid
+        `,
+}
+
+func (postgresDialect) PrimaryKeyColumns(db *gorm.DB, tableName string) ([]string, error) {
+	return postgresCatalog.primaryKeyColumns(db, tableName)
+}
+
+func (postgresDialect) IntrospectTable(db *gorm.DB, tableName string) (*Table, error) {
+	return postgresCatalog.introspect(db, tableName)
+}
+
+// InsertSQL overrides the values of GENERATED ALWAYS identity columns with
+// the exported ones.
+func (d postgresDialect) InsertSQL(tableName string, columns []string, rows [][]string) string {
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = "(" + strings.Join(row, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) OVERRIDING SYSTEM VALUE VALUES\n%s;\n", quoteTableName(d, tableName), quoteNames(d, columns), strings.Join(values, ",\n"))
+}
+
+func (d postgresDialect) AlterColumnSQL(tableName string, c ColumnChange) []string {
+	col := c.Source
+	var stmts []string
+	name := d.QuoteIdentifier(col.Name)
+	prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", quoteTableName(d, tableName), name)
+	if NormalizeType(col.Type) != NormalizeType(c.Target.Type) {
+		stmts = append(stmts, fmt.Sprintf("%s TYPE %s USING %s::%s;", prefix, col.Type, name, col.Type))
+	}
+	if col.Nullable != c.Target.Nullable {
+		if col.Nullable {
+			stmts = append(stmts, prefix+" DROP NOT NULL;")
+		} else {
+			stmts = append(stmts, prefix+" SET NOT NULL;")
+		}
+	}
+	if normalizeDefault(col.Default) != normalizeDefault(c.Target.Default) {
+		if col.Default == nil {
+			stmts = append(stmts, prefix+" DROP DEFAULT;")
+		} else {
+			stmts = append(stmts, fmt.Sprintf("%s SET DEFAULT %s;", prefix, *col.Default))
+		}
+	}
+	return stmts
+}
+
+// EnumType keeps the named enum types of PostgreSQL columns, which are
+// exported ahead of the tables. Inline enums read from other databases are
+// checked instead, as PostgreSQL has no anonymous enum type.
+func (postgresDialect) EnumType(c Column) (string, bool) {
+	if inlineEnumLabels(c.Type) != nil {
+		return "", false
+	}
+	return c.Type, true
+}
+
+// AlterPrimaryKeySQL drops the primary key under its default name,
+// <table>_pkey, before adding the new one.
+func (d postgresDialect) AlterPrimaryKeySQL(tableName string, from, to []string) []string {
+	var stmts []string
+	if len(from) > 0 {
+		_, table := SplitTableName(tableName)
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", quoteTableName(d, tableName), d.QuoteIdentifier(table+"_pkey")))
+	}
+	if len(to) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", quoteTableName(d, tableName), quoteNames(d, to)))
+	}
+	return stmts
+}
+
+// DropObjectSQL drops triggers from their table and routines by signature,
+// which tells overloads apart. Extensions are dropped by name, as they do
+// not belong to their schema.
+func (d postgresDialect) DropObjectSQL(obj SchemaObject) string {
+	switch obj.Kind {
+	case KindExtension:
+		_, name := SplitTableName(obj.Name)
+		return fmt.Sprintf("DROP EXTENSION IF EXISTS %s;", d.QuoteIdentifier(name))
+	case KindTrigger:
+		return fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", d.QuoteIdentifier(obj.Name), quoteTableName(d, obj.Table))
+	case KindFunction, KindProcedure:
+		return fmt.Sprintf("DROP %s IF EXISTS %s(%s);", strings.ToUpper(obj.Kind), quoteTableName(d, obj.Name), obj.Signature)
+	}
+	return d.StandardSQL.DropObjectSQL(obj)
+}
+
+// TruncateTables empties all tables in one statement, which PostgreSQL
+// accepts as long as every table referencing them is part of it.
+func (d postgresDialect) TruncateTables(db *gorm.DB, tableNames []string) error {
+	return db.Exec(fmt.Sprintf("TRUNCATE TABLE %s", quoteTableNames(d, tableNames))).Error
+}
+
+func (d postgresDialect) DropTables(db *gorm.DB, tableNames []string) error {
+	return db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", quoteTableNames(d, tableNames))).Error
+}
+
+// serialColumn is a PostgreSQL column backed by a sequence, either through a
+// serial default or as an identity column.
+type serialColumn struct {
+	Column   string
+	Sequence string
+	Identity bool
+}
+
+func postgresSerialColumns(db *gorm.DB, tableName string) ([]serialColumn, error) {
+	rows, err := db.Raw(`
+            SELECT column_name, pg_get_serial_sequence(quote_ident(table_schema) || '.' || quote_ident(table_name), column_name), is_identity
+            FROM information_schema.columns
+            WHERE table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+            ORDER BY ordinal_position
+        `, schemaTableArgs(tableName)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []serialColumn
+	for rows.Next() {
+		var name, isIdentity string
+		var sequence *string
+		if err := rows.Scan(&name, &sequence, &isIdentity); err != nil {
+			return nil, err
+		}
+		if sequence != nil {
+			columns = append(columns, serialColumn{Column: name, Sequence: *sequence, Identity: isIdentity == "YES"})
+		}
+	}
+	return columns, rows.Err()
+}
+
+func (d postgresDialect) ResetSequences(db *gorm.DB, tableName string) error {
+	columns, err := postgresSerialColumns(db, tableName)
+	if err != nil {
+		return err
+	}
+	for _, col := range columns {
+		stmt := fmt.Sprintf(`SELECT setval(?, GREATEST(COALESCE(MAX(%s), 0) + 1,
+                (SELECT CASE WHEN is_called THEN last_value + 1 ELSE last_value END FROM %s)), false) FROM %s`,
+			d.QuoteIdentifier(col.Column), col.Sequence, quoteTableName(d, tableName))
+		if err := db.Exec(stmt, col.Sequence).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SequencesSQL creates serial sequences before the table and sets them after
+// it, and restarts identity columns, which the table declares, at their next
+// value.
+func (d postgresDialect) SequencesSQL(db *gorm.DB, tableName string) (before, after []string, err error) {
+	columns, err := postgresSerialColumns(db, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, col := range columns {
+		var lastValue int64
+		var isCalled bool
+		row := db.Raw(fmt.Sprintf("SELECT last_value, is_called FROM %s", col.Sequence)).Row()
+		if err := row.Scan(&lastValue, &isCalled); err != nil {
+			return nil, nil, err
+		}
+		if col.Identity {
+			next := lastValue
+			if isCalled {
+				next++
+			}
+			after = append(after, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s RESTART WITH %d;", quoteTableName(d, tableName), d.QuoteIdentifier(col.Column), next))
+			continue
+		}
+		before = append(before, fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s;", col.Sequence))
+		after = append(after, fmt.Sprintf("SELECT setval('%s', %d, %t);", escapeSQLString(col.Sequence), lastValue, isCalled))
+	}
+	return before, after, nil
+}
+
+func (postgresDialect) AcquireLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	return acquirePostgresLock(db, name, timeout)
+}
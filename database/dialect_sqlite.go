@@ -0,0 +1,385 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterDialect(sqliteDialect{})
+}
+
+// sqliteDialect is SQLite. Schemas are attached databases; unqualified names
+// refer to the main database.
+type sqliteDialect struct {
+	StandardSQL
+}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+// Open connects to dsn, attaching the databases of its attach.<alias>
+// parameters.
+func (sqliteDialect) Open(dsn string, config *gorm.Config) (*gorm.DB, error) {
+	dialector, err := sqliteDialector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, config)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(10)
+	sqlDB.SetConnMaxLifetime(5 * time.Second)
+	return db, nil
+}
+
+func (sqliteDialect) CurrentSchema(db *gorm.DB) (string, error) {
+	return "main", nil
+}
+
+// sqliteDatabases returns the names of the main and attached databases.
+func sqliteDatabases(db *gorm.DB) ([]string, error) {
+	var databases []struct {
+		Seq  int
+		Name string
+		File string
+	}
+	if err := db.Raw("PRAGMA database_list").Scan(&databases).Error; err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, database := range databases {
+		if database.Name != "temp" {
+			names = append(names, database.Name)
+		}
+	}
+	return names, nil
+}
+
+// ListTables lists sqlite_master of each database. The lock table is left out.
+func (d sqliteDialect) ListTables(db *gorm.DB, schemas []string, all bool) ([]SchemaTable, error) {
+	if all {
+		var err error
+		if schemas, err = sqliteDatabases(db); err != nil {
+			return nil, err
+		}
+	}
+
+	var tables []SchemaTable
+	for _, schema := range schemas {
+		var names []string
+		query := fmt.Sprintf(`SELECT name FROM %s.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%%' AND name <> ? ORDER BY rowid`, d.QuoteIdentifier(schema))
+		if err := db.Raw(query, SQLiteLockTable).Scan(&names).Error; err != nil {
+			return nil, fmt.Errorf("failed to list tables of database %s: %w", schema, err)
+		}
+		for _, name := range names {
+			tables = append(tables, SchemaTable{Schema: schema, Table: name})
+		}
+	}
+	return tables, nil
+}
+
+func (d sqliteDialect) TableExists(db *gorm.DB, tableName string) (bool, error) {
+	schema, table := SplitTableName(tableName)
+	var count int64
+	err := db.Raw(fmt.Sprintf(`SELECT COUNT(*) FROM %s.sqlite_master WHERE type = 'table' AND name = ?`, d.QuoteIdentifier(sqliteSchema(schema))),
+		table).Row().Scan(&count)
+	return count > 0, err
+}
+
+// ListObjects lists the views, triggers and indexes of sqlite_master, leaving
+// out the indexes SQLite creates for constraints, which have no statement.
+// Objects of attached databases are created in them by qualified name, with
+// the ON table unqualified as SQLite requires.
+func (d sqliteDialect) ListObjects(db *gorm.DB, schemas []string, all bool) ([]SchemaObject, error) {
+	if all {
+		var err error
+		if schemas, err = sqliteDatabases(db); err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []SchemaObject
+	for _, schema := range schemas {
+		query := fmt.Sprintf(`SELECT type, name, tbl_name, sql FROM %s.sqlite_master
+                WHERE type IN ('index', 'view', 'trigger') AND sql IS NOT NULL AND name NOT LIKE 'sqlite_%%' AND tbl_name <> ?
+                ORDER BY rowid`, d.QuoteIdentifier(schema))
+		rows, err := db.Raw(query, SQLiteLockTable).Rows()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects of database %s: %w", schema, err)
+		}
+		for rows.Next() {
+			var kind, name, table, stmt string
+			if err := rows.Scan(&kind, &name, &table, &stmt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			obj := SchemaObject{Kind: kind, Name: name, Definition: stmt}
+			if kind != KindView {
+				obj.Table = table
+			}
+			if schema != "main" {
+				obj.Name = QualifyTableName(schema, name)
+				if obj.Table != "" {
+					obj.Table = QualifyTableName(schema, obj.Table)
+				}
+				obj.Definition = qualifyCreateStatement(d, stmt, schema, name)
+			}
+			objects = append(objects, obj)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+func (d sqliteDialect) CreateTableStatement(db *gorm.DB, tableName string) (string, error) {
+	schema, table := SplitTableName(tableName)
+	query := fmt.Sprintf(`SELECT sql FROM %s.sqlite_master WHERE type = 'table' AND name = ?`, d.QuoteIdentifier(sqliteSchema(schema)))
+	var stmt string
+	if err := db.Raw(query, table).Row().Scan(&stmt); err != nil {
+		return "", err
+	}
+	return qualifyCreateStatement(d, stmt, schema, table), nil
+}
+
+// sqliteTableArgs locates tableName for the pragma table functions, which
+// take the table before the database.
+func sqliteTableArgs(tableName string) []any {
+	schema, table := SplitTableName(tableName)
+	return []any{table, sqliteSchema(schema)}
+}
+
+// sqliteCatalog has no checks query, as SQLite keeps CHECK constraints only
+// in the original DDL.
+var sqliteCatalog = catalogQueries{
+	args: sqliteTableArgs,
+	// Primary key columns are implicitly NOT NULL only for INTEGER keys,
+	// so nullability is taken as declared. A single INTEGER key column is
+	// the rowid, numbered by SQLite.
+	columns: `
+            SELECT name, type, "notnull" = 0, dflt_value, lower(type) = 'integer' AND pk = 1 AND MAX(pk) OVER () = 1
+            FROM pragma_table_info(?, ?)
+            ORDER BY cid
+        `,
+	primaryKey: `SELECT name FROM pragma_table_info(?, ?) WHERE pk > 0 ORDER BY pk`,
+	indexes: `
+            SELECT il.name, il."unique", ii.name
+            FROM pragma_index_list(?, ?) il
+            JOIN pragma_index_info(il.name, il.schema) ii
+            WHERE il.origin <> 'pk'
+            ORDER BY il.name, ii.seqno
+        `,
+	// SQLite foreign keys are unnamed; they get the PostgreSQL default name
+	// in IntrospectTable.
+	foreignKeys: `SELECT 'fk_' || id, "from", "table", "to" FROM pragma_foreign_key_list(?, ?) ORDER BY id, seq`,
+}
+
+func (sqliteDialect) PrimaryKeyColumns(db *gorm.DB, tableName string) ([]string, error) {
+	return sqliteCatalog.primaryKeyColumns(db, tableName)
+}
+
+func (d sqliteDialect) IntrospectTable(db *gorm.DB, tableName string) (*Table, error) {
+	t, err := sqliteCatalog.introspect(db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	schema, table := SplitTableName(tableName)
+
+	// Names of indexes SQLite creates for UNIQUE constraints are reserved
+	for i, idx := range t.Indexes {
+		if strings.HasPrefix(idx.Name, "sqlite_autoindex_") {
+			t.Indexes[i].Name = fmt.Sprintf("%s_%s_key", table, strings.Join(idx.Columns, "_"))
+		}
+	}
+	// SQLite references always point into the table's own database
+	for i, fk := range t.ForeignKeys {
+		t.ForeignKeys[i].Name = fmt.Sprintf("%s_%s_fkey", table, strings.Join(fk.Columns, "_"))
+		t.ForeignKeys[i].RefTable = QualifyTableName(schema, fk.RefTable)
+	}
+
+	ddl, err := d.CreateTableStatement(db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ParseSchemaSQL(ddl)
+	if err != nil {
+		return nil, err
+	}
+	for _, pt := range parsed.Tables {
+		t.Checks = pt.Checks
+		t.Comment = pt.Comment
+		for _, pc := range pt.Columns {
+			if c := t.Column(pc.Name); c != nil {
+				c.Comment = pc.Comment
+			}
+		}
+	}
+	return t, nil
+}
+
+// CreateSchemaSQL returns "", as databases are attached rather than created.
+func (sqliteDialect) CreateSchemaSQL(schema string) string {
+	return ""
+}
+
+// ForeignKeysInCreateTable is true, as SQLite cannot add foreign keys to an
+// existing table.
+func (sqliteDialect) ForeignKeysInCreateTable() bool {
+	return true
+}
+
+// CreateIndexSQL qualifies the index rather than the table with the attached
+// database.
+func (d sqliteDialect) CreateIndexSQL(tableName string, idx Index) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	schema, table := SplitTableName(tableName)
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, quoteTableName(d, QualifyTableName(schema, idx.Name)),
+		d.QuoteIdentifier(table), quoteNames(d, idx.Columns))
+}
+
+func (sqliteDialect) AddForeignKeySQL(tableName string, fk ForeignKey) string {
+	return fmt.Sprintf("-- SQLite cannot add foreign key %s to existing table %s; rebuild the table", fk.Name, tableName)
+}
+
+func (sqliteDialect) DropForeignKeySQL(tableName string, fk ForeignKey) string {
+	return rebuildTableComment("SQLite", "drop foreign key "+fk.Name, tableName)
+}
+
+func (sqliteDialect) AddCheckSQL(tableName string, c Check) string {
+	return rebuildTableComment("SQLite", "add check "+c.Expression, tableName)
+}
+
+func (sqliteDialect) DropCheckSQL(tableName string, c Check) string {
+	return rebuildTableComment("SQLite", "drop check "+c.Expression, tableName)
+}
+
+func (sqliteDialect) AlterColumnSQL(tableName string, c ColumnChange) []string {
+	return []string{fmt.Sprintf("-- SQLite cannot alter column %s on table %s (%s); rebuild the table",
+		c.Source.Name, tableName, strings.Join(c.Changes, "; "))}
+}
+
+// CommentSQL cannot set comments, as SQLite has none. CreateTableSQL
+// writes them as SQL comments in CREATE TABLE instead, which SQLite keeps
+// in sqlite_master: a column comment in lines ahead of the column, the
+// table comment after the opening parenthesis, on one line.
+func (sqliteDialect) CommentSQL(tableName, column, comment string) string {
+	if column != "" {
+		return rebuildTableComment("SQLite", "set the comment of column "+column, tableName)
+	}
+	return rebuildTableComment("SQLite", "set the comment", tableName)
+}
+
+func (sqliteDialect) ColumnComment(def, comment string) string {
+	return "-- " + strings.ReplaceAll(comment, "\n", "\n    -- ") + "\n    " + def
+}
+
+func (sqliteDialect) TableComment(stmt, comment string) string {
+	comment = strings.Join(strings.Split(comment, "\n"), " ")
+	return strings.Replace(stmt, " (\n", " ( -- "+comment+"\n", 1)
+}
+
+func (sqliteDialect) AlterPrimaryKeySQL(tableName string, from, to []string) []string {
+	return []string{rebuildTableComment("SQLite", "change the primary key", tableName)}
+}
+
+// sqliteForeignKeys switches foreign key enforcement for one connection.
+var sqliteForeignKeys = foreignKeySwitch{
+	current: "PRAGMA foreign_keys",
+	disable: "PRAGMA foreign_keys = OFF",
+	enable:  "PRAGMA foreign_keys = ON",
+}
+
+// TruncateTables deletes every row, as SQLite has no TRUNCATE.
+func (d sqliteDialect) TruncateTables(db *gorm.DB, tableNames []string) error {
+	return sqliteForeignKeys.run(db, eachTable(d, "DELETE FROM %s", tableNames)...)
+}
+
+func (d sqliteDialect) DropTables(db *gorm.DB, tableNames []string) error {
+	return sqliteForeignKeys.run(db, eachTable(d, "DROP TABLE IF EXISTS %s", tableNames)...)
+}
+
+// ResetSequences moves the AUTOINCREMENT counter of tableName, kept in the
+// sqlite_sequence of its database. Tables without AUTOINCREMENT reuse the
+// highest rowid anyway.
+func (d sqliteDialect) ResetSequences(db *gorm.DB, tableName string) error {
+	schema, table := SplitTableName(tableName)
+	var count int64
+	err := db.Raw(fmt.Sprintf(`SELECT COUNT(*) FROM %s.sqlite_master WHERE type = 'table' AND name = ? AND sql LIKE '%%AUTOINCREMENT%%'`, d.QuoteIdentifier(sqliteSchema(schema))),
+		table).Row().Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	var seq, current int64
+	if err := db.Raw(fmt.Sprintf("SELECT COALESCE(MAX(rowid), 0) FROM %s", quoteTableName(d, tableName))).Row().Scan(&seq); err != nil {
+		return err
+	}
+	// No sqlite_sequence row until the table had rows
+	err = db.Raw(fmt.Sprintf(`SELECT seq FROM %s WHERE name = ?`, quoteTableName(d, QualifyTableName(schema, "sqlite_sequence"))), table).Row().Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if current > seq {
+		seq = current
+	}
+	return db.Exec(sqliteSequenceSQL(tableName, seq)).Error
+}
+
+// SequencesSQL writes the AUTOINCREMENT counter of tableName to
+// sqlite_sequence after the table is created.
+func (d sqliteDialect) SequencesSQL(db *gorm.DB, tableName string) (before, after []string, err error) {
+	var seq int64
+	schema, table := SplitTableName(tableName)
+	err = db.Raw(fmt.Sprintf(`SELECT seq FROM %s WHERE name = ?`, quoteTableName(d, QualifyTableName(schema, "sqlite_sequence"))), table).Row().Scan(&seq)
+	if err == nil {
+		after = append(after, sqliteSequenceSQL(tableName, seq))
+	}
+	return nil, after, nil
+}
+
+// sqliteSequenceSQL sets the AUTOINCREMENT counter of tableName, creating its
+// sqlite_sequence row when the table never had one. Each attached database
+// has its own sqlite_sequence.
+func sqliteSequenceSQL(tableName string, seq int64) string {
+	schema, table := SplitTableName(tableName)
+	sequences := quoteTableName(sqliteDialect{}, QualifyTableName(schema, "sqlite_sequence"))
+	name := escapeSQLString(table)
+	return fmt.Sprintf("UPDATE %s SET seq = %d WHERE name = '%s';\n"+
+		"INSERT INTO %s (name, seq) SELECT '%s', %d WHERE NOT EXISTS (SELECT 1 FROM %s WHERE name = '%s');",
+		sequences, seq, name, sequences, name, seq, sequences, name)
+}
+
+// Literal renders BLOB values as hex blob literals (X'00FF'), which hold any
+// bytes.
+func (sqliteDialect) Literal(val any, dbType string) string {
+	if v, ok := val.([]byte); ok && valueKind(dbType) == valueBinary {
+		return "X'" + strings.ToUpper(hex.EncodeToString(v)) + "'"
+	}
+	return sqlLiteral(val)
+}
+
+func (sqliteDialect) AcquireLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	return acquireTableLock(db, name, timeout)
+}
@@ -0,0 +1,31 @@
+package database
+
+import "testing"
+
+func TestSQLiteResetSequences(t *testing.T) {
+	db := openTestSQLite(t,
+		"CREATE TABLE plain (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE counted (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)",
+		"CREATE TABLE empty (id INTEGER PRIMARY KEY AUTOINCREMENT)",
+		"INSERT INTO counted (id, name) VALUES (7, 'a'), (9, 'b')",
+		"DELETE FROM sqlite_sequence",
+	)
+	d := sqliteDialect{}
+	for _, table := range []string{"plain", "counted", "empty"} {
+		if err := d.ResetSequences(db, table); err != nil {
+			t.Errorf("%s: %v", table, err)
+		}
+	}
+
+	var seq int64
+	if err := db.Raw("SELECT seq FROM sqlite_sequence WHERE name = 'counted'").Row().Scan(&seq); err != nil || seq != 9 {
+		t.Errorf("counted sequence = %d, %v, want 9", seq, err)
+	}
+	if err := db.Raw("SELECT seq FROM sqlite_sequence WHERE name = 'empty'").Row().Scan(&seq); err != nil || seq != 0 {
+		t.Errorf("empty sequence = %d, %v, want 0", seq, err)
+	}
+
+	if err := d.ResetSequences(db, "missing.counted"); err == nil {
+		t.Error("ResetSequences of a table in an unknown schema succeeded")
+	}
+}
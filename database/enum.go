@@ -0,0 +1,114 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Enum columns are typed by a named type on PostgreSQL (CREATE TYPE mood AS
+// ENUM ...) and inline on MySQL and DuckDB (ENUM('sad', 'ok')). Introspection
+// records their labels in Column.Enum, so that CreateTableSQL can write them
+// for a database that types them differently: as ENUM(...) where the target
+// has inline enums, and as a varchar column limited to the labels by a CHECK
+// constraint where it has none, such as SQLite.
+
+// enumTyper is implemented by dialects that have enum column types.
+type enumTyper interface {
+	// EnumType returns the type of enum column c, or false when the
+	// dialect cannot type it and the labels are to be checked instead.
+	EnumType(c Column) (string, bool)
+}
+
+// enumTypePattern matches inline enum types and captures their labels.
+var enumTypePattern = regexp.MustCompile(`(?is)^enum\s*\((.*)\)$`)
+
+// inlineEnumLabels returns the labels of an inline enum type such as
+// enum('sad','ok'), or nil for any other type.
+func inlineEnumLabels(t string) []string {
+	m := enumTypePattern.FindStringSubmatch(strings.TrimSpace(t))
+	if m == nil {
+		return nil
+	}
+	var labels []string
+	list := m[1]
+	for i := 0; i < len(list); i++ {
+		if list[i] != '\'' {
+			continue
+		}
+		// Read up to the closing quote, which is escaped by doubling it
+		var label strings.Builder
+		j := i + 1
+		for ; j < len(list); j++ {
+			if list[j] == '\'' {
+				if j+1 < len(list) && list[j+1] == '\'' {
+					label.WriteByte('\'')
+					j++
+					continue
+				}
+				break
+			}
+			label.WriteByte(list[j])
+		}
+		labels = append(labels, label.String())
+		i = j
+	}
+	return labels
+}
+
+// enumLiterals renders labels as a list of string literals.
+func enumLiterals(labels []string) string {
+	literals := make([]string, len(labels))
+	for i, label := range labels {
+		literals[i] = "'" + escapeSQLString(label) + "'"
+	}
+	return strings.Join(literals, ", ")
+}
+
+// inlineEnumType renders labels as an inline ENUM type.
+func inlineEnumType(labels []string) string {
+	return "ENUM(" + enumLiterals(labels) + ")"
+}
+
+// translateEnum returns enum column c typed for dialect, with the CHECK
+// constraint limiting it to its labels when the dialect has no enum type
+// for it. Casts to the enum type are dropped from the default.
+func translateEnum(dialect Dialect, c Column) (Column, *Check) {
+	if len(c.Enum) == 0 {
+		return c, nil
+	}
+	if c.Default != nil {
+		def := castPattern.ReplaceAllString(*c.Default, "")
+		c.Default = &def
+	}
+	if typer, ok := dialect.(enumTyper); ok {
+		if t, ok := typer.EnumType(c); ok {
+			c.Type = t
+			return c, nil
+		}
+	}
+	n := 1
+	for _, label := range c.Enum {
+		n = max(n, utf8.RuneCountInString(label))
+	}
+	c.Type = fmt.Sprintf("varchar(%d)", n)
+	quoted := `"` + strings.ReplaceAll(c.Name, `"`, `""`) + `"`
+	return c, &Check{Expression: fmt.Sprintf("(%s IN (%s))", quoted, enumLiterals(c.Enum))}
+}
+
+// translateEnums returns table with its enum columns typed for dialect, see
+// translateEnum.
+func translateEnums(dialect Dialect, table *Table) *Table {
+	translated := *table
+	translated.Columns = make([]Column, len(table.Columns))
+	translated.Checks = append([]Check(nil), table.Checks...)
+	for i, c := range table.Columns {
+		var check *Check
+		translated.Columns[i], check = translateEnum(dialect, c)
+		if check != nil {
+			translated.Checks = append(translated.Checks, *check)
+		}
+	}
+	return &translated
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInlineEnumLabels(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want []string
+	}{
+		{"enum('sad','ok','happy')", []string{"sad", "ok", "happy"}},
+		{"ENUM('sad', 'ok')", []string{"sad", "ok"}},
+		{" Enum ( 'a' ) ", []string{"a"}},
+		{"enum('it''s','a, b','(x)')", []string{"it's", "a, b", "(x)"}},
+		{"enum('')", []string{""}},
+		{"mood", nil},
+		{"varchar(10)", nil},
+		{"enumeration", nil},
+	}
+	for _, tt := range tests {
+		if got := inlineEnumLabels(tt.typ); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("inlineEnumLabels(%q) = %q, want %q", tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateEnum(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		column  Column
+		typ     string
+		def     string
+		check   string
+	}{
+		{
+			"inline enum on MySQL",
+			mysqlDialect{},
+			Column{Name: "mood", Type: "mood", Enum: []string{"sad", "it's"}, Default: stringPtr("'sad'::mood")},
+			"ENUM('sad', 'it''s')", "'sad'", "",
+		},
+		{
+			"inline enum on DuckDB",
+			duckdbDialect{},
+			Column{Name: "mood", Type: "enum('sad','ok')", Enum: []string{"sad", "ok"}},
+			"ENUM('sad', 'ok')", "", "",
+		},
+		{
+			"named type kept on PostgreSQL",
+			postgresDialect{},
+			Column{Name: "mood", Type: "mood", Enum: []string{"sad", "ok"}, Default: stringPtr("'ok'::mood")},
+			"mood", "'ok'", "",
+		},
+		{
+			"inline enum checked on PostgreSQL",
+			postgresDialect{},
+			Column{Name: "mood", Type: "enum('sad','happy')", Enum: []string{"sad", "happy"}},
+			"varchar(5)", "", `("mood" IN ('sad', 'happy'))`,
+		},
+		{
+			"checked on SQLite, sized in characters",
+			sqliteDialect{},
+			Column{Name: `my "mood"`, Type: "mood", Enum: []string{"sad", "très"}},
+			"varchar(4)", "", `("my ""mood""" IN ('sad', 'très'))`,
+		},
+		{
+			"not an enum",
+			sqliteDialect{},
+			Column{Name: "note", Type: "text", Default: stringPtr("'x'::text")},
+			"text", "'x'::text", "",
+		},
+	}
+	for _, tt := range tests {
+		var original string
+		if tt.column.Default != nil {
+			original = *tt.column.Default
+		}
+		got, check := translateEnum(tt.dialect, tt.column)
+		if tt.column.Default != nil && *tt.column.Default != original {
+			t.Errorf("%s: default of the source column changed to %q", tt.name, *tt.column.Default)
+		}
+		if got.Type != tt.typ {
+			t.Errorf("%s: type %q, want %q", tt.name, got.Type, tt.typ)
+		}
+		def := ""
+		if got.Default != nil {
+			def = *got.Default
+		}
+		if def != tt.def {
+			t.Errorf("%s: default %q, want %q", tt.name, def, tt.def)
+		}
+		expr := ""
+		if check != nil {
+			expr = check.Expression
+		}
+		if expr != tt.check {
+			t.Errorf("%s: check %q, want %q", tt.name, expr, tt.check)
+		}
+	}
+}
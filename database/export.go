@@ -1,9 +1,8 @@
 package database
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -15,193 +14,318 @@ func escapeSQLString(s string) string {
 	return strings.ReplaceAll(s, "'", "''")
 }
 
+// ExportSchemaSQL writes the CREATE TABLE statement of tableName to filename.
+// Tables outside the current schema are preceded by the statement creating
+// their schema.
 func ExportSchemaSQL(db *gorm.DB, tableName, filename string) error {
-	var createStmt string
+	return exportSchemaSQL(db, tableName, filename, false)
+}
 
-	switch db.Dialector.Name() {
-	case "mysql":
-		row := db.Raw(fmt.Sprintf("SHOW CREATE TABLE %s", tableName)).Row()
-		var table, stmt string
-		if err := row.Scan(&table, &stmt); err != nil {
-			return err
-		}
-		createStmt = stmt
-	case "postgres":
-		// Build a partial CREATE TABLE statement from information_schema
-		rows, err := db.Raw(`
-            SELECT column_name, data_type, is_nullable, column_default
-            FROM information_schema.columns
-            WHERE table_name = ?
-            ORDER BY ordinal_position
-        `, tableName).Rows()
-		if err != nil {
-			return err
-		}
-		defer rows.Close()
+// ExportFlatSchemaSQL is ExportSchemaSQL writing a partitioned table as a
+// plain table, for databases without its kind of partitioning.
+func ExportFlatSchemaSQL(db *gorm.DB, tableName, filename string) error {
+	return exportSchemaSQL(db, tableName, filename, true)
+}
 
-		var columns []string
-		for rows.Next() {
-			var name, dataType, isNullable string
-			var colDefault *string
-			if err := rows.Scan(&name, &dataType, &isNullable, &colDefault); err != nil {
-				return err
-			}
-			colDef := fmt.Sprintf("%s %s", name, dataType)
-			if colDefault != nil {
-				colDef += fmt.Sprintf(" DEFAULT %s", *colDefault)
-			}
-			if isNullable == "NO" {
-				colDef += " NOT NULL"
-			}
-			columns = append(columns, colDef)
-		}
-		if len(columns) == 0 {
-			return fmt.Errorf("no columns found for table %s", tableName)
-		}
-		createStmt = fmt.Sprintf("CREATE TABLE %s (\n    %s\n);", tableName, strings.Join(columns, ",\n    "))
-	case "sqlite":
-		query := fmt.Sprintf("SELECT sql FROM sqlite_master WHERE type='table' AND name='%s';", tableName)
-		row := db.Raw(query).Row()
-		if err := row.Scan(&createStmt); err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("unsupported dialect: %s", db.Dialector.Name())
+func exportSchemaSQL(db *gorm.DB, tableName, filename string, flat bool) error {
+	d, err := DialectOf(db)
+	if err != nil {
+		return err
+	}
+	createTable := d.CreateTableStatement
+	if p, ok := d.(partitioner); ok && flat {
+		createTable = p.FlatTableStatement
+	}
+	createStmt, err := createTable(db, tableName)
+	if err != nil {
+		return err
 	}
 
+	// SHOW CREATE TABLE and sqlite_master omit the terminator, which later
+	// statements appended to the file rely on.
+	createStmt = strings.TrimSpace(createStmt)
+	if !strings.HasSuffix(createStmt, ";") {
+		createStmt += ";"
+	}
+	if schema, _ := SplitTableName(tableName); schema != "" {
+		if create := d.CreateSchemaSQL(schema); create != "" {
+			createStmt = create + "\n" + createStmt
+		}
+	}
 	return os.WriteFile(filename, []byte(createStmt+"\n"), 0644)
 }
 
-func ExportDataSQL(db *gorm.DB, driver, tableName, filename string) error {
-	rows, err := db.Table(tableName).Rows()
+// ExportTranslatedSchemaSQL writes tableName to filename as a CREATE TABLE
+// statement in dialect, rendered from the introspected table rather than
+// copied from the database. This is how schemas are written for the
+// output-only dialects. Foreign keys the dialect adds with ALTER TABLE are
+// left to ExportTranslatedForeignKeysSQL, as the tables they reference may
+// not exist yet.
+func ExportTranslatedSchemaSQL(db *gorm.DB, tableName, filename, dialect string) error {
+	d, err := LookupDialect(dialect)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-
-	cols, _ := rows.Columns()
-	colCount := len(cols)
-
-	file, err := os.Create(filename)
+	table, err := IntrospectTable(db, tableName)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	file.WriteString(fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", tableName, strings.Join(cols, ", ")))
 
-	for rows.Next() {
-		values := make([]any, colCount)
-		ptrs := make([]any, colCount)
-		for i := range values {
-			ptrs[i] = &values[i]
+	var stmts []string
+	if schema, _ := SplitTableName(tableName); schema != "" {
+		if create := d.CreateSchemaSQL(schema); create != "" {
+			stmts = append(stmts, create)
 		}
+	}
+	stmts = append(stmts, CreateTableSQL(table, d)...)
+	return os.WriteFile(filename, []byte(strings.Join(stmts, "\n")+"\n"), 0644)
+}
 
-		if err := rows.Scan(ptrs...); err != nil {
-			return err
-		}
+// ExportTranslatedForeignKeysSQL writes the foreign keys of tables to
+// filename as ALTER TABLE statements in dialect, in reference order, to run
+// once every table exists. It returns false without writing anything when
+// the dialect declares foreign keys in CREATE TABLE or tables have none.
+func ExportTranslatedForeignKeysSQL(db *gorm.DB, tables []string, filename, dialect string) (bool, error) {
+	d, err := LookupDialect(dialect)
+	if err != nil {
+		return false, err
+	}
+	if d.ForeignKeysInCreateTable() {
+		return false, nil
+	}
+	schema, err := LoadSchema(db, tables)
+	if err != nil {
+		return false, err
+	}
 
-		var valueStrings []string
-		for _, val := range values {
-			switch v := val.(type) {
-			case nil:
-				valueStrings = append(valueStrings, "NULL")
-			case []byte:
-				s := string(v)
-				valueStrings = append(valueStrings, "'"+escapeSQLString(s)+"'")
-			case time.Time:
-				// Format MySQL-compatible datetime string
-				valueStrings = append(valueStrings, fmt.Sprintf("'%s'", v.Format("2006-01-02 15:04:05")))
-			default:
-				valueStrings = append(valueStrings, fmt.Sprintf("'%v'", v))
-			}
+	var stmts []string
+	for _, tableName := range schema.ReferenceOrder() {
+		for _, fk := range schema.Tables[tableName].ForeignKeys {
+			stmts = append(stmts, d.AddForeignKeySQL(tableName, fk))
 		}
-
-		stmt := fmt.Sprintf("(%s),\n",
-			strings.Join(valueStrings, ", "),
-		)
-
-		file.WriteString(stmt)
 	}
-
-	return nil
+	if len(stmts) == 0 {
+		return false, nil
+	}
+	return true, os.WriteFile(filename, []byte(strings.Join(stmts, "\n")+"\n"), 0644)
 }
 
-func FixSQLFileLastComma(filePath string) error {
-	content, err := os.ReadFile(filePath)
+// PrimaryKeyColumns returns the primary key columns of tableName in key order.
+func PrimaryKeyColumns(db *gorm.DB, tableName string) ([]string, error) {
+	d, err := DialectOf(db)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
+	return d.PrimaryKeyColumns(db, tableName)
+}
 
-	// Look for the last ",\n" and replace it with ";\n"
-	target := []byte(",\n")
-	replacement := []byte(";\n")
-	idx := bytes.LastIndex(content, target)
+// sqlLiteral renders a scanned column value as a SQL literal.
+func sqlLiteral(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return "'" + escapeSQLString(string(v)) + "'"
+	case string:
+		return "'" + escapeSQLString(v) + "'"
+	case time.Time:
+		// Format MySQL-compatible datetime string
+		return fmt.Sprintf("'%s'", v.Format("2006-01-02 15:04:05.999999"))
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", v)
+	default:
+		return "'" + escapeSQLString(fmt.Sprintf("%v", v)) + "'"
+	}
+}
+
+// keyTimeLayout renders time keys in UTC with their offset, so they mean
+// the same instant whatever location the driver scanned them in. The space
+// separator is the one SQLite stores times with, which compare as text.
+const keyTimeLayout = "2006-01-02 15:04:05.999999999-07:00"
 
-	if idx == -1 {
-		return fmt.Errorf("no trailing comma found in: %s", filePath)
+// keyString renders a primary key value the way it is stored in a checkpoint
+// and bound back into the next page query.
+func keyString(val any) string {
+	switch v := val.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(keyTimeLayout)
+	default:
+		return fmt.Sprintf("%v", v)
 	}
+}
 
-	copy(content[idx:], replacement)
+// ExportDataSQL writes the rows of tableName to filename as INSERT statements
+// of at most batchSize rows each. Tables with a primary key are read with
+// keyset pagination (WHERE pk > ? ORDER BY pk LIMIT n) so no single query
+// stays open for the whole table. After every flushed page progress is
+// updated and onPage is called, and a non-empty progress resumes the export
+// after its last key. Tables without a primary key are read in one query.
+// driver names the dialect the statements are written in, the dialect of db
+// when empty.
+func ExportDataSQL(db *gorm.DB, driver, tableName, filename string, batchSize int, progress *TableCheckpoint, onPage func() error) error {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if progress == nil {
+		progress = &TableCheckpoint{}
+	}
 
-	err = os.WriteFile(filePath, content, 0644)
+	source, err := DialectOf(db)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return err
+	}
+	d := source
+	if driver != "" && driver != source.Name() {
+		if d, err = LookupDialect(driver); err != nil {
+			return err
+		}
+	}
+	keys, err := source.PrimaryKeyColumns(db, tableName)
+	if err != nil {
+		return err
+	}
+	var before, after []string
+	if w, ok := d.(dataFileWrapper); ok {
+		table, err := source.IntrospectTable(db, tableName)
+		if err != nil {
+			return err
+		}
+		before, after = w.DataFileSQL(table)
 	}
 
-	fmt.Printf("Fixed last comma in: %s\n", filePath)
-	return nil
-}
-
-func FixOrTruncateSQLFile(filePath string) error {
-	// First, open and count the number of non-empty lines
-	file, err := os.Open(filePath)
+	// Resume after the last flushed page, dropping anything written after it
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if len(keys) > 0 && progress.Offset > 0 {
+		flags = os.O_WRONLY
+	} else {
+		*progress = TableCheckpoint{SchemaDone: progress.SchemaDone}
+	}
+	file, err := os.OpenFile(filename, flags, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return err
 	}
 	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lineCount := 0
-	for scanner.Scan() {
-		if line := scanner.Text(); len(line) > 0 {
-			lineCount++
-		}
+	if err := file.Truncate(progress.Offset); err != nil {
+		return err
 	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error scanning file: %w", err)
+	if _, err := file.Seek(progress.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	if progress.Offset == 0 {
+		for _, stmt := range before {
+			if _, err := file.WriteString(stmt + "\n"); err != nil {
+				return err
+			}
+		}
 	}
 
-	// If only one non-empty line, truncate the file
-	if lineCount <= 1 {
-		err := os.WriteFile(filePath, []byte{}, 0644)
+	flush := func(cols []string, rows [][]string, lastKey []string) error {
+		if len(rows) == 0 {
+			return nil
+		}
+		if _, err := file.WriteString(d.InsertSQL(tableName, cols, rows)); err != nil {
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			return err
+		}
+		offset, err := file.Seek(0, io.SeekCurrent)
 		if err != nil {
-			return fmt.Errorf("failed to truncate file: %w", err)
+			return err
+		}
+		progress.Offset = offset
+		progress.Rows += int64(len(rows))
+		progress.LastKey = lastKey
+		if onPage != nil {
+			return onPage()
 		}
-		fmt.Printf("File truncated (only one line): %s\n", filePath)
 		return nil
 	}
 
-	// Otherwise, read content and fix the trailing comma
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
+	for {
+		var r KeyRange
+		limit := 0
+		if len(keys) > 0 {
+			if len(progress.LastKey) == len(keys) {
+				r.After = progress.LastKey
+			}
+			limit = batchSize
+		}
 
-	target := []byte(",\n")
-	replacement := []byte(";\n")
-	idx := bytes.LastIndex(content, target)
+		rows, err := queryKeyRange(db, tableName, keys, r, limit)
+		if err != nil {
+			return err
+		}
+		cols, _ := rows.Columns()
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		keyIndex := columnIndexes(cols, keys)
 
-	if idx == -1 {
-		return fmt.Errorf("no trailing comma found in: %s", filePath)
-	}
+		var page [][]string
+		var lastKey []string
+		count := 0
+		for rows.Next() {
+			values := make([]any, len(cols))
+			ptrs := make([]any, len(cols))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				rows.Close()
+				return err
+			}
 
-	copy(content[idx:], replacement)
-	err = os.WriteFile(filePath, content, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+			literals := make([]string, len(values))
+			for i, val := range values {
+				literals[i] = d.Literal(val, types[i].DatabaseTypeName())
+			}
+			page = append(page, literals)
+			count++
+
+			lastKey = make([]string, len(keyIndex))
+			for i, idx := range keyIndex {
+				lastKey[i] = keyString(values[idx])
+			}
+
+			// Without a key the whole table is a single query, split into
+			// statements of batchSize rows.
+			if len(keys) == 0 && len(page) == batchSize {
+				if err := flush(cols, page, nil); err != nil {
+					rows.Close()
+					return err
+				}
+				page = page[:0]
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if err := flush(cols, page, lastKey); err != nil {
+			return err
+		}
+		if len(keys) == 0 || count < batchSize {
+			break
+		}
 	}
 
-	fmt.Printf("Fixed last comma in: %s\n", filePath)
+	for _, stmt := range after {
+		if _, err := file.WriteString(stmt + "\n"); err != nil {
+			return err
+		}
+	}
+	progress.DataDone = true
 	return nil
 }
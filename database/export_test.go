@@ -0,0 +1,99 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExportDataSQLResume interrupts an export after its first page and
+// resumes it from the saved checkpoint, which must give the same file as an
+// uninterrupted export even when a partial page was written after the
+// checkpoint.
+func TestExportDataSQLResume(t *testing.T) {
+	db := openTestSQLite(t,
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE notes (body TEXT)",
+		"INSERT INTO items VALUES (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e')",
+		"INSERT INTO notes VALUES ('x'), ('y'), ('z')",
+	)
+	dir := t.TempDir()
+	errInterrupted := errors.New("interrupted")
+
+	tests := []struct {
+		table   string
+		rows    int64
+		lastKey string
+		// run before resuming; the exported rows are gone from the source,
+		// so the file only matches if the export resumes after them
+		before string
+	}{
+		{"items", 5, "2", "DELETE FROM items WHERE id <= 2"},
+		// without a primary key the export starts over
+		{"notes", 3, "", ""},
+	}
+	for _, tt := range tests {
+		table := tt.table
+		full := filepath.Join(dir, table+"_full.sql")
+		if err := ExportDataSQL(db, "", table, full, 2, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(full)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		filename := filepath.Join(dir, table+".sql")
+		cp := NewCheckpoint(filepath.Join(dir, table+".checkpoint.json"))
+		err = ExportDataSQL(db, "", table, filename, 2, cp.Table(table), func() error {
+			if err := cp.Save(); err != nil {
+				return err
+			}
+			return errInterrupted
+		})
+		if !errors.Is(err, errInterrupted) {
+			t.Fatalf("%s: first run returned %v, want the interruption", table, err)
+		}
+
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.WriteString("INSERT INTO partial"); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		cp, err = LoadCheckpoint(cp.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		progress := cp.Table(table)
+		if progress.Rows != 2 || progress.Offset == 0 {
+			t.Errorf("%s: checkpoint after one page = %+v, want 2 rows and an offset", table, progress)
+		}
+		if tt.lastKey != "" && (len(progress.LastKey) != 1 || progress.LastKey[0] != tt.lastKey) {
+			t.Errorf("%s: last key %v, want [%s]", table, progress.LastKey, tt.lastKey)
+		}
+
+		if tt.before != "" {
+			if err := db.Exec(tt.before).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ExportDataSQL(db, "", table, filename, 2, progress, nil); err != nil {
+			t.Fatalf("%s: resuming: %v", table, err)
+		}
+		got, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s: resumed export\n%s\nwant\n%s", table, got, want)
+		}
+		if !progress.DataDone || progress.Rows != tt.rows {
+			t.Errorf("%s: progress after resume = %+v, want done with %d rows", table, progress, tt.rows)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// TableFilter selects table names with include and exclude patterns. A
+// pattern is a glob (audit_*, tmp_?) or, with the re: prefix, a regular
+// expression that must match the whole name.
+type TableFilter struct {
+	include []tablePattern
+	exclude []tablePattern
+}
+
+type tablePattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func (p tablePattern) match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	ok, _ := path.Match(p.glob, name)
+	return ok
+}
+
+func compileTablePatterns(patterns []string) ([]tablePattern, error) {
+	compiled := make([]tablePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+			re, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+			}
+			compiled = append(compiled, tablePattern{re: re})
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, tablePattern{glob: pattern})
+	}
+	return compiled, nil
+}
+
+// NewTableFilter compiles the include and exclude patterns. With no include
+// patterns every table is included.
+func NewTableFilter(include, exclude []string) (*TableFilter, error) {
+	inc, err := compileTablePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	exc, err := compileTablePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &TableFilter{include: inc, exclude: exc}, nil
+}
+
+// Match reports whether name matches an include pattern and no exclude pattern.
+func (f *TableFilter) Match(name string) bool {
+	for _, p := range f.exclude {
+		if p.match(name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the names that match, in their original order.
+func (f *TableFilter) Filter(names []string) []string {
+	var kept []string
+	for _, name := range names {
+		if f.Match(name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTableFilterMatch(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude []string
+		matched          []string
+		unmatched        []string
+	}{
+		{
+			name:    "empty include list",
+			matched: []string{"orders", "audit_log", "sales.orders"},
+		},
+		{
+			name:      "globs",
+			include:   []string{"audit_*", "tmp_?"},
+			matched:   []string{"audit_log", "audit_", "tmp_1"},
+			unmatched: []string{"orders", "tmp_12", "my_audit_log"},
+		},
+		{
+			name:      "regular expression",
+			include:   []string{"re:order(s|_items)"},
+			matched:   []string{"orders", "order_items"},
+			unmatched: []string{"orders_archive", "old_orders", "order"},
+		},
+		{
+			name:      "regular expression alternatives are anchored",
+			include:   []string{"re:a|b"},
+			matched:   []string{"a", "b"},
+			unmatched: []string{"ab", "xa", "bx"},
+		},
+		{
+			name:      "exclude takes precedence",
+			include:   []string{"audit_*"},
+			exclude:   []string{"audit_tmp*", "re:.*_old"},
+			matched:   []string{"audit_log"},
+			unmatched: []string{"audit_tmp", "audit_tmp_2", "audit_log_old"},
+		},
+		{
+			name:      "exclude only",
+			exclude:   []string{"tmp_*"},
+			matched:   []string{"orders"},
+			unmatched: []string{"tmp_orders"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTableFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.matched {
+				if !f.Match(name) {
+					t.Errorf("Match(%q) = false, want true", name)
+				}
+			}
+			for _, name := range tt.unmatched {
+				if f.Match(name) {
+					t.Errorf("Match(%q) = true, want false", name)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTableFilterInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude []string
+	}{
+		{"invalid glob", []string{"audit_["}, nil},
+		{"invalid regular expression", []string{"re:audit_("}, nil},
+		{"invalid exclude", nil, []string{"re:*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTableFilter(tt.include, tt.exclude); err == nil {
+				t.Error("NewTableFilter succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestTableFilterFilter(t *testing.T) {
+	f, err := NewTableFilter([]string{"re:.*s"}, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := f.Filter([]string{"users", "orders", "audit_log", "items"})
+	if want := []string{"orders", "items"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter = %q, want %q", got, want)
+	}
+}
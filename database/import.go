@@ -3,8 +3,11 @@ package database
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"strings"
+	"unicode"
 
 	"gorm.io/gorm"
 )
@@ -37,17 +40,203 @@ func ImportSQLFileManyInserts(db *gorm.DB, filename string) error {
 	return scanner.Err()
 }
 
+// ImportSQLFile executes every statement of filename in order. Statements are
+// sent one at a time, as not every driver accepts several in a single call.
 func ImportSQLFile(db *gorm.DB, filename string) error {
-	content, err := os.ReadFile(filename)
+	file, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+
+	return SplitSQLStatements(file, func(stmt string) error {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("error executing SQL from file %s: %w", filename, err)
+		}
+		return nil
+	})
+}
+
+// SplitSQLStatements reads SQL from r and calls fn with each complete
+// statement. Semicolons inside quoted strings, quoted identifiers, comments and
+// PostgreSQL dollar-quoted bodies do not end a statement. Statements made only
+// of whitespace and comments are dropped. A DELIMITER line, as written by
+// mysqldump, changes what ends the statements that follow, so bodies of
+// triggers and routines can hold semicolons.
+func SplitSQLStatements(r io.Reader, fn func(stmt string) error) error {
+	reader := bufio.NewReader(r)
+	var stmt strings.Builder
+	hasCode := false
+	delimiter := ";"
+
+	flush := func() error {
+		if hasCode {
+			if err := fn(strings.TrimSpace(stmt.String())); err != nil {
+				return err
+			}
+		}
+		stmt.Reset()
+		hasCode = false
+		return nil
+	}
+
+	// readUntil copies runes up to and including the terminator, which is
+	// only looked for in the runes it copies, not in the opening text.
+	readUntil := func(terminator string) error {
+		start := stmt.Len()
+		for {
+			c, _, err := reader.ReadRune()
+			if err != nil {
+				return err
+			}
+			stmt.WriteRune(c)
+			if strings.HasSuffix(stmt.String()[start:], terminator) {
+				return nil
+			}
+		}
+	}
+
+	for {
+		c, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+		stmt.WriteRune(c)
+
+		if strings.HasSuffix(stmt.String(), delimiter) {
+			s := strings.TrimSuffix(stmt.String(), delimiter)
+			stmt.Reset()
+			stmt.WriteString(s)
+			if err := flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		if !hasCode && (c == 'D' || c == 'd') {
+			if line, ok := peekDelimiterLine(reader); ok {
+				reader.Discard(len(line))
+				delimiter = strings.TrimSpace(line[len("ELIMITER"):])
+				stmt.Reset()
+				continue
+			}
+		}
+
+		switch c {
+		case '\'', '"', '`':
+			// Quotes are escaped by doubling them, which simply reads as two
+			// adjacent quoted sections.
+			hasCode = true
+			start := stmt.Len()
+			for {
+				q, _, err := reader.ReadRune()
+				if err != nil {
+					return fmt.Errorf("unterminated quoted text in statement starting %.40q", stmt.String()[:start])
+				}
+				stmt.WriteRune(q)
+				if q == c {
+					break
+				}
+			}
+		case '-':
+			if next, _ := reader.Peek(1); len(next) == 1 && next[0] == '-' {
+				if err := readUntil("\n"); err != nil && err != io.EOF {
+					return err
+				}
+				continue
+			}
+			hasCode = true
+		case '/':
+			if next, _ := reader.Peek(1); len(next) == 1 && next[0] == '*' {
+				reader.Discard(1)
+				stmt.WriteByte('*')
+				if err := readUntil("*/"); err != nil {
+					return fmt.Errorf("unterminated block comment")
+				}
+				continue
+			}
+			hasCode = true
+		case '$':
+			hasCode = true
+			tag, ok := peekDollarTag(reader)
+			if !ok {
+				continue
+			}
+			reader.Discard(len(tag) - 1)
+			stmt.WriteString(tag[1:])
+			if err := readUntil(tag); err != nil {
+				return fmt.Errorf("unterminated dollar-quoted text %s", tag)
+			}
+		default:
+			if !unicode.IsSpace(c) {
+				hasCode = true
+			}
+		}
+	}
+}
 
-	sql := string(content)
+// delimiterPattern matches the rest of a DELIMITER line after its D.
+var delimiterPattern = regexp.MustCompile(`(?i)^ELIMITER[ \t]+\S+[ \t]*(\r?\n|$)`)
 
-	if err := db.Exec(sql).Error; err != nil {
-		return fmt.Errorf("error executing SQL from file %s: %w", filename, err)
+// peekDelimiterLine returns the rest of the DELIMITER line starting at the D
+// just read, without consuming it.
+func peekDelimiterLine(reader *bufio.Reader) (string, bool) {
+	buf, _ := reader.Peek(64)
+	m := delimiterPattern.Find(buf)
+	if m == nil {
+		return "", false
 	}
+	return string(m), true
+}
 
-	return nil
+// peekDollarTag returns the PostgreSQL dollar-quote tag ($$ or $name$) that
+// starts at the '$' just read, without consuming it.
+func peekDollarTag(reader *bufio.Reader) (string, bool) {
+	for n := 1; n <= 64; n++ {
+		buf, err := reader.Peek(n)
+		if err != nil {
+			return "", false
+		}
+		c := rune(buf[n-1])
+		if c == '$' {
+			return "$" + string(buf), true
+		}
+		if !(c == '_' || unicode.IsLetter(c) || (n > 1 && unicode.IsDigit(c))) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// ImportSQLStatements executes the statements of filename one by one, each in
+// its own transaction, skipping the first skip statements. After every commit
+// onCommit is called with the number of statements committed so far, which
+// lets callers checkpoint progress. It returns that same count.
+func ImportSQLStatements(db *gorm.DB, filename string, skip int, onCommit func(done int) error) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	done := 0
+	err = SplitSQLStatements(file, func(stmt string) error {
+		if done < skip {
+			done++
+			return nil
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return tx.Exec(stmt).Error
+		}); err != nil {
+			return fmt.Errorf("error executing statement %d from file %s: %w", done+1, filename, err)
+		}
+		done++
+		if onCommit != nil {
+			return onCommit(done)
+		}
+		return nil
+	})
+	return done, err
 }
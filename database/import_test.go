@@ -0,0 +1,42 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"plain", "SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"quoted semicolon", "INSERT INTO t VALUES ('a;b');", []string{"INSERT INTO t VALUES ('a;b')"}},
+		{"comments", "-- a; b\nSELECT 1; /* ; */", []string{"-- a; b\nSELECT 1"}},
+		{"block comment slash", "/*/ ; */ SELECT 1;", []string{"/*/ ; */ SELECT 1"}},
+		{"dollar body", "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql; SELECT 2;",
+			[]string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql", "SELECT 2"}},
+		{"empty dollar body", "CREATE FUNCTION f() RETURNS void AS $$$$ LANGUAGE sql; SELECT 2;",
+			[]string{"CREATE FUNCTION f() RETURNS void AS $$$$ LANGUAGE sql", "SELECT 2"}},
+		{"empty tagged body", "DO $body$$body$; SELECT 2;", []string{"DO $body$$body$", "SELECT 2"}},
+		{"delimiter", "DELIMITER //\nCREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW BEGIN SET @a = 1; END//\nDELIMITER ;\nSELECT 1;",
+			[]string{"CREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW BEGIN SET @a = 1; END", "SELECT 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := SplitSQLStatements(strings.NewReader(tt.sql), func(stmt string) error {
+				got = append(got, stmt)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LockName identifies the lock taken by every command that modifies the
+// import database.
+const LockName = "sql_migration"
+
+// SQLiteLockTable holds the lock rows on SQLite and DuckDB, which have no
+// advisory locks.
+const SQLiteLockTable = "sql_migration_lock"
+
+// lockRetryInterval is how often a held lock is retried until the timeout.
+const lockRetryInterval = 500 * time.Millisecond
+
+// Lock is a database-wide lock held until Release is called.
+type Lock struct {
+	release func() error
+}
+
+// Release frees the lock.
+func (l *Lock) Release() error {
+	return l.release()
+}
+
+// AcquireLock takes the named lock on the database of db, waiting up to
+// timeout while another process holds it. The error names the holder when
+// the lock could not be taken.
+//
+// PostgreSQL uses a session-level advisory lock and MySQL GET_LOCK, both held
+// on a dedicated connection so they are released if the process dies. SQLite
+// and DuckDB use a row of the sql_migration_lock table, which a crashed
+// process leaves behind until it is deleted.
+func AcquireLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.AcquireLock(db, name, timeout)
+}
+
+// advisoryKey maps a lock name to the bigint key of pg_advisory_lock.
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// pinConnection takes a connection out of the pool of db for session-level locks.
+func pinConnection(db *gorm.DB) (*sql.Conn, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return sqlDB.Conn(context.Background())
+}
+
+func acquirePostgresLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	conn, err := pinConnection(db)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	key := advisoryKey(name)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			holder := postgresLockHolder(ctx, conn, key)
+			conn.Close()
+			return nil, fmt.Errorf("lock %q is held by %s (waited %s)", name, holder, timeout)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	return &Lock{release: func() error {
+		defer conn.Close()
+		var released bool
+		return conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", key).Scan(&released)
+	}}, nil
+}
+
+// postgresLockHolder describes the session holding the advisory lock key. A
+// bigint advisory key is stored in pg_locks as classid (high half) and objid
+// (low half) with objsubid 1.
+func postgresLockHolder(ctx context.Context, conn *sql.Conn, key int64) string {
+	var (
+		pid     int64
+		user    string
+		app     string
+		client  string
+		started time.Time
+	)
+	err := conn.QueryRowContext(ctx, `SELECT a.pid, COALESCE(a.usename, ''), COALESCE(a.application_name, ''),
+		COALESCE(host(a.client_addr), 'local'), a.backend_start
+		FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+		AND ((l.classid::bigint << 32) | l.objid::bigint) = $1`, key).Scan(&pid, &user, &app, &client, &started)
+	if err != nil {
+		return "another session"
+	}
+	return fmt.Sprintf("backend pid %d (user %s, application %q, client %s, connected since %s)",
+		pid, user, app, client, started.Format(time.RFC3339))
+}
+
+func acquireMySQLLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	conn, err := pinConnection(db)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	// GET_LOCK names are server-wide, so scope the lock to the database
+	var schema string
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(DATABASE(), '')").Scan(&schema); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	lockName := name + ":" + schema
+	if len(lockName) > 64 {
+		lockName = fmt.Sprintf("%s:%x", name, advisoryKey(schema))
+	}
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(timeout.Seconds())).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		holder := mysqlLockHolder(ctx, conn, lockName)
+		conn.Close()
+		return nil, fmt.Errorf("lock %q is held by %s (waited %s)", lockName, holder, timeout)
+	}
+
+	return &Lock{release: func() error {
+		defer conn.Close()
+		var released sql.NullInt64
+		return conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", lockName).Scan(&released)
+	}}, nil
+}
+
+// mysqlLockHolder describes the connection holding lockName.
+func mysqlLockHolder(ctx context.Context, conn *sql.Conn, lockName string) string {
+	var id sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", lockName).Scan(&id); err != nil || !id.Valid {
+		return "another connection"
+	}
+	var (
+		user    string
+		host    string
+		seconds int64
+	)
+	err := conn.QueryRowContext(ctx, "SELECT user, host, time FROM information_schema.processlist WHERE id = ?", id.Int64).
+		Scan(&user, &host, &seconds)
+	if err != nil {
+		return fmt.Sprintf("connection %d", id.Int64)
+	}
+	return fmt.Sprintf("connection %d (user %s from %s, running for %ds)", id.Int64, user, host, seconds)
+}
+
+// acquireTableLock takes the lock as a row of SQLiteLockTable, for databases
+// without advisory locks.
+func acquireTableLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name TEXT PRIMARY KEY, holder TEXT NOT NULL, acquired_at DATETIME NOT NULL)", SQLiteLockTable)
+	if err := db.Exec(create).Error; err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	holder := fmt.Sprintf("pid %d on %s", os.Getpid(), host)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		// The insert only succeeds when no other process holds the row
+		result := db.Exec(fmt.Sprintf("INSERT OR IGNORE INTO %s (name, holder, acquired_at) VALUES (?, ?, ?)", SQLiteLockTable),
+			name, holder, time.Now().UTC())
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			var current struct {
+				Holder     string
+				AcquiredAt time.Time
+			}
+			err := db.Raw(fmt.Sprintf("SELECT holder, acquired_at FROM %s WHERE name = ?", SQLiteLockTable), name).Scan(&current).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("lock %q is held by %s since %s (waited %s); if that process is gone, delete its row from %s",
+				name, current.Holder, current.AcquiredAt.Format(time.RFC3339), timeout, SQLiteLockTable)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	return &Lock{release: func() error {
+		return db.Exec(fmt.Sprintf("DELETE FROM %s WHERE name = ? AND holder = ?", SQLiteLockTable), name, holder).Error
+	}}, nil
+}
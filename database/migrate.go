@@ -0,0 +1,336 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a numbered pair of NNNN_name.up.sql / NNNN_name.down.sql files.
+type Migration struct {
+	Version  int64
+	Name     string
+	UpFile   string
+	DownFile string
+}
+
+// AppliedMigration is a row of the schema_migrations history table.
+type AppliedMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string `gorm:"size:64"`
+	AppliedAt time.Time
+}
+
+// TableName keeps the history table name independent of gorm's naming strategy.
+func (AppliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// BaselineFile is the schema snapshot written by migrate baseline. It is
+// loaded as migration 0, which has no down file.
+const BaselineFile = "0000_baseline.sql"
+
+// LoadMigrations reads the migrations of dir, sorted by version.
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	hasBaseline := false
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if entry.Name() == BaselineFile {
+			if _, ok := byVersion[0]; ok {
+				return nil, fmt.Errorf("migration version 0 is used by both %s and another migration", BaselineFile)
+			}
+			byVersion[0] = &Migration{Version: 0, Name: "baseline", UpFile: filepath.Join(dir, BaselineFile)}
+			hasBaseline = true
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if ok && version == 0 && hasBaseline {
+			return nil, fmt.Errorf("migration version 0 is used by both %s and %s", BaselineFile, entry.Name())
+		}
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+		path := filepath.Join(dir, entry.Name())
+		if match[3] == "up" {
+			m.UpFile = path
+		} else {
+			m.DownFile = path
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpFile == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no .up.sql file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Checksum returns the SHA-256 of the up file, recorded when the migration is
+// applied to detect later edits.
+func (m Migration) Checksum() (string, error) {
+	content, err := os.ReadFile(m.UpFile)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NewMigration creates empty up and down files for the next version in dir.
+func NewMigration(dir, name string) (Migration, error) {
+	return WriteMigration(dir, name, nil, nil)
+}
+
+// WriteMigration creates the up and down files for the next version in dir,
+// holding the given statements.
+func WriteMigration(dir, name string, up, down []string) (Migration, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return Migration{}, fmt.Errorf("migration name must contain letters or digits")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Migration{}, err
+	}
+
+	existing, err := LoadMigrations(dir)
+	if err != nil {
+		return Migration{}, err
+	}
+	version := int64(1)
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	m := Migration{
+		Version:  version,
+		Name:     name,
+		UpFile:   filepath.Join(dir, fmt.Sprintf("%04d_%s.up.sql", version, name)),
+		DownFile: filepath.Join(dir, fmt.Sprintf("%04d_%s.down.sql", version, name)),
+	}
+	if err := writeMigrationFile(m.UpFile, fmt.Sprintf("-- Migration %04d_%s: apply", version, name), up); err != nil {
+		return Migration{}, err
+	}
+	if err := writeMigrationFile(m.DownFile, fmt.Sprintf("-- Migration %04d_%s: revert", version, name), down); err != nil {
+		return Migration{}, err
+	}
+	return m, nil
+}
+
+func writeMigrationFile(path, header string, statements []string) error {
+	var b strings.Builder
+	b.WriteString(header + "\n")
+	for _, stmt := range statements {
+		b.WriteString(stmt + "\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// EnsureMigrationsTable creates the schema_migrations table if needed.
+func EnsureMigrationsTable(db *gorm.DB) error {
+	return db.AutoMigrate(&AppliedMigration{})
+}
+
+// AppliedMigrations returns the history of applied migrations by version.
+func AppliedMigrations(db *gorm.DB) ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	err := db.Order("version").Find(&applied).Error
+	return applied, err
+}
+
+// VerifyMigrationChecksums fails when the up file of an applied migration was
+// modified after it was applied.
+func VerifyMigrationChecksums(migrations []Migration, applied []AppliedMigration) error {
+	files := make(map[int64]Migration)
+	for _, m := range migrations {
+		files[m.Version] = m
+	}
+
+	var modified []string
+	for _, a := range applied {
+		m, ok := files[a.Version]
+		if !ok || a.Checksum == "" {
+			continue
+		}
+		sum, err := m.Checksum()
+		if err != nil {
+			return err
+		}
+		if sum != a.Checksum {
+			modified = append(modified, filepath.Base(m.UpFile))
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("applied migrations were modified: %s", strings.Join(modified, ", "))
+	}
+	return nil
+}
+
+// ApplyMigration runs the up file of m and records it in the history table,
+// in a single transaction where the database supports transactional DDL.
+func ApplyMigration(db *gorm.DB, m Migration) error {
+	sum, err := m.Checksum()
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := ImportSQLFile(tx, m.UpFile); err != nil {
+			return err
+		}
+		return tx.Create(&AppliedMigration{Version: m.Version, Name: m.Name, Checksum: sum, AppliedAt: time.Now().UTC()}).Error
+	})
+}
+
+// RevertMigration runs the down file of m and removes it from the history table.
+func RevertMigration(db *gorm.DB, m Migration) error {
+	if m.DownFile == "" {
+		return fmt.Errorf("migration %04d_%s has no .down.sql file", m.Version, m.Name)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := ImportSQLFile(tx, m.DownFile); err != nil {
+			return err
+		}
+		return tx.Delete(&AppliedMigration{}, "version = ?", m.Version).Error
+	})
+}
+
+var baselineHeaderPattern = regexp.MustCompile(`^-- Migration 0000_baseline: schema snapshot at version (\d+)`)
+
+// WriteBaseline snapshots the schema of tables into dir/0000_baseline.sql:
+// the extensions, types and domains first, then one ExportSchemaSQL statement
+// per table, each after the tables its foreign keys reference, and the views,
+// routines and the indexes and triggers of tables last. The header records
+// version, the last migration the snapshot already contains.
+func WriteBaseline(db *gorm.DB, dir string, tables []string, version int64) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".baseline-*.sql")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	schema, err := LoadSchema(db, tables)
+	if err != nil {
+		return "", err
+	}
+	objects, err := ListObjects(db, nil, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to list objects: %w", err)
+	}
+	var prelude, rest []SchemaObject
+	for _, obj := range objects {
+		switch {
+		case BeforeTables(obj.Kind):
+			prelude = append(prelude, obj)
+		case obj.Table == "" || schema.Tables[obj.Table] != nil:
+			rest = append(rest, obj)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Migration 0000_baseline: schema snapshot at version %d\n", version)
+	// appendExport writes a file with export and appends it to the baseline
+	appendExport := func(what string, export func(filename string) error) error {
+		if err := export(tmp.Name()); err != nil {
+			return fmt.Errorf("failed to export %s: %w", what, err)
+		}
+		content, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return err
+		}
+		b.Write(content)
+		return nil
+	}
+	if len(prelude) > 0 {
+		if err := appendExport("extensions, types and domains", func(filename string) error {
+			return ExportObjectsSQL(db, prelude, filename)
+		}); err != nil {
+			return "", err
+		}
+	}
+	for _, table := range schema.ReferenceOrder() {
+		if err := appendExport("schema of "+table, func(filename string) error {
+			return ExportSchemaSQL(db, table, filename)
+		}); err != nil {
+			return "", err
+		}
+	}
+	if len(rest) > 0 {
+		if err := appendExport("objects", func(filename string) error {
+			return ExportObjectsSQL(db, rest, filename)
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	path := filepath.Join(dir, BaselineFile)
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// BaselineVersion returns the last migration contained in the baseline m, so
+// that applying the baseline to an empty database also skips those migrations.
+func BaselineVersion(m Migration) (int64, error) {
+	content, err := os.ReadFile(m.UpFile)
+	if err != nil {
+		return 0, err
+	}
+	match := baselineHeaderPattern.FindSubmatch(content)
+	if match == nil {
+		return 0, nil
+	}
+	return strconv.ParseInt(string(match[1]), 10, 64)
+}
+
+// MarkMigrationsApplied records migrations as applied without running them.
+func MarkMigrationsApplied(db *gorm.DB, migrations []Migration) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range migrations {
+			sum, err := m.Checksum()
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(&AppliedMigration{Version: m.Version, Name: m.Name, Checksum: sum, AppliedAt: time.Now().UTC()}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
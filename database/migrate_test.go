@@ -0,0 +1,255 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// openTestDuckDB opens an in-memory DuckDB database and runs stmts on it.
+func openTestDuckDB(t *testing.T, stmts ...string) *gorm.DB {
+	t.Helper()
+	d, err := LookupDialect("duckdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := d.Open("", &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+// TestWriteBaseline applies a baseline to an empty DuckDB database, which
+// checks foreign keys when a table is created: the comments table sorts
+// before the posts table it references.
+func TestWriteBaseline(t *testing.T) {
+	source := openTestDuckDB(t,
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, title VARCHAR NOT NULL)",
+		"CREATE TABLE comments (id INTEGER PRIMARY KEY, post_id INTEGER REFERENCES posts (id), body VARCHAR)",
+		"CREATE INDEX idx_comments_post ON comments (post_id)",
+		"CREATE VIEW post_comments AS SELECT p.title, c.body FROM posts p JOIN comments c ON c.post_id = p.id",
+	)
+	dir := t.TempDir()
+	path, err := WriteBaseline(source, dir, []string{"comments", "posts"}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(content)
+	order := []string{"CREATE TABLE posts", `CREATE TABLE "comments"`, "CREATE VIEW post_comments", "CREATE INDEX idx_comments_post"}
+	last := -1
+	for _, stmt := range order {
+		at := strings.Index(sql, stmt)
+		if at < last {
+			t.Fatalf("%q missing or out of order in\n%s", stmt, sql)
+		}
+		last = at
+	}
+
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 {
+		t.Fatalf("loaded %d migrations, want the baseline", len(migrations))
+	}
+	if version, err := BaselineVersion(migrations[0]); err != nil || version != 3 {
+		t.Errorf("BaselineVersion = %d, %v, want 3", version, err)
+	}
+
+	target := openTestDuckDB(t)
+	if err := EnsureMigrationsTable(target); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyMigration(target, migrations[0]); err != nil {
+		t.Fatalf("applying the baseline: %v", err)
+	}
+	diff := DiffSchemas(mustLoadSchema(t, source, "comments", "posts"), mustLoadSchema(t, target, "comments", "posts"))
+	if stmts := diff.AlterSQL(duckdbDialect{}); len(stmts) > 0 {
+		t.Errorf("baseline differs from the source: %q", stmts)
+	}
+	if err := target.Exec("SELECT * FROM post_comments").Error; err != nil {
+		t.Errorf("view not created: %v", err)
+	}
+}
+
+func mustLoadSchema(t *testing.T, db *gorm.DB, tables ...string) *Schema {
+	t.Helper()
+	schema, err := LoadSchema(db, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestWriteMigration(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	first, err := NewMigration(dir, "Create Users!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Version != 1 || first.Name != "create_users" || filepath.Base(first.UpFile) != "0001_create_users.up.sql" ||
+		filepath.Base(first.DownFile) != "0001_create_users.down.sql" {
+		t.Errorf("NewMigration = %+v, want 0001_create_users", first)
+	}
+	second, err := WriteMigration(dir, "add email", []string{"ALTER TABLE users ADD COLUMN email TEXT;"}, []string{"ALTER TABLE users DROP COLUMN email;"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Version != 2 {
+		t.Errorf("second migration has version %d, want 2", second.Version)
+	}
+	content, err := os.ReadFile(second.UpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-- Migration 0002_add_email: apply\nALTER TABLE users ADD COLUMN email TEXT;\n"; string(content) != want {
+		t.Errorf("up file = %q, want %q", content, want)
+	}
+	if _, err := WriteMigration(dir, "--", nil, nil); err == nil {
+		t.Error("WriteMigration accepted a name without letters or digits")
+	}
+
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0] != first || migrations[1] != second {
+		t.Errorf("LoadMigrations = %+v, want %+v and %+v", migrations, first, second)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+		err   string
+	}{
+		{"sorted by version", []string{"0010_b.up.sql", "0002_a.up.sql", "0002_a.down.sql", "notes.txt"}, "2_a,10_b", ""},
+		{"baseline", []string{BaselineFile, "0001_a.up.sql"}, "0_baseline,1_a", ""},
+		{"no up file", []string{"0001_a.down.sql"}, "", "has no .up.sql file"},
+		{"version reused", []string{"0001_a.up.sql", "0001_b.up.sql"}, "", "is used by both"},
+		{"baseline version reused", []string{BaselineFile, "0000_init.up.sql"}, "", "is used by both"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, name := range tt.files {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		migrations, err := LoadMigrations(dir)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, m := range migrations {
+			got = append(got, fmt.Sprintf("%d_%s", m.Version, m.Name))
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: loaded %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyAndRevertMigration(t *testing.T) {
+	db := openTestSQLite(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	if err := EnsureMigrationsTable(db); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	m, err := WriteMigration(dir, "add email",
+		[]string{"ALTER TABLE users ADD COLUMN email TEXT;", "CREATE INDEX idx_users_email ON users (email);"},
+		[]string{"DROP INDEX idx_users_email;", "ALTER TABLE users DROP COLUMN email;"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyMigration(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO users (id, name, email) VALUES (1, 'Ann', 'ann@example.com')").Error; err != nil {
+		t.Errorf("column not added: %v", err)
+	}
+	applied, err := AppliedMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := m.Checksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].Version != 1 || applied[0].Name != "add_email" || applied[0].Checksum != sum {
+		t.Fatalf("history after apply = %+v", applied)
+	}
+	if err := VerifyMigrationChecksums([]Migration{m}, applied); err != nil {
+		t.Errorf("unmodified migration: %v", err)
+	}
+	if err := os.WriteFile(m.UpFile, []byte("ALTER TABLE users ADD COLUMN phone TEXT;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyMigrationChecksums([]Migration{m}, applied); err == nil || !strings.Contains(err.Error(), "0001_add_email.up.sql") {
+		t.Errorf("modified migration: error %v, want it named", err)
+	}
+
+	if err := RevertMigration(db, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("SELECT email FROM users").Error; err == nil {
+		t.Error("column still exists after revert")
+	}
+	if applied, err := AppliedMigrations(db); err != nil || len(applied) != 0 {
+		t.Errorf("history after revert = %+v, %v, want empty", applied, err)
+	}
+
+	if err := RevertMigration(db, Migration{Version: 0, Name: "baseline", UpFile: m.UpFile}); err == nil {
+		t.Error("reverted a migration without a down file")
+	}
+}
+
+// TestApplyMigrationFailure checks that a failing migration is not recorded
+// and, as SQLite has transactional DDL, leaves no partial changes.
+func TestApplyMigrationFailure(t *testing.T) {
+	db := openTestSQLite(t, "CREATE TABLE users (id INTEGER PRIMARY KEY)")
+	if err := EnsureMigrationsTable(db); err != nil {
+		t.Fatal(err)
+	}
+	m, err := WriteMigration(t.TempDir(), "broken", []string{"CREATE TABLE accounts (id INTEGER);", "ALTER TABLE missing ADD COLUMN x TEXT;"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyMigration(db, m); err == nil {
+		t.Fatal("applied a migration with a failing statement")
+	}
+	if applied, err := AppliedMigrations(db); err != nil || len(applied) != 0 {
+		t.Errorf("history = %+v, %v, want empty", applied, err)
+	}
+	if exists, err := TableExists(db, "accounts"); err != nil || exists {
+		t.Errorf("accounts exists = %v, %v, want the transaction rolled back", exists, err)
+	}
+}
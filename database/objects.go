@@ -0,0 +1,343 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Besides tables, a schema holds views, materialized views, triggers,
+// routines and standalone indexes (those created with CREATE INDEX rather
+// than inside CREATE TABLE). They are exported to files of their own and
+// imported after the tables and their data: routines first, as views and
+// triggers call them, then views in dependency order, materialized views,
+// indexes and finally triggers, so imported rows do not fire them.
+//
+// The extensions, types and domains of PostgreSQL are exported the same
+// way, but imported ahead of the tables whose columns they type.
+
+// Kinds of SchemaObject, in import order.
+const (
+	KindExtension        = "extension"
+	KindType             = "type"
+	KindDomain           = "domain"
+	KindFunction         = "function"
+	KindProcedure        = "procedure"
+	KindView             = "view"
+	KindMaterializedView = "materialized view"
+	KindIndex            = "index"
+	KindTrigger          = "trigger"
+)
+
+// Object types select tables and kinds of objects, as listed by --objects.
+const (
+	ObjectTables     = "tables"
+	ObjectExtensions = "extensions"
+	// ObjectUserTypes selects enum and composite types and domains.
+	ObjectUserTypes = "types"
+	ObjectIndexes   = "indexes"
+	ObjectViews     = "views"
+	ObjectTriggers  = "triggers"
+	ObjectRoutines  = "routines"
+)
+
+// ObjectTypes lists every object type.
+var ObjectTypes = []string{ObjectTables, ObjectExtensions, ObjectUserTypes, ObjectIndexes, ObjectViews, ObjectTriggers, ObjectRoutines}
+
+// objectKinds lists the kinds in import order with their object type, and
+// whether they are imported before the tables.
+var objectKinds = []struct {
+	kind, objectType string
+	beforeTables     bool
+}{
+	{KindExtension, ObjectExtensions, true},
+	{KindType, ObjectUserTypes, true},
+	{KindDomain, ObjectUserTypes, true},
+	{KindFunction, ObjectRoutines, false},
+	{KindProcedure, ObjectRoutines, false},
+	{KindView, ObjectViews, false},
+	{KindMaterializedView, ObjectViews, false},
+	{KindIndex, ObjectIndexes, false},
+	{KindTrigger, ObjectTriggers, false},
+}
+
+// ObjectKinds returns every kind of object, in import order.
+func ObjectKinds() []string {
+	kinds := make([]string, len(objectKinds))
+	for i, k := range objectKinds {
+		kinds[i] = k.kind
+	}
+	return kinds
+}
+
+// ObjectType returns the object type of kind.
+func ObjectType(kind string) string {
+	for _, k := range objectKinds {
+		if k.kind == kind {
+			return k.objectType
+		}
+	}
+	return ""
+}
+
+// BeforeTables reports whether objects of kind are imported before the
+// tables, as tables may use them.
+func BeforeTables(kind string) bool {
+	for _, k := range objectKinds {
+		if k.kind == kind {
+			return k.beforeTables
+		}
+	}
+	return false
+}
+
+// kindRank is the position of kind in the import order.
+func kindRank(kind string) int {
+	for i, k := range objectKinds {
+		if k.kind == kind {
+			return i
+		}
+	}
+	return len(objectKinds)
+}
+
+// ValidObjectTypes reports an error for an unknown object type.
+func ValidObjectTypes(types []string) error {
+	for _, t := range types {
+		known := false
+		for _, ot := range ObjectTypes {
+			known = known || t == ot
+		}
+		if !known {
+			return fmt.Errorf("unknown object type %q (expected %s)", t, strings.Join(ObjectTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// SchemaObject is a view, materialized view, trigger, routine, standalone
+// index, extension, type or domain.
+type SchemaObject struct {
+	Kind string
+	// Name is schema-qualified outside the current schema, as table names
+	// are. Trigger names are not, on databases where triggers belong to
+	// their table.
+	Name string
+	// Table is the table of an index or trigger.
+	Table string
+	// Signature is the argument list of a PostgreSQL routine, which tells
+	// overloads apart.
+	Signature string
+	// Definition is the statement creating the object.
+	Definition string
+}
+
+// ListObjects returns the objects of the current schema, of schemas, or of
+// every user schema with all set, ordered for import: by kind, and views
+// after the views they select from.
+func ListObjects(db *gorm.DB, schemas []string, all bool) ([]SchemaObject, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 && !all {
+		current, err := d.CurrentSchema(db)
+		if err != nil {
+			return nil, err
+		}
+		schemas = []string{current}
+	}
+	objects, err := d.ListObjects(db, schemas, all)
+	if err != nil {
+		return nil, err
+	}
+	return SortObjects(objects), nil
+}
+
+// queryObjects runs a catalog query returning the kind, schema, name, table,
+// signature and definition of objects, qualifying names and tables outside
+// current.
+func queryObjects(db *gorm.DB, current, query string, args ...any) ([]SchemaObject, error) {
+	rows, err := db.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var objects []SchemaObject
+	for rows.Next() {
+		var obj SchemaObject
+		var schema string
+		if err := rows.Scan(&obj.Kind, &schema, &obj.Name, &obj.Table, &obj.Signature, &obj.Definition); err != nil {
+			return nil, err
+		}
+		if schema != current {
+			obj.Name = QualifyTableName(schema, obj.Name)
+			if obj.Table != "" {
+				obj.Table = QualifyTableName(schema, obj.Table)
+			}
+		}
+		objects = append(objects, obj)
+	}
+	return objects, rows.Err()
+}
+
+// SortObjects orders objects for import: by kind, and within a kind after
+// the objects of that kind their definitions name, so views come after the
+// views they select from. Objects in a dependency cycle keep their order.
+func SortObjects(objects []SchemaObject) []SchemaObject {
+	sorted := append([]SchemaObject(nil), objects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return kindRank(sorted[i].Kind) < kindRank(sorted[j].Kind)
+	})
+
+	var result []SchemaObject
+	for start := 0; start < len(sorted); {
+		end := start
+		for end < len(sorted) && sorted[end].Kind == sorted[start].Kind {
+			end++
+		}
+		result = append(result, sortByDependencies(sorted[start:end])...)
+		start = end
+	}
+	return result
+}
+
+// sortByDependencies orders objects so that each comes after the others
+// its definition names, keeping the given order otherwise.
+func sortByDependencies(objects []SchemaObject) []SchemaObject {
+	names := make([]map[string]bool, len(objects))
+	for i, obj := range objects {
+		names[i] = definitionNames(obj.Definition)
+	}
+	// deps[i] lists the objects i names
+	deps := make([][]int, len(objects))
+	for i := range objects {
+		for j, other := range objects {
+			_, bare := SplitTableName(other.Name)
+			if i != j && (names[i][other.Name] || names[i][bare]) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	placed := make([]bool, len(objects))
+	var result []SchemaObject
+	for len(result) < len(objects) {
+		progress := false
+		for i, obj := range objects {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for _, j := range deps[i] {
+				ready = ready && placed[j]
+			}
+			if ready {
+				placed[i], progress = true, true
+				result = append(result, obj)
+			}
+		}
+		if !progress {
+			// A cycle; the rest keep their order
+			for i, obj := range objects {
+				if !placed[i] {
+					placed[i] = true
+					result = append(result, obj)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// definitionNames returns the names a definition mentions, bare and
+// qualified. Definitions that cannot be tokenized mention nothing.
+func definitionNames(definition string) map[string]bool {
+	names := map[string]bool{}
+	tokens, err := tokenizeDDL(definition)
+	if err != nil {
+		return names
+	}
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].ident == "" {
+			continue
+		}
+		names[QualifyTableName("", tokens[i].ident)] = true
+		if i+2 < len(tokens) && tokens[i+1].text == "." && tokens[i+2].ident != "" {
+			names[QualifyTableName(tokens[i].ident, tokens[i+2].ident)] = true
+		}
+	}
+	return names
+}
+
+// ObjectTable returns the table an index or trigger definition is created
+// on, the name following its first ON, or "" without one.
+func ObjectTable(definition string) string {
+	tokens, err := tokenizeDDL(definition)
+	if err != nil {
+		return ""
+	}
+	for i, t := range tokens {
+		if t.is("ON") {
+			name, _ := parseDDLName(tokens, i+1)
+			return name
+		}
+	}
+	return ""
+}
+
+// ExportObjectsSQL writes the definitions of objects to filename. A
+// definition holding several statements, such as a trigger with a BEGIN ...
+// END body, is wrapped in DELIMITER lines as mysqldump does, so that
+// SplitSQLStatements reads it back as one statement. Objects outside the
+// current schema are preceded by the statement creating their schema, as
+// those imported before the tables cannot rely on the tables creating it.
+func ExportObjectsSQL(db *gorm.DB, objects []SchemaObject, filename string) error {
+	d, err := DialectOf(db)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	created := map[string]bool{}
+	for _, obj := range objects {
+		if schema, _ := SplitTableName(obj.Name); schema != "" && !created[schema] {
+			created[schema] = true
+			if create := d.CreateSchemaSQL(schema); create != "" {
+				b.WriteString(create + "\n")
+			}
+		}
+		def := strings.TrimSuffix(strings.TrimSpace(obj.Definition), ";")
+		if countSQLStatements(def) > 1 {
+			fmt.Fprintf(&b, "DELIMITER %s\n%s%s\nDELIMITER ;\n", objectDelimiter, def, objectDelimiter)
+		} else {
+			b.WriteString(def + ";\n")
+		}
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
+
+// objectDelimiter ends definitions holding several statements.
+const objectDelimiter = ";;"
+
+// countSQLStatements returns the number of statements SplitSQLStatements
+// reads from sql.
+func countSQLStatements(sql string) int {
+	n := 0
+	SplitSQLStatements(strings.NewReader(sql), func(string) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+// DropObjectSQL returns the statement dropping obj, which must exist.
+func DropObjectSQL(db *gorm.DB, obj SchemaObject) (string, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return "", err
+	}
+	return d.DropObjectSQL(obj), nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []SchemaObject
+		want    string
+	}{
+		{
+			"kinds in import order",
+			[]SchemaObject{
+				{Kind: KindTrigger, Name: "audit", Definition: "CREATE TRIGGER audit AFTER INSERT ON orders BEGIN SELECT 1; END"},
+				{Kind: KindIndex, Name: "idx_orders_date", Definition: "CREATE INDEX idx_orders_date ON orders (date)"},
+				{Kind: KindMaterializedView, Name: "totals", Definition: "CREATE MATERIALIZED VIEW totals AS SELECT 1"},
+				{Kind: KindView, Name: "recent", Definition: "CREATE VIEW recent AS SELECT 1"},
+				{Kind: KindFunction, Name: "tax", Definition: "CREATE FUNCTION tax() RETURNS int AS 'SELECT 1'"},
+				{Kind: KindType, Name: "mood", Definition: "CREATE TYPE mood AS ENUM ('ok')"},
+				{Kind: KindExtension, Name: "pgcrypto", Definition: "CREATE EXTENSION IF NOT EXISTS pgcrypto"},
+			},
+			"pgcrypto,mood,tax,recent,totals,idx_orders_date,audit",
+		},
+		{
+			"views after the views they select from",
+			[]SchemaObject{
+				{Kind: KindView, Name: "top_customers", Definition: "CREATE VIEW top_customers AS SELECT * FROM customer_totals WHERE total > 100"},
+				{Kind: KindView, Name: "customer_totals", Definition: "CREATE VIEW customer_totals AS SELECT customer_id, sum(total) AS total FROM orders GROUP BY customer_id"},
+				{Kind: KindView, Name: "active", Definition: "CREATE VIEW active AS SELECT * FROM customers"},
+			},
+			"customer_totals,active,top_customers",
+		},
+		{
+			"qualified names",
+			[]SchemaObject{
+				{Kind: KindView, Name: "sales.b", Definition: "CREATE VIEW sales.b AS SELECT * FROM sales.a"},
+				{Kind: KindView, Name: "sales.a", Definition: "CREATE VIEW sales.a AS SELECT 1"},
+			},
+			"sales.a,sales.b",
+		},
+		{
+			"cycles keep their order",
+			[]SchemaObject{
+				{Kind: KindFunction, Name: "even", Definition: "CREATE FUNCTION even(n int) RETURNS bool AS 'SELECT odd(n - 1)'"},
+				{Kind: KindFunction, Name: "odd", Definition: "CREATE FUNCTION odd(n int) RETURNS bool AS 'SELECT even(n - 1)'"},
+			},
+			"even,odd",
+		},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, obj := range SortObjects(tt.objects) {
+			names = append(names, obj.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("%s: SortObjects = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package database
+
+import "gorm.io/gorm"
+
+// Partitioned tables are listed once, by the partitioned table: PostgreSQL
+// partitions are left out of ListTables, and MySQL partitions are not tables
+// of their own. The CREATE TABLE statement of a partitioned table declares
+// its partitioning and creates its partitions, and its rows, those of every
+// partition, are read through it. Schemas written for another dialect are
+// plain tables, as partitioning differs between databases or is missing;
+// ExportFlatSchemaSQL writes them as plain tables in the same dialect.
+
+// partitioner is implemented by dialects that have partitioned tables.
+type partitioner interface {
+	// FlatTableStatement returns the CREATE TABLE statement of tableName
+	// like CreateTableStatement, but without its partitioning.
+	FlatTableStatement(db *gorm.DB, tableName string) (string, error)
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Modes accepted by --if-exists, describing what to do when the target table
+// already exists before an import.
+const (
+	IfExistsFail     = "fail"
+	IfExistsSkip     = "skip"
+	IfExistsTruncate = "truncate"
+	IfExistsDrop     = "drop"
+)
+
+// ValidIfExists reports whether mode is one of the supported --if-exists modes.
+func ValidIfExists(mode string) bool {
+	switch mode {
+	case IfExistsFail, IfExistsSkip, IfExistsTruncate, IfExistsDrop:
+		return true
+	}
+	return false
+}
+
+// PrepareTables gets existing tables ready for an import before any schema or
+// data is loaded: the tables of truncate are emptied and those of drop are
+// dropped. Tables that do not exist are left out. Both lists are handled at
+// once with the tables referencing others first, so that emptying or
+// dropping a parent never reaches into a table already imported. What was
+// done is reported to logf, which may be nil.
+func PrepareTables(db *gorm.DB, truncate, drop []string, logf func(format string, args ...any)) error {
+	d, err := DialectOf(db)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	var names []string
+	for _, tableName := range append(append([]string(nil), truncate...), drop...) {
+		exists, err := d.TableExists(db, tableName)
+		if err != nil {
+			return err
+		}
+		if exists && !existing[tableName] {
+			existing[tableName] = true
+			names = append(names, tableName)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	schema, err := LoadSchema(db, names)
+	if err != nil {
+		return err
+	}
+	order := schema.ReferenceOrder()
+	dropped := make(map[string]bool)
+	for _, tableName := range drop {
+		dropped[tableName] = true
+	}
+	var truncateOrder, dropOrder []string
+	for i := len(order) - 1; i >= 0; i-- {
+		if dropped[order[i]] {
+			dropOrder = append(dropOrder, order[i])
+		} else {
+			truncateOrder = append(truncateOrder, order[i])
+		}
+	}
+
+	if len(truncateOrder) > 0 {
+		if err := d.TruncateTables(db, truncateOrder); err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", strings.Join(truncateOrder, ", "), err)
+		}
+		printf(logf, "Truncated existing table(s) %s\n", strings.Join(truncateOrder, ", "))
+	}
+	if len(dropOrder) > 0 {
+		if err := d.DropTables(db, dropOrder); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", strings.Join(dropOrder, ", "), err)
+		}
+		printf(logf, "Dropped existing table(s) %s\n", strings.Join(dropOrder, ", "))
+	}
+	return nil
+}
+
+// PrepareSchemaImport gets tableName ready for its schema file to be executed,
+// once PrepareTables has emptied or dropped the existing tables. It returns
+// false when the schema should not be imported, either because the table is
+// kept as is (skip) or was emptied in place (truncate). What was done to an
+// existing table is reported to logf, which may be nil.
+func PrepareSchemaImport(db *gorm.DB, tableName, mode string, logf func(format string, args ...any)) (bool, error) {
+	exists, err := TableExists(db, tableName)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return true, nil
+	}
+
+	switch mode {
+	case IfExistsSkip:
+		printf(logf, "Table %s already exists, skipping schema\n", tableName)
+		return false, nil
+	case IfExistsTruncate:
+		printf(logf, "Table %s already exists, truncated instead of recreating\n", tableName)
+		return false, nil
+	case IfExistsDrop:
+		// Only a schema file that failed halfway leaves the table behind
+		// once PrepareTables dropped it, before it is retried
+		d, err := DialectOf(db)
+		if err != nil {
+			return false, err
+		}
+		if err := d.DropTables(db, []string{tableName}); err != nil {
+			return false, err
+		}
+		printf(logf, "Dropped table %s left by a failed schema import\n", tableName)
+		return true, nil
+	default:
+		return false, fmt.Errorf("table %s already exists (use --if-exists=skip|truncate|drop)", tableName)
+	}
+}
+
+// PrepareDataImport gets tableName ready for its data file to be loaded, once
+// PrepareTables has emptied or dropped the existing tables. It returns false
+// when the data should not be imported because the table already holds rows
+// and mode is skip. What was done to an existing table is reported to logf,
+// which may be nil.
+func PrepareDataImport(db *gorm.DB, tableName, mode string, logf func(format string, args ...any)) (bool, error) {
+	if mode == IfExistsTruncate || mode == IfExistsDrop {
+		return true, nil
+	}
+	exists, err := TableExists(db, tableName)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return true, nil
+	}
+
+	rows, err := queryKeyRange(db, tableName, nil, KeyRange{}, 1)
+	if err != nil {
+		return false, err
+	}
+	hasRows := rows.Next()
+	err = rows.Close()
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		return false, err
+	}
+	if !hasRows {
+		return true, nil
+	}
+
+	if mode == IfExistsSkip {
+		printf(logf, "Table %s already has rows, skipping data\n", tableName)
+		return false, nil
+	}
+	return false, fmt.Errorf("table %s already has rows (use --if-exists=skip|truncate|drop)", tableName)
+}
+
+// printf formats a message for logf, unless logf is nil.
+func printf(logf func(format string, args ...any), format string, args ...any) {
+	if logf != nil {
+		logf(format, args...)
+	}
+}
+
+// foreignKeySwitch is how a dialect reads, disables and enables foreign key
+// checks for the current connection.
+type foreignKeySwitch struct {
+	current, disable, enable string
+}
+
+// run runs stmts with foreign key checks disabled. The checks are a
+// per-connection setting, so everything runs on a single pinned connection
+// and the previous setting is restored afterwards.
+func (s foreignKeySwitch) run(db *gorm.DB, stmts ...string) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		var enabled int
+		if err := conn.Raw(s.current).Row().Scan(&enabled); err != nil {
+			return err
+		}
+		if enabled != 0 {
+			if err := conn.Exec(s.disable).Error; err != nil {
+				return err
+			}
+			defer conn.Exec(s.enable)
+		}
+		for _, stmt := range stmts {
+			if err := conn.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
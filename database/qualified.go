@@ -0,0 +1,213 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Table names are schema-qualified ("sales.orders") when the table lives
+// outside the current schema of the connection: the search_path schema on
+// PostgreSQL, the connected database on MySQL and the main database on
+// SQLite. Unqualified names refer to the current schema, so single-schema
+// exports keep their plain names and file names.
+
+// SplitTableName splits a schema-qualified table name at its last dot outside
+// quotes, so that "db.schema.table" is table of schema "db.schema". Names
+// quoted with double quotes, backticks or brackets may contain dots, and the
+// table, and a schema of one name, are returned without their quotes. The
+// schema is empty for unqualified names.
+func SplitTableName(name string) (schema, table string) {
+	dot := lastUnquotedDot(name)
+	if dot < 0 {
+		return "", unquoteName(name)
+	}
+	schema = name[:dot]
+	if lastUnquotedDot(schema) < 0 {
+		schema = unquoteName(schema)
+	}
+	return schema, unquoteName(name[dot+1:])
+}
+
+// lastUnquotedDot returns the index of the last dot of name outside quotes,
+// or -1.
+func lastUnquotedDot(name string) int {
+	dot := -1
+	var closing byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case closing != 0:
+			if c == closing {
+				closing = 0
+			}
+		case c == '"' || c == '`':
+			closing = c
+		case c == '[':
+			closing = ']'
+		case c == '.':
+			dot = i
+		}
+	}
+	return dot
+}
+
+// unquoteName removes the quotes around a name quoted as a whole, undoubling
+// the quotes it contains.
+func unquoteName(name string) string {
+	if len(name) < 2 {
+		return name
+	}
+	var closing byte
+	switch name[0] {
+	case '"', '`':
+		closing = name[0]
+	case '[':
+		closing = ']'
+	default:
+		return name
+	}
+	if name[len(name)-1] != closing {
+		return name
+	}
+	return strings.ReplaceAll(name[1:len(name)-1], string(closing)+string(closing), string(closing))
+}
+
+// QualifyTableName joins schema and table, leaving table alone without a
+// schema. A table whose name contains a dot is double-quoted, so that
+// SplitTableName returns it whole.
+func QualifyTableName(schema, table string) string {
+	if strings.Contains(table, ".") {
+		table = `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+	}
+	if schema == "" {
+		return table
+	}
+	return schema + "." + table
+}
+
+// sqliteSchema returns the SQLite database name of schema, main by default.
+func sqliteSchema(schema string) string {
+	if schema == "" {
+		return "main"
+	}
+	return schema
+}
+
+// CurrentSchema returns the schema unqualified table names refer to.
+func CurrentSchema(db *gorm.DB) (string, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return "", err
+	}
+	return d.CurrentSchema(db)
+}
+
+// TableExists reports whether the (possibly schema-qualified) table exists.
+func TableExists(db *gorm.DB, tableName string) (bool, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return false, err
+	}
+	return d.TableExists(db, tableName)
+}
+
+// ListTables returns the tables of the current schema, of schemas, or of
+// every user schema with all set. Tables outside the current schema are
+// schema-qualified. The SQLite lock table is never listed.
+func ListTables(db *gorm.DB, schemas []string, all bool) ([]string, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	current, err := d.CurrentSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 && !all {
+		schemas = []string{current}
+	}
+
+	listed, err := d.ListTables(db, schemas, all)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, 0, len(listed))
+	for _, t := range listed {
+		schema := t.Schema
+		if schema == current {
+			schema = ""
+		}
+		tables = append(tables, QualifyTableName(schema, t.Table))
+	}
+	return tables, nil
+}
+
+// informationSchemaTables lists the base tables of schemas from
+// information_schema, or with all set of every schema matching userSchemas, a
+// condition leaving out the system schemas. Tables must also match
+// tableFilter unless it is empty. The current schema comes first.
+func informationSchemaTables(db *gorm.DB, schemas []string, all bool, current string, userSchemas, tableFilter string) ([]SchemaTable, error) {
+	query := `SELECT table_schema, table_name FROM information_schema.tables WHERE table_type = 'BASE TABLE'`
+	if tableFilter != "" {
+		query += " AND " + tableFilter
+	}
+	var args []any
+	if all {
+		query += " AND " + userSchemas
+	} else {
+		query += " AND table_schema IN ?"
+		args = append(args, schemas)
+	}
+	// The current schema comes first, the others by name
+	query += " ORDER BY table_schema <> ?, table_schema, table_name"
+	args = append(args, current)
+
+	rows, err := db.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []SchemaTable
+	for rows.Next() {
+		var t SchemaTable
+		if err := rows.Scan(&t.Schema, &t.Table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// informationSchemaTableExists reports whether tableName is a base table in
+// information_schema, where currentSchema is the SQL function returning the
+// schema of unqualified names.
+func informationSchemaTableExists(db *gorm.DB, tableName, currentSchema string) (bool, error) {
+	var count int64
+	err := db.Raw(fmt.Sprintf(`SELECT COUNT(*) FROM information_schema.tables
+            WHERE table_schema = COALESCE(NULLIF(?, ''), %s) AND table_name = ? AND table_type = 'BASE TABLE'`, currentSchema),
+		schemaTableArgs(tableName)...).Row().Scan(&count)
+	return count > 0, err
+}
+
+// createNamePattern matches one part of a name, which may be quoted.
+const createNamePattern = "(?:`[^`]+`|\"[^\"]+\"|\\[[^\\]]+\\]|[^\\s(.]+)"
+
+// createStatementPattern matches the head of a CREATE statement up to the
+// possibly qualified name of the table, view, index, trigger or routine it
+// creates. MySQL puts options such as ALGORITHM and SQL SECURITY before the
+// kind of object.
+var createStatementPattern = regexp.MustCompile("(?is)^(\\s*CREATE\\s+(?:[^(;]*?\\s)??(?:TABLE|VIEW|INDEX|TRIGGER|FUNCTION|PROCEDURE)\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?)(" +
+	createNamePattern + "(?:\\." + createNamePattern + ")?)")
+
+// qualifyCreateStatement rewrites the name of the object a CREATE statement
+// creates as schema.name quoted by q, for catalogs that return the statement
+// with a bare name.
+func qualifyCreateStatement(q identifierQuoter, stmt, schema, name string) string {
+	if schema == "" {
+		return stmt
+	}
+	return createStatementPattern.ReplaceAllString(stmt, "${1}"+strings.ReplaceAll(quoteTableName(q, QualifyTableName(schema, name)), "$", "$$"))
+}
@@ -0,0 +1,42 @@
+package database
+
+import "testing"
+
+func TestSplitTableName(t *testing.T) {
+	tests := []struct {
+		name, schema, table string
+	}{
+		{"orders", "", "orders"},
+		{"sales.orders", "sales", "orders"},
+		{"db.dbo.orders", "db.dbo", "orders"},
+		{`"my.table"`, "", "my.table"},
+		{`sales."my.table"`, "sales", "my.table"},
+		{"`sales`.`my.table`", "sales", "my.table"},
+		{"[db].[dbo].[my.table]", "[db].[dbo]", "my.table"},
+		{`"say ""hi"""`, "", `say "hi"`},
+	}
+	for _, tt := range tests {
+		schema, table := SplitTableName(tt.name)
+		if schema != tt.schema || table != tt.table {
+			t.Errorf("SplitTableName(%q) = %q, %q, want %q, %q", tt.name, schema, table, tt.schema, tt.table)
+		}
+	}
+}
+
+func TestQualifyTableNameRoundTrip(t *testing.T) {
+	for _, tt := range []struct{ schema, table, quoted string }{
+		{"", "orders", `"orders"`},
+		{"sales", "orders", `"sales"."orders"`},
+		{"", "my.table", `"my.table"`},
+		{"sales", "my.table", `"sales"."my.table"`},
+		{"db.dbo", "orders", `"db"."dbo"."orders"`},
+	} {
+		name := QualifyTableName(tt.schema, tt.table)
+		if schema, table := SplitTableName(name); schema != tt.schema || table != tt.table {
+			t.Errorf("SplitTableName(%q) = %q, %q, want %q, %q", name, schema, table, tt.schema, tt.table)
+		}
+		if got := quoteTableName(StandardSQL{}, name); got != tt.quoted {
+			t.Errorf("quoteTableName(%q) = %s, want %s", name, got, tt.quoted)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Column is a table column as seen by the schema diff.
+type Column struct {
+	Name     string
+	Type     string
+	Nullable bool
+	// Default is the default expression, nil when the column has none.
+	Default *string
+	// AutoIncrement is set for columns the database numbers itself: serial
+	// and identity columns, AUTO_INCREMENT and SQLite INTEGER PRIMARY KEY.
+	AutoIncrement bool
+	// Enum lists the labels of an enum column, whose Type is then either
+	// an inline ENUM(...) or the name of an enum type.
+	Enum []string
+	// Comment is the comment of the column, "" without one.
+	Comment string
+}
+
+// Index is a secondary index, including the ones backing UNIQUE constraints.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// ForeignKey is a foreign key constraint.
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// Check is a CHECK constraint.
+type Check struct {
+	Name       string
+	Expression string
+}
+
+// Table is the structure of a single table.
+type Table struct {
+	Name string
+	// Comment is the comment of the table, "" without one.
+	Comment     string
+	Columns     []Column
+	PrimaryKey  []string
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+	Checks      []Check
+}
+
+// Schema is a set of tables keyed by name.
+type Schema struct {
+	Tables map[string]*Table
+}
+
+// NewSchema returns an empty schema.
+func NewSchema() *Schema {
+	return &Schema{Tables: make(map[string]*Table)}
+}
+
+// TableNames returns the names of the tables of the schema in sorted order.
+func (s *Schema) TableNames() []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReferenceOrder returns the names of the tables of the schema with every
+// table after the tables its foreign keys reference, in sorted order
+// otherwise. Tables referencing each other, directly or not, keep their
+// sorted order among themselves.
+func (s *Schema) ReferenceOrder() []string {
+	names := s.TableNames()
+	var order []string
+	done := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if done[name] || visiting[name] {
+			return
+		}
+		visiting[name] = true
+		for _, fk := range s.Tables[name].ForeignKeys {
+			for _, other := range names {
+				if other != name && strings.EqualFold(other, fk.RefTable) {
+					visit(other)
+				}
+			}
+		}
+		visiting[name] = false
+		done[name] = true
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return order
+}
+
+// Column returns the column called name, or nil.
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// LoadSchema introspects tables from db.
+func LoadSchema(db *gorm.DB, tables []string) (*Schema, error) {
+	schema := NewSchema()
+	for _, tbl := range tables {
+		table, err := IntrospectTable(db, tbl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to introspect table %s: %w", tbl, err)
+		}
+		schema.Tables[tbl] = table
+	}
+	return schema, nil
+}
+
+// IntrospectTable reads the columns, keys, indexes and constraints of tableName.
+func IntrospectTable(db *gorm.DB, tableName string) (*Table, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.IntrospectTable(db, tableName)
+}
+
+// catalogQueries are the introspection queries of a dialect. Each reads one
+// table, located by the arguments args returns for its name.
+type catalogQueries struct {
+	args func(tableName string) []any
+	// columns selects name, type, nullability, default and whether the
+	// column is auto-incremented, in column order.
+	columns string
+	// primaryKey selects the primary key columns in key order.
+	primaryKey string
+	// indexes selects index name, uniqueness and column of the secondary
+	// indexes, ordered by index and column position.
+	indexes string
+	// foreignKeys selects constraint name, column, referenced table and
+	// referenced column, ordered by constraint and column position.
+	foreignKeys string
+	// checks selects constraint name and expression, when the catalog has them.
+	checks string
+	// enums selects column name and label of the columns typed by a named
+	// enum type, ordered by column and label position, when the database
+	// has such types. Inline enum types are read from the column type.
+	enums string
+	// comments selects column name, "" for the table, and comment of the
+	// table and the columns that have one, table first, when the catalog
+	// has them.
+	comments string
+}
+
+// schemaTableArgs locates tableName by schema, then table, for queries where
+// an empty schema falls back to the current one.
+func schemaTableArgs(tableName string) []any {
+	schema, table := SplitTableName(tableName)
+	return []any{schema, table}
+}
+
+// introspect reads tableName with the queries of q.
+func (q catalogQueries) introspect(db *gorm.DB, tableName string) (*Table, error) {
+	table := &Table{Name: tableName}
+
+	var err error
+	if table.Columns, err = q.introspectColumns(db, tableName); err != nil {
+		return nil, err
+	}
+	if len(table.Columns) == 0 {
+		return nil, fmt.Errorf("no columns found for table %s", tableName)
+	}
+	if table.PrimaryKey, err = q.primaryKeyColumns(db, tableName); err != nil {
+		return nil, err
+	}
+	if table.Indexes, err = q.introspectIndexes(db, tableName); err != nil {
+		return nil, err
+	}
+	if table.ForeignKeys, err = q.introspectForeignKeys(db, tableName); err != nil {
+		return nil, err
+	}
+	if q.checks != "" {
+		if table.Checks, err = q.introspectChecks(db, tableName); err != nil {
+			return nil, err
+		}
+	}
+	if q.enums != "" {
+		if err := q.introspectEnums(db, table); err != nil {
+			return nil, err
+		}
+	}
+	if q.comments != "" {
+		comments, err := q.introspectComments(db, tableName)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range comments {
+			if c.column == "" {
+				table.Comment = c.text
+			} else if col := table.Column(c.column); col != nil {
+				col.Comment = c.text
+			}
+		}
+	}
+	return table, nil
+}
+
+func (q catalogQueries) primaryKeyColumns(db *gorm.DB, tableName string) ([]string, error) {
+	var keys []string
+	if err := db.Raw(q.primaryKey, q.args(tableName)...).Scan(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (q catalogQueries) introspectColumns(db *gorm.DB, tableName string) ([]Column, error) {
+	rows, err := db.Raw(q.columns, q.args(tableName)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var col Column
+		if err := rows.Scan(&col.Name, &col.Type, &col.Nullable, &col.Default, &col.AutoIncrement); err != nil {
+			return nil, err
+		}
+		col.Enum = inlineEnumLabels(col.Type)
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// scanGroupedColumns collects rows of (name, flag, column) into per-name
+// column lists, preserving the order in which names first appear.
+func scanGroupedColumns(db *gorm.DB, query string, args ...any) ([]string, map[string]bool, map[string][]string, error) {
+	rows, err := db.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	flags := make(map[string]bool)
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var name, column string
+		var flag bool
+		if err := rows.Scan(&name, &flag, &column); err != nil {
+			return nil, nil, nil, err
+		}
+		if _, ok := columns[name]; !ok {
+			names = append(names, name)
+		}
+		flags[name] = flag
+		columns[name] = append(columns[name], column)
+	}
+	return names, flags, columns, rows.Err()
+}
+
+func (q catalogQueries) introspectIndexes(db *gorm.DB, tableName string) ([]Index, error) {
+	names, unique, columns, err := scanGroupedColumns(db, q.indexes, q.args(tableName)...)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []Index
+	for _, name := range names {
+		indexes = append(indexes, Index{Name: name, Columns: columns[name], Unique: unique[name]})
+	}
+	return indexes, nil
+}
+
+func (q catalogQueries) introspectForeignKeys(db *gorm.DB, tableName string) ([]ForeignKey, error) {
+	rows, err := db.Raw(q.foreignKeys, q.args(tableName)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []ForeignKey
+	for rows.Next() {
+		var name, column, refTable string
+		var refColumn *string
+		if err := rows.Scan(&name, &column, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 || keys[len(keys)-1].Name != name {
+			keys = append(keys, ForeignKey{Name: name, RefTable: refTable})
+		}
+		fk := &keys[len(keys)-1]
+		fk.Columns = append(fk.Columns, column)
+		if refColumn != nil {
+			fk.RefColumns = append(fk.RefColumns, *refColumn)
+		}
+	}
+	return keys, rows.Err()
+}
+
+func (q catalogQueries) introspectChecks(db *gorm.DB, tableName string) ([]Check, error) {
+	rows, err := db.Raw(q.checks, q.args(tableName)...).Rows()
+	if err != nil {
+		// CHECK constraints are not reported by MySQL before 8.0.16
+		return nil, nil
+	}
+	defer rows.Close()
+
+	var checks []Check
+	for rows.Next() {
+		var check Check
+		if err := rows.Scan(&check.Name, &check.Expression); err != nil {
+			return nil, err
+		}
+		check.Expression = strings.TrimSpace(check.Expression)
+		if upper := strings.ToUpper(check.Expression); strings.HasPrefix(upper, "CHECK") {
+			check.Expression = strings.TrimSpace(check.Expression[len("CHECK"):])
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
+
+// introspectEnums records the labels of the columns of table typed by a
+// named enum type.
+func (q catalogQueries) introspectEnums(db *gorm.DB, table *Table) error {
+	rows, err := db.Raw(q.enums, q.args(table.Name)...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, label string
+		if err := rows.Scan(&name, &label); err != nil {
+			return err
+		}
+		if c := table.Column(name); c != nil {
+			c.Enum = append(c.Enum, label)
+		}
+	}
+	return rows.Err()
+}
+
+// comment is the comment of a table, or of one of its columns.
+type comment struct {
+	column, text string
+}
+
+// introspectComments reads the comments of tableName and its columns.
+func (q catalogQueries) introspectComments(db *gorm.DB, tableName string) ([]comment, error) {
+	rows, err := db.Raw(q.comments, q.args(tableName)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []comment
+	for rows.Next() {
+		var c comment
+		if err := rows.Scan(&c.column, &c.text); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// tableComments returns the comments of table and its columns, table first.
+func tableComments(table *Table) []comment {
+	var comments []comment
+	if table.Comment != "" {
+		comments = append(comments, comment{text: table.Comment})
+	}
+	for _, c := range table.Columns {
+		if c.Comment != "" {
+			comments = append(comments, comment{column: c.Name, text: c.Comment})
+		}
+	}
+	return comments
+}
+
+// commentStatements renders comments on tableName with CommentSQL.
+func commentStatements(d Dialect, tableName string, comments []comment) []string {
+	var stmts []string
+	for _, c := range comments {
+		stmts = append(stmts, d.CommentSQL(tableName, c.column, c.text))
+	}
+	return stmts
+}
@@ -0,0 +1,529 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ColumnChange is a column present on both sides with a different definition.
+type ColumnChange struct {
+	Source  Column
+	Target  Column
+	Changes []string
+}
+
+// TableDiff lists the differences of a table present on both sides. "Added"
+// means present in the source and missing from the target.
+type TableDiff struct {
+	Name               string
+	Source             *Table
+	Target             *Table
+	AddedColumns       []Column
+	RemovedColumns     []Column
+	ChangedColumns     []ColumnChange
+	PrimaryKeyChanged  bool
+	AddedIndexes       []Index
+	RemovedIndexes     []Index
+	AddedForeignKeys   []ForeignKey
+	RemovedForeignKeys []ForeignKey
+	AddedChecks        []Check
+	RemovedChecks      []Check
+}
+
+func (d *TableDiff) empty() bool {
+	return len(d.AddedColumns) == 0 && len(d.RemovedColumns) == 0 && len(d.ChangedColumns) == 0 &&
+		!d.PrimaryKeyChanged && len(d.AddedIndexes) == 0 && len(d.RemovedIndexes) == 0 &&
+		len(d.AddedForeignKeys) == 0 && len(d.RemovedForeignKeys) == 0 &&
+		len(d.AddedChecks) == 0 && len(d.RemovedChecks) == 0
+}
+
+// SchemaDiff describes what has to change in a target schema to match a source.
+type SchemaDiff struct {
+	AddedTables   []*Table
+	RemovedTables []*Table
+	ChangedTables []*TableDiff
+}
+
+// Empty reports whether both schemas are equivalent.
+func (d *SchemaDiff) Empty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 && len(d.ChangedTables) == 0
+}
+
+// DiffSchemas compares target against source.
+func DiffSchemas(source, target *Schema) *SchemaDiff {
+	diff := &SchemaDiff{}
+	for _, name := range source.TableNames() {
+		src := source.Tables[name]
+		dst, ok := target.Tables[name]
+		if !ok {
+			diff.AddedTables = append(diff.AddedTables, src)
+			continue
+		}
+		if td := DiffTables(src, dst); !td.empty() {
+			diff.ChangedTables = append(diff.ChangedTables, td)
+		}
+	}
+	for _, name := range target.TableNames() {
+		if _, ok := source.Tables[name]; !ok {
+			diff.RemovedTables = append(diff.RemovedTables, target.Tables[name])
+		}
+	}
+	return diff
+}
+
+// DiffTables compares the target definition of a table against the source one.
+func DiffTables(source, target *Table) *TableDiff {
+	d := &TableDiff{Name: source.Name, Source: source, Target: target}
+
+	for _, col := range source.Columns {
+		other := target.Column(col.Name)
+		if other == nil {
+			d.AddedColumns = append(d.AddedColumns, col)
+			continue
+		}
+		if changes := compareColumns(keyColumn(source, col), keyColumn(target, *other)); len(changes) > 0 {
+			d.ChangedColumns = append(d.ChangedColumns, ColumnChange{Source: col, Target: *other, Changes: changes})
+		}
+	}
+	for _, col := range target.Columns {
+		if source.Column(col.Name) == nil {
+			d.RemovedColumns = append(d.RemovedColumns, col)
+		}
+	}
+
+	d.PrimaryKeyChanged = !sameNames(source.PrimaryKey, target.PrimaryKey)
+
+	for _, idx := range source.Indexes {
+		if findIndex(target.Indexes, idx) == nil {
+			d.AddedIndexes = append(d.AddedIndexes, idx)
+		}
+	}
+	for _, idx := range target.Indexes {
+		if findIndex(source.Indexes, idx) == nil {
+			d.RemovedIndexes = append(d.RemovedIndexes, idx)
+		}
+	}
+
+	for _, fk := range source.ForeignKeys {
+		other := findForeignKey(target.ForeignKeys, fk)
+		if other == nil {
+			d.AddedForeignKeys = append(d.AddedForeignKeys, fk)
+		}
+	}
+	for _, fk := range target.ForeignKeys {
+		if findForeignKey(source.ForeignKeys, fk) == nil {
+			d.RemovedForeignKeys = append(d.RemovedForeignKeys, fk)
+		}
+	}
+
+	for _, check := range source.Checks {
+		if !hasCheck(target.Checks, check) {
+			d.AddedChecks = append(d.AddedChecks, check)
+		}
+	}
+	for _, check := range target.Checks {
+		if !hasCheck(source.Checks, check) {
+			d.RemovedChecks = append(d.RemovedChecks, check)
+		}
+	}
+	return d
+}
+
+// keyColumn returns c as NOT NULL when it is part of the primary key of
+// table, which every database but SQLite implies, so that a key declared
+// without NOT NULL compares equal to the introspected one.
+func keyColumn(table *Table, c Column) Column {
+	for _, key := range table.PrimaryKey {
+		if strings.EqualFold(key, c.Name) {
+			c.Nullable = false
+		}
+	}
+	return c
+}
+
+func compareColumns(source, target Column) []string {
+	var changes []string
+	if NormalizeType(source.Type) != NormalizeType(target.Type) {
+		changes = append(changes, fmt.Sprintf("type %s -> %s", target.Type, source.Type))
+	}
+	if source.Nullable != target.Nullable {
+		changes = append(changes, fmt.Sprintf("nullable %s -> %s", yesNo(target.Nullable), yesNo(source.Nullable)))
+	}
+	if normalizeDefault(source.Default) != normalizeDefault(target.Default) {
+		changes = append(changes, fmt.Sprintf("default %s -> %s", describeDefault(target.Default), describeDefault(source.Default)))
+	}
+	return changes
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func describeDefault(def *string) string {
+	if def == nil {
+		return "none"
+	}
+	return *def
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// findIndex matches indexes by definition rather than by name, as indexes
+// backing UNIQUE constraints are named differently by each database.
+func findIndex(indexes []Index, idx Index) *Index {
+	for i := range indexes {
+		if indexes[i].Unique == idx.Unique && sameNames(indexes[i].Columns, idx.Columns) {
+			return &indexes[i]
+		}
+	}
+	return nil
+}
+
+// findForeignKey matches foreign keys by definition, as their names are often
+// generated differently by each database.
+func findForeignKey(keys []ForeignKey, fk ForeignKey) *ForeignKey {
+	for i := range keys {
+		if sameNames(keys[i].Columns, fk.Columns) && strings.EqualFold(keys[i].RefTable, fk.RefTable) &&
+			(len(keys[i].RefColumns) == 0 || len(fk.RefColumns) == 0 || sameNames(keys[i].RefColumns, fk.RefColumns)) {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+func hasCheck(checks []Check, check Check) bool {
+	for _, c := range checks {
+		if normalizeExpression(c.Expression) == normalizeExpression(check.Expression) {
+			return true
+		}
+	}
+	return false
+}
+
+var typeAliases = map[string]string{
+	"int": "integer", "int4": "integer", "serial": "integer", "serial4": "integer",
+	"int8": "bigint", "bigserial": "bigint", "serial8": "bigint",
+	"int2": "smallint", "smallserial": "smallint",
+	"bool":   "boolean",
+	"float8": "double precision", "double": "double precision", "float4": "real",
+	"decimal":           "numeric",
+	"character varying": "varchar", "character": "char",
+	"timestamp without time zone": "timestamp", "timestamp with time zone": "timestamptz",
+	"time without time zone": "time", "time with time zone": "timetz",
+}
+
+var intDisplayWidth = regexp.MustCompile(`^((?:tiny|small|medium|big)?int(?:eger)?)\(\d+\)`)
+
+// NormalizeType maps equivalent spellings of a column type to one form, so
+// that "int4", "int(11)" and "INTEGER" compare equal.
+func NormalizeType(t string) string {
+	t = strings.Join(strings.Fields(strings.ToLower(t)), " ")
+	t = strings.ReplaceAll(t, " [", "[")
+	if !strings.HasPrefix(t, "tinyint(1)") {
+		t = intDisplayWidth.ReplaceAllString(t, "$1")
+	}
+
+	base, params := t, ""
+	if i := strings.Index(t, "("); i >= 0 {
+		base, params = strings.TrimSpace(t[:i]), strings.ReplaceAll(t[i:], " ", "")
+	}
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	return base + params
+}
+
+var castPattern = regexp.MustCompile(`::[a-z_ ]+(\[\])?`)
+
+// normalizeExpression strips casts, redundant outer parentheses, letter case
+// outside of quotes, identifier quotes and whitespace from a default or CHECK
+// expression.
+func normalizeExpression(expr string) string {
+	expr = castPattern.ReplaceAllString(strings.TrimSpace(expr), "")
+
+	var b strings.Builder
+	inQuote := false
+	for _, c := range expr {
+		switch {
+		case c == '\'':
+			inQuote = !inQuote
+			b.WriteRune(c)
+		case inQuote:
+			b.WriteRune(c)
+		case c == ' ' || c == '\t' || c == '\n', c == '"' || c == '`':
+		default:
+			b.WriteString(strings.ToLower(string(c)))
+		}
+	}
+	expr = b.String()
+
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		tokens, err := tokenizeDDL(expr)
+		if err != nil || matchingParen(tokens, 0) != len(tokens)-1 {
+			break
+		}
+		expr = expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+func normalizeDefault(def *string) string {
+	if def == nil || strings.EqualFold(strings.TrimSpace(*def), "NULL") {
+		return ""
+	}
+	return normalizeExpression(*def)
+}
+
+// WriteReport prints the differences in a human readable form.
+func (d *SchemaDiff) WriteReport(w io.Writer) {
+	for _, t := range d.AddedTables {
+		fmt.Fprintf(w, "+ table %s (%d column(s))\n", t.Name, len(t.Columns))
+	}
+	for _, t := range d.RemovedTables {
+		fmt.Fprintf(w, "- table %s\n", t.Name)
+	}
+	for _, td := range d.ChangedTables {
+		fmt.Fprintf(w, "~ table %s\n", td.Name)
+		for _, c := range td.AddedColumns {
+			fmt.Fprintf(w, "    + column %s %s\n", c.Name, columnSpec(c))
+		}
+		for _, c := range td.RemovedColumns {
+			fmt.Fprintf(w, "    - column %s\n", c.Name)
+		}
+		for _, c := range td.ChangedColumns {
+			fmt.Fprintf(w, "    ~ column %s: %s\n", c.Source.Name, strings.Join(c.Changes, "; "))
+		}
+		if td.PrimaryKeyChanged {
+			fmt.Fprintf(w, "    ~ primary key (%s) -> (%s)\n", strings.Join(td.Target.PrimaryKey, ", "), strings.Join(td.Source.PrimaryKey, ", "))
+		}
+		for _, idx := range td.RemovedIndexes {
+			fmt.Fprintf(w, "    - index %s\n", describeIndex(idx))
+		}
+		for _, idx := range td.AddedIndexes {
+			fmt.Fprintf(w, "    + index %s\n", describeIndex(idx))
+		}
+		for _, fk := range td.RemovedForeignKeys {
+			fmt.Fprintf(w, "    - foreign key %s\n", describeForeignKey(fk))
+		}
+		for _, fk := range td.AddedForeignKeys {
+			fmt.Fprintf(w, "    + foreign key %s\n", describeForeignKey(fk))
+		}
+		for _, c := range td.RemovedChecks {
+			fmt.Fprintf(w, "    - check %s\n", c.Expression)
+		}
+		for _, c := range td.AddedChecks {
+			fmt.Fprintf(w, "    + check %s\n", c.Expression)
+		}
+	}
+}
+
+func describeIndex(idx Index) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("%s %s(%s)", idx.Name, unique, strings.Join(idx.Columns, ", "))
+}
+
+func describeForeignKey(fk ForeignKey) string {
+	return fmt.Sprintf("%s (%s) REFERENCES %s (%s)", fk.Name, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+}
+
+// columnDefinition renders a column as it appears in CREATE TABLE.
+func columnDefinition(q identifierQuoter, c Column) string {
+	return q.QuoteIdentifier(c.Name) + " " + columnSpec(c)
+}
+
+// columnSpec renders the type, nullability and default of a column.
+func columnSpec(c Column) string {
+	def := c.Type
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.Default != nil {
+		def += " DEFAULT " + *c.Default
+	}
+	return def
+}
+
+func foreignKeyDefinition(q identifierQuoter, fk ForeignKey) string {
+	def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", quoteNames(q, fk.Columns), quoteTableName(q, fk.RefTable))
+	if len(fk.RefColumns) > 0 {
+		def += fmt.Sprintf(" (%s)", quoteNames(q, fk.RefColumns))
+	}
+	return def
+}
+
+// CreateTableSQL renders a CREATE TABLE statement for table, followed by its
+// indexes. Foreign keys are only inlined for dialects that cannot add them
+// later; the others get them from AddForeignKeySQL once all tables exist.
+// Output-only dialects translate the column types, and enum columns are
+// typed for dialect (see translateEnum). Comments follow as CommentSQL
+// statements, unless the dialect declares them in CREATE TABLE.
+func CreateTableSQL(table *Table, dialect Dialect) []string {
+	table = translateEnums(dialect, table)
+	if r, ok := dialect.(tableRenderer); ok {
+		return r.CreateTableSQL(table)
+	}
+	commenter, inline := dialect.(inlineCommenter)
+	var defs []string
+	for _, c := range table.Columns {
+		def := columnDefinition(dialect, c)
+		if inline && c.Comment != "" {
+			def = commenter.ColumnComment(def, c.Comment)
+		}
+		defs = append(defs, def)
+	}
+	if len(table.PrimaryKey) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", quoteNames(dialect, table.PrimaryKey)))
+	}
+	for _, c := range table.Checks {
+		defs = append(defs, checkDefinition(dialect, c))
+	}
+	if dialect.ForeignKeysInCreateTable() {
+		for _, fk := range table.ForeignKeys {
+			defs = append(defs, foreignKeyDefinition(dialect, fk))
+		}
+	}
+
+	create := fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", quoteTableName(dialect, table.Name), strings.Join(defs, ",\n    "))
+	if inline && table.Comment != "" {
+		create = commenter.TableComment(create, table.Comment)
+	}
+	stmts := []string{create + ";"}
+	for _, idx := range table.Indexes {
+		stmts = append(stmts, dialect.CreateIndexSQL(table.Name, idx))
+	}
+	if !inline {
+		stmts = append(stmts, commentStatements(dialect, table.Name, tableComments(table))...)
+	}
+	return stmts
+}
+
+// inlineCommenter is implemented by dialects that declare comments in
+// CREATE TABLE rather than with CommentSQL.
+type inlineCommenter interface {
+	// ColumnComment returns column definition def declaring comment.
+	ColumnComment(def, comment string) string
+	// TableComment returns CREATE TABLE statement stmt, without its
+	// terminator, declaring comment.
+	TableComment(stmt, comment string) string
+}
+
+func checkDefinition(q identifierQuoter, c Check) string {
+	expr := requoteIdentifiers(q, c.Expression)
+	if !strings.HasPrefix(expr, "(") {
+		expr = "(" + expr + ")"
+	}
+	if c.Name != "" {
+		return fmt.Sprintf("CONSTRAINT %s CHECK %s", q.QuoteIdentifier(c.Name), expr)
+	}
+	return "CHECK " + expr
+}
+
+// AlterSQL renders the statements that bring the target in line with the
+// source in dialect. Changes a dialect cannot express, such as altering a
+// column in SQLite, are emitted as comments.
+func (d *SchemaDiff) AlterSQL(dialect Dialect) []string {
+	var stmts []string
+
+	// Drop constraints and indexes first so columns can change freely
+	for _, td := range d.ChangedTables {
+		for _, fk := range td.RemovedForeignKeys {
+			stmts = append(stmts, dialect.DropForeignKeySQL(td.Name, fk))
+		}
+		for _, c := range td.RemovedChecks {
+			stmts = append(stmts, dialect.DropCheckSQL(td.Name, c))
+		}
+		for _, idx := range td.RemovedIndexes {
+			stmts = append(stmts, dialect.DropIndexSQL(td.Name, idx))
+		}
+	}
+
+	// New tables after the new tables they reference, as some databases
+	// check the references of CREATE TABLE
+	added := referenceOrdered(d.AddedTables)
+	for _, t := range added {
+		stmts = append(stmts, CreateTableSQL(t, dialect)...)
+	}
+
+	for _, td := range d.ChangedTables {
+		for _, c := range td.AddedColumns {
+			c, check := translateEnum(dialect, c)
+			if adder, ok := dialect.(columnAdder); ok {
+				stmts = append(stmts, adder.AddColumnSQL(td.Name, c)...)
+			} else {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", quoteTableName(dialect, td.Name), columnDefinition(dialect, c)))
+			}
+			if check != nil {
+				stmts = append(stmts, dialect.AddCheckSQL(td.Name, *check))
+			}
+		}
+		for _, c := range td.ChangedColumns {
+			c.Source, _ = translateEnum(dialect, c.Source)
+			stmts = append(stmts, dialect.AlterColumnSQL(td.Name, c)...)
+		}
+		for _, c := range td.RemovedColumns {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", quoteTableName(dialect, td.Name), dialect.QuoteIdentifier(c.Name)))
+		}
+		if td.PrimaryKeyChanged {
+			stmts = append(stmts, dialect.AlterPrimaryKeySQL(td.Name, td.Target.PrimaryKey, td.Source.PrimaryKey)...)
+		}
+		for _, idx := range td.AddedIndexes {
+			stmts = append(stmts, dialect.CreateIndexSQL(td.Name, idx))
+		}
+		for _, c := range td.AddedChecks {
+			stmts = append(stmts, dialect.AddCheckSQL(td.Name, c))
+		}
+	}
+
+	// Foreign keys last, once every referenced table exists
+	if !dialect.ForeignKeysInCreateTable() {
+		for _, t := range added {
+			for _, fk := range t.ForeignKeys {
+				stmts = append(stmts, dialect.AddForeignKeySQL(t.Name, fk))
+			}
+		}
+	}
+	for _, td := range d.ChangedTables {
+		for _, fk := range td.AddedForeignKeys {
+			stmts = append(stmts, dialect.AddForeignKeySQL(td.Name, fk))
+		}
+	}
+
+	// Removed tables before the removed tables they reference
+	removed := referenceOrdered(d.RemovedTables)
+	for i := len(removed) - 1; i >= 0; i-- {
+		stmts = append(stmts, fmt.Sprintf("DROP TABLE %s;", quoteTableName(dialect, removed[i].Name)))
+	}
+	return stmts
+}
+
+// referenceOrdered returns tables in Schema.ReferenceOrder, each after the
+// tables among them its foreign keys reference.
+func referenceOrdered(tables []*Table) []*Table {
+	schema := NewSchema()
+	for _, t := range tables {
+		schema.Tables[t.Name] = t
+	}
+	ordered := make([]*Table, 0, len(tables))
+	for _, name := range schema.ReferenceOrder() {
+		ordered = append(ordered, schema.Tables[name])
+	}
+	return ordered
+}
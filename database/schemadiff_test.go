@@ -0,0 +1,295 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeType(t *testing.T) {
+	tests := []struct {
+		types []string
+		want  string
+	}{
+		{[]string{"int4", "int(11)", "INTEGER", "integer"}, "integer"},
+		{[]string{"character varying(20)", "VARCHAR(20)", "varchar( 20 )"}, "varchar(20)"},
+		{[]string{"text []", "TEXT[]"}, "text[]"},
+		{[]string{"tinyint(1)", "TINYINT(1)"}, "tinyint(1)"},
+		{[]string{"decimal(10, 2)", "DECIMAL(10,2)", "numeric(10,2)"}, "numeric(10,2)"},
+		{[]string{"timestamp without time zone", "TIMESTAMP"}, "timestamp"},
+		{[]string{"timestamp with time zone", "timestamptz"}, "timestamptz"},
+		{[]string{"bigint(20) unsigned"}, "bigint unsigned"},
+	}
+	for _, tt := range tests {
+		for _, typ := range tt.types {
+			if got := NormalizeType(typ); got != tt.want {
+				t.Errorf("NormalizeType(%q) = %q, want %q", typ, got, tt.want)
+			}
+		}
+	}
+}
+
+// diffFixture returns a source schema and an older target it is diffed
+// against.
+func diffFixture() (source, target *Schema) {
+	source, target = NewSchema(), NewSchema()
+	source.Tables["customers"] = &Table{
+		Name:       "customers",
+		Columns:    []Column{{Name: "id", Type: "INTEGER"}, {Name: "email", Type: "VARCHAR(255)"}},
+		PrimaryKey: []string{"id"},
+	}
+	target.Tables["customers"] = &Table{
+		Name:       "customers",
+		Columns:    []Column{{Name: "id", Type: "int4"}, {Name: "email", Type: "character varying(100)", Nullable: true}},
+		PrimaryKey: []string{"id"},
+	}
+	source.Tables["orders"] = &Table{
+		Name: "orders",
+		Columns: []Column{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "customer_id", Type: "INTEGER"},
+			{Name: "total", Type: "DECIMAL(10,2)", Default: stringPtr("0")},
+			{Name: "note", Type: "TEXT", Nullable: true},
+		},
+		PrimaryKey:  []string{"id"},
+		Indexes:     []Index{{Name: "idx_orders_customer", Columns: []string{"customer_id"}}},
+		ForeignKeys: []ForeignKey{{Name: "orders_customer_id_fkey", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}}},
+		Checks:      []Check{{Name: "orders_total_check", Expression: "total >= 0"}},
+	}
+	target.Tables["orders"] = &Table{
+		Name: "orders",
+		Columns: []Column{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "customer_id", Type: "INTEGER"},
+			{Name: "total", Type: "DECIMAL(8,2)", Nullable: true},
+			{Name: "legacy", Type: "TEXT", Nullable: true},
+		},
+		PrimaryKey:  []string{"id"},
+		Indexes:     []Index{{Name: "idx_orders_legacy", Columns: []string{"legacy"}}},
+		ForeignKeys: []ForeignKey{{Name: "orders_old_fkey", Columns: []string{"customer_id"}, RefTable: "old_customers", RefColumns: []string{"id"}}},
+		Checks:      []Check{{Name: "orders_legacy_check", Expression: "legacy <> ''"}},
+	}
+	source.Tables["items"] = &Table{
+		Name:        "items",
+		Columns:     []Column{{Name: "id", Type: "INTEGER"}, {Name: "order_id", Type: "INTEGER"}},
+		PrimaryKey:  []string{"id"},
+		ForeignKeys: []ForeignKey{{Name: "items_order_id_fkey", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}}},
+	}
+	target.Tables["old_customers"] = &Table{
+		Name:       "old_customers",
+		Columns:    []Column{{Name: "id", Type: "INTEGER"}},
+		PrimaryKey: []string{"id"},
+	}
+	return source, target
+}
+
+func TestDiffSchemas(t *testing.T) {
+	source, target := diffFixture()
+	if diff := DiffSchemas(source, source); !diff.Empty() {
+		t.Errorf("schema differs from itself: %+v", diff)
+	}
+
+	diff := DiffSchemas(source, target)
+	if len(diff.AddedTables) != 1 || diff.AddedTables[0].Name != "items" {
+		t.Errorf("added tables = %+v, want items", diff.AddedTables)
+	}
+	if len(diff.RemovedTables) != 1 || diff.RemovedTables[0].Name != "old_customers" {
+		t.Errorf("removed tables = %+v, want old_customers", diff.RemovedTables)
+	}
+	if len(diff.ChangedTables) != 2 {
+		t.Fatalf("changed tables = %+v, want customers and orders", diff.ChangedTables)
+	}
+
+	customers := diff.ChangedTables[0]
+	if customers.Name != "customers" || len(customers.ChangedColumns) != 1 || customers.ChangedColumns[0].Source.Name != "email" {
+		t.Errorf("customers diff = %+v, want only email changed", customers)
+	} else if want := []string{"type character varying(100) -> VARCHAR(255)", "nullable yes -> no"}; !reflect.DeepEqual(customers.ChangedColumns[0].Changes, want) {
+		t.Errorf("email changes = %q, want %q", customers.ChangedColumns[0].Changes, want)
+	}
+
+	orders := diff.ChangedTables[1]
+	var changed []string
+	for _, c := range orders.ChangedColumns {
+		changed = append(changed, c.Source.Name)
+	}
+	got := []string{
+		orders.Name, columnNames(orders.AddedColumns), columnNames(orders.RemovedColumns), strings.Join(changed, ","),
+		orders.AddedIndexes[0].Name, orders.RemovedIndexes[0].Name,
+		orders.AddedForeignKeys[0].Name, orders.RemovedForeignKeys[0].Name,
+		orders.AddedChecks[0].Name, orders.RemovedChecks[0].Name,
+	}
+	want := []string{
+		"orders", "note", "legacy", "total",
+		"idx_orders_customer", "idx_orders_legacy",
+		"orders_customer_id_fkey", "orders_old_fkey",
+		"orders_total_check", "orders_legacy_check",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orders diff = %q, want %q", got, want)
+	}
+	if orders.PrimaryKeyChanged {
+		t.Error("orders primary key reported as changed")
+	}
+}
+
+func columnNames(columns []Column) string {
+	var names []string
+	for _, c := range columns {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// TestAlterSQL renders one diff in every dialect: constraints and indexes
+// are dropped first, new tables are created before foreign keys reference
+// them, and removed tables are dropped last.
+func TestAlterSQL(t *testing.T) {
+	source, target := diffFixture()
+	diff := DiffSchemas(source, target)
+	tests := map[string][]string{
+		"postgres": {
+			`ALTER TABLE "orders" DROP CONSTRAINT "orders_old_fkey";`,
+			`ALTER TABLE "orders" DROP CONSTRAINT "orders_legacy_check";`,
+			`DROP INDEX "idx_orders_legacy";`,
+			"CREATE TABLE \"items\" (\n    \"id\" INTEGER NOT NULL,\n    \"order_id\" INTEGER NOT NULL,\n    PRIMARY KEY (\"id\")\n);",
+			`ALTER TABLE "customers" ALTER COLUMN "email" TYPE VARCHAR(255) USING "email"::VARCHAR(255);`,
+			`ALTER TABLE "customers" ALTER COLUMN "email" SET NOT NULL;`,
+			`ALTER TABLE "orders" ADD COLUMN "note" TEXT;`,
+			`ALTER TABLE "orders" ALTER COLUMN "total" TYPE DECIMAL(10,2) USING "total"::DECIMAL(10,2);`,
+			`ALTER TABLE "orders" ALTER COLUMN "total" SET NOT NULL;`,
+			`ALTER TABLE "orders" ALTER COLUMN "total" SET DEFAULT 0;`,
+			`ALTER TABLE "orders" DROP COLUMN "legacy";`,
+			`CREATE INDEX "idx_orders_customer" ON "orders" ("customer_id");`,
+			`ALTER TABLE "orders" ADD CONSTRAINT "orders_total_check" CHECK (total >= 0);`,
+			`ALTER TABLE "items" ADD CONSTRAINT "items_order_id_fkey" FOREIGN KEY ("order_id") REFERENCES "orders" ("id");`,
+			`ALTER TABLE "orders" ADD CONSTRAINT "orders_customer_id_fkey" FOREIGN KEY ("customer_id") REFERENCES "customers" ("id");`,
+			`DROP TABLE "old_customers";`,
+		},
+		"mysql": {
+			"ALTER TABLE `orders` DROP FOREIGN KEY `orders_old_fkey`;",
+			"ALTER TABLE `orders` DROP CHECK `orders_legacy_check`;",
+			"DROP INDEX `idx_orders_legacy` ON `orders`;",
+			"CREATE TABLE `items` (\n    `id` INTEGER NOT NULL,\n    `order_id` INTEGER NOT NULL,\n    PRIMARY KEY (`id`)\n);",
+			"ALTER TABLE `customers` MODIFY COLUMN `email` VARCHAR(255) NOT NULL;",
+			"ALTER TABLE `orders` ADD COLUMN `note` TEXT;",
+			"ALTER TABLE `orders` MODIFY COLUMN `total` DECIMAL(10,2) NOT NULL DEFAULT 0;",
+			"ALTER TABLE `orders` DROP COLUMN `legacy`;",
+			"CREATE INDEX `idx_orders_customer` ON `orders` (`customer_id`);",
+			"ALTER TABLE `orders` ADD CONSTRAINT `orders_total_check` CHECK (total >= 0);",
+			"ALTER TABLE `items` ADD CONSTRAINT `items_order_id_fkey` FOREIGN KEY (`order_id`) REFERENCES `orders` (`id`);",
+			"ALTER TABLE `orders` ADD CONSTRAINT `orders_customer_id_fkey` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`);",
+			"DROP TABLE `old_customers`;",
+		},
+		"sqlite": {
+			"-- SQLite cannot drop foreign key orders_old_fkey on table orders; rebuild the table",
+			"-- SQLite cannot drop check legacy <> '' on table orders; rebuild the table",
+			`DROP INDEX "idx_orders_legacy";`,
+			"CREATE TABLE \"items\" (\n    \"id\" INTEGER NOT NULL,\n    \"order_id\" INTEGER NOT NULL,\n    PRIMARY KEY (\"id\"),\n    FOREIGN KEY (\"order_id\") REFERENCES \"orders\" (\"id\")\n);",
+			"-- SQLite cannot alter column email on table customers (type character varying(100) -> VARCHAR(255); nullable yes -> no); rebuild the table",
+			`ALTER TABLE "orders" ADD COLUMN "note" TEXT;`,
+			"-- SQLite cannot alter column total on table orders (type DECIMAL(8,2) -> DECIMAL(10,2); nullable yes -> no; default none -> 0); rebuild the table",
+			`ALTER TABLE "orders" DROP COLUMN "legacy";`,
+			`CREATE INDEX "idx_orders_customer" ON "orders" ("customer_id");`,
+			"-- SQLite cannot add check total >= 0 on table orders; rebuild the table",
+			"-- SQLite cannot add foreign key orders_customer_id_fkey to existing table orders; rebuild the table",
+			`DROP TABLE "old_customers";`,
+		},
+		"duckdb": {
+			"-- DuckDB cannot drop foreign key orders_old_fkey on table orders; rebuild the table",
+			"-- DuckDB cannot drop check legacy <> '' on table orders; rebuild the table",
+			`DROP INDEX "idx_orders_legacy";`,
+			"CREATE TABLE \"items\" (\n    \"id\" INTEGER NOT NULL,\n    \"order_id\" INTEGER NOT NULL,\n    PRIMARY KEY (\"id\"),\n    FOREIGN KEY (\"order_id\") REFERENCES \"orders\" (\"id\")\n);",
+			`ALTER TABLE "customers" ALTER COLUMN "email" TYPE VARCHAR(255) USING "email"::VARCHAR(255);`,
+			`ALTER TABLE "customers" ALTER COLUMN "email" SET NOT NULL;`,
+			`ALTER TABLE "orders" ADD COLUMN "note" TEXT;`,
+			`ALTER TABLE "orders" ALTER COLUMN "total" TYPE DECIMAL(10,2) USING "total"::DECIMAL(10,2);`,
+			`ALTER TABLE "orders" ALTER COLUMN "total" SET NOT NULL;`,
+			`ALTER TABLE "orders" ALTER COLUMN "total" SET DEFAULT 0;`,
+			`ALTER TABLE "orders" DROP COLUMN "legacy";`,
+			`CREATE INDEX "idx_orders_customer" ON "orders" ("customer_id");`,
+			"-- DuckDB cannot add check total >= 0 on table orders; rebuild the table",
+			"-- DuckDB cannot add foreign key orders_customer_id_fkey to existing table orders; rebuild the table",
+			`DROP TABLE "old_customers";`,
+		},
+		"mssql": {
+			"ALTER TABLE [orders] DROP CONSTRAINT [orders_old_fkey];",
+			"ALTER TABLE [orders] DROP CONSTRAINT [orders_legacy_check];",
+			"DROP INDEX [idx_orders_legacy] ON [orders];",
+			"CREATE TABLE [items] (\n    [id] int NOT NULL,\n    [order_id] int NOT NULL,\n    PRIMARY KEY ([id])\n);",
+			"ALTER TABLE [customers] ALTER COLUMN [email] nvarchar(255) NOT NULL;",
+			"ALTER TABLE [orders] ADD [note] nvarchar(max) NULL;",
+			"ALTER TABLE [orders] ALTER COLUMN [total] decimal(10, 2) NOT NULL;",
+			"ALTER TABLE [orders] DROP COLUMN [legacy];",
+			"CREATE INDEX [idx_orders_customer] ON [orders] ([customer_id]);",
+			"ALTER TABLE [orders] ADD CONSTRAINT [orders_total_check] CHECK (total >= 0);",
+			"ALTER TABLE [items] ADD CONSTRAINT [items_order_id_fkey] FOREIGN KEY ([order_id]) REFERENCES [orders] ([id]);",
+			"ALTER TABLE [orders] ADD CONSTRAINT [orders_customer_id_fkey] FOREIGN KEY ([customer_id]) REFERENCES [customers] ([id]);",
+			"DROP TABLE [old_customers];",
+		},
+		"oracle": {
+			`ALTER TABLE "orders" DROP CONSTRAINT "orders_old_fkey";`,
+			`ALTER TABLE "orders" DROP CONSTRAINT "orders_legacy_check";`,
+			`DROP INDEX "idx_orders_legacy";`,
+			"CREATE TABLE \"items\" (\n    \"id\" NUMBER(10) NOT NULL,\n    \"order_id\" NUMBER(10) NOT NULL,\n    PRIMARY KEY (\"id\")\n);",
+			`ALTER TABLE "customers" MODIFY ("email" VARCHAR2(255 CHAR) NOT NULL);`,
+			`ALTER TABLE "orders" ADD ("note" CLOB);`,
+			`ALTER TABLE "orders" MODIFY ("total" NUMBER(10, 2) DEFAULT 0 NOT NULL);`,
+			`ALTER TABLE "orders" DROP COLUMN "legacy";`,
+			`CREATE INDEX "idx_orders_customer" ON "orders" ("customer_id");`,
+			`ALTER TABLE "orders" ADD CONSTRAINT "orders_total_check" CHECK (total >= 0);`,
+			`ALTER TABLE "items" ADD CONSTRAINT "items_order_id_fkey" FOREIGN KEY ("order_id") REFERENCES "orders" ("id");`,
+			`ALTER TABLE "orders" ADD CONSTRAINT "orders_customer_id_fkey" FOREIGN KEY ("customer_id") REFERENCES "customers" ("id");`,
+			`DROP TABLE "old_customers";`,
+		},
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			dialect, err := LookupDialect(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := diff.AlterSQL(dialect); !reflect.DeepEqual(got, want) {
+				t.Errorf("AlterSQL =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+			if stmts := DiffSchemas(source, source).AlterSQL(dialect); len(stmts) > 0 {
+				t.Errorf("identical schemas alter with %q", stmts)
+			}
+		})
+	}
+}
+
+// TestAlterSQLReferenceOrder runs a script on DuckDB, which checks foreign
+// keys when tables are created and dropped: the new comments table sorts
+// before the new posts table it references, and the removed accounts table
+// before the removed sessions table referencing it.
+func TestAlterSQLReferenceOrder(t *testing.T) {
+	source := openTestDuckDB(t,
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, title VARCHAR)",
+		"CREATE TABLE comments (id INTEGER PRIMARY KEY, post_id INTEGER REFERENCES posts (id))",
+	)
+	target := openTestDuckDB(t,
+		"CREATE TABLE accounts (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE sessions (id INTEGER PRIMARY KEY, account_id INTEGER REFERENCES accounts (id))",
+	)
+	diff := DiffSchemas(mustLoadSchema(t, source, "comments", "posts"), mustLoadSchema(t, target, "accounts", "sessions"))
+	stmts := diff.AlterSQL(duckdbDialect{})
+	for _, stmt := range stmts {
+		if err := target.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v\nscript:\n%s", stmt, err, strings.Join(stmts, "\n"))
+		}
+	}
+	tables, err := ListTables(target, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"comments", "posts"}; !reflect.DeepEqual(sortedCopy(tables), want) {
+		t.Errorf("tables after the script = %q, want %q", tables, want)
+	}
+}
+
+func sortedCopy(names []string) []string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return sorted
+}
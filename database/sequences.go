@@ -0,0 +1,52 @@
+package database
+
+import (
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ResetSequences moves the sequences and auto-increment counters of tableName
+// past the highest key it holds, so inserts that rely on generated ids do not
+// collide with rows imported with explicit ids. Counters are never lowered.
+func ResetSequences(db *gorm.DB, tableName string) error {
+	d, err := DialectOf(db)
+	if err != nil {
+		return err
+	}
+	return d.ResetSequences(db, tableName)
+}
+
+// ExportSequencesSQL adds the current sequence values of tableName to the
+// schema file written by ExportSchemaSQL. PostgreSQL sequences are created
+// before the table and set after it, identity columns are restored with their
+// next value, and SQLite AUTOINCREMENT counters are written to sqlite_sequence.
+// MySQL needs nothing, as SHOW CREATE TABLE already carries AUTO_INCREMENT.
+func ExportSequencesSQL(db *gorm.DB, tableName, filename string) error {
+	d, err := DialectOf(db)
+	if err != nil {
+		return err
+	}
+	before, after, err := d.SequencesSQL(db, tableName)
+	if err != nil {
+		return err
+	}
+	if len(before) == 0 && len(after) == 0 {
+		return nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var out strings.Builder
+	for _, stmt := range before {
+		out.WriteString(stmt + "\n")
+	}
+	out.Write(content)
+	for _, stmt := range after {
+		out.WriteString(stmt + "\n")
+	}
+	return os.WriteFile(filename, []byte(out.String()), 0644)
+}
@@ -0,0 +1,357 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcboeker/go-duckdb"
+	"gorm.io/gorm"
+)
+
+// Output-only dialects render schema and data files for databases
+// sql-migration cannot connect to, such as SQL Server and Oracle. They are
+// registered like any other dialect so export --target-dialect accepts them,
+// but every method that needs a connection fails.
+
+// outputOnly implements the connection methods of Dialect for an output-only
+// dialect of database.
+type outputOnly struct {
+	database string
+}
+
+func (o outputOnly) err() error {
+	return fmt.Errorf("%s is output-only: export --target-dialect writes files for it, but sql-migration cannot connect to it", o.database)
+}
+
+func (o outputOnly) Open(dsn string, config *gorm.Config) (*gorm.DB, error) {
+	return nil, o.err()
+}
+
+func (o outputOnly) CurrentSchema(db *gorm.DB) (string, error) {
+	return "", o.err()
+}
+
+func (o outputOnly) ListTables(db *gorm.DB, schemas []string, all bool) ([]SchemaTable, error) {
+	return nil, o.err()
+}
+
+func (o outputOnly) TableExists(db *gorm.DB, tableName string) (bool, error) {
+	return false, o.err()
+}
+
+func (o outputOnly) ListObjects(db *gorm.DB, schemas []string, all bool) ([]SchemaObject, error) {
+	return nil, o.err()
+}
+
+func (o outputOnly) CreateTableStatement(db *gorm.DB, tableName string) (string, error) {
+	return "", o.err()
+}
+
+func (o outputOnly) PrimaryKeyColumns(db *gorm.DB, tableName string) ([]string, error) {
+	return nil, o.err()
+}
+
+func (o outputOnly) IntrospectTable(db *gorm.DB, tableName string) (*Table, error) {
+	return nil, o.err()
+}
+
+func (o outputOnly) TruncateTables(db *gorm.DB, tableNames []string) error {
+	return o.err()
+}
+
+func (o outputOnly) DropTables(db *gorm.DB, tableNames []string) error {
+	return o.err()
+}
+
+func (o outputOnly) ResetSequences(db *gorm.DB, tableName string) error {
+	return o.err()
+}
+
+func (o outputOnly) SequencesSQL(db *gorm.DB, tableName string) (before, after []string, err error) {
+	return nil, nil, o.err()
+}
+
+func (o outputOnly) AcquireLock(db *gorm.DB, name string, timeout time.Duration) (*Lock, error) {
+	return nil, o.err()
+}
+
+// tableRenderer is implemented by dialects that render whole tables read
+// from another database, translating types and quoting names, instead of
+// CreateTableSQL copying the introspected definitions.
+type tableRenderer interface {
+	CreateTableSQL(table *Table) []string
+}
+
+// columnAdder is implemented by dialects whose ALTER TABLE adds columns
+// without the standard ADD COLUMN, or with types of their own.
+type columnAdder interface {
+	AddColumnSQL(tableName string, c Column) []string
+}
+
+// dataFileWrapper is implemented by dialects that need statements around the
+// INSERT statements of a data file, such as SQL Server's IDENTITY_INSERT.
+type dataFileWrapper interface {
+	DataFileSQL(table *Table) (before, after []string)
+}
+
+// translatedCreateTable renders table for an output-only dialect, with column
+// rendering each column definition. key is set for columns of the primary
+// key, an index or a foreign key, which need a type that can be indexed.
+// Defaults column cannot translate are dropped, and listed in comments ahead
+// of the statement. Comments follow as CommentSQL statements.
+func translatedCreateTable(d Dialect, table *Table, column func(c Column, key bool) (string, bool)) []string {
+	keys := map[string]bool{}
+	for _, name := range table.PrimaryKey {
+		keys[name] = true
+	}
+	for _, idx := range table.Indexes {
+		for _, name := range idx.Columns {
+			keys[name] = true
+		}
+	}
+	for _, fk := range table.ForeignKeys {
+		for _, name := range fk.Columns {
+			keys[name] = true
+		}
+	}
+
+	var stmts, defs []string
+	for _, c := range table.Columns {
+		// SQLite primary keys may be declared nullable
+		for _, name := range table.PrimaryKey {
+			if name == c.Name {
+				c.Nullable = false
+			}
+		}
+		def, ok := column(c, keys[c.Name])
+		if !ok {
+			stmts = append(stmts, fmt.Sprintf("-- Default %s of column %s not translated", *c.Default, c.Name))
+		}
+		defs = append(defs, def)
+	}
+	if len(table.PrimaryKey) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", quoteNames(d, table.PrimaryKey)))
+	}
+	for _, c := range table.Checks {
+		c.Expression = castPattern.ReplaceAllString(c.Expression, "")
+		defs = append(defs, checkDefinition(d, c))
+	}
+
+	stmts = append(stmts, fmt.Sprintf("CREATE TABLE %s (\n    %s\n);", quoteTableName(d, table.Name), strings.Join(defs, ",\n    ")))
+	for _, idx := range table.Indexes {
+		stmts = append(stmts, d.CreateIndexSQL(table.Name, idx))
+	}
+	return append(stmts, commentStatements(d, table.Name, tableComments(table))...)
+}
+
+// addTranslatedColumn renders the statement adding c to tableName for an
+// output-only dialect, formatting the quoted table name and the column
+// definition with format. A default column cannot translate is dropped and
+// noted in a comment ahead of the statement.
+func addTranslatedColumn(d Dialect, tableName string, c Column, column func(c Column, key bool) (string, bool), format string) []string {
+	var stmts []string
+	def, ok := column(c, false)
+	if !ok {
+		stmts = append(stmts, fmt.Sprintf("-- Default %s of column %s not translated", *c.Default, c.Name))
+	}
+	return append(stmts, fmt.Sprintf(format, quoteTableName(d, tableName), def))
+}
+
+// splitRows splits rows into batches of at most size rows, the most a single
+// INSERT statement of some databases accepts.
+func splitRows(rows [][]string, size int) [][][]string {
+	var batches [][][]string
+	for len(rows) > size {
+		batches = append(batches, rows[:size])
+		rows = rows[size:]
+	}
+	return append(batches, rows)
+}
+
+// baseType breaks a column type read from any dialect into a normalized base
+// type and its parameters, so "int(10) unsigned", "character varying(20)"
+// and "TIMESTAMP(3) WITH TIME ZONE" can be mapped by output-only dialects.
+// Unsigned integers become the next wider signed type.
+func baseType(t string) (base string, params []string) {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if strings.HasSuffix(t, "[]") {
+		return "array", nil
+	}
+	if i, j := strings.Index(t, "("), strings.LastIndex(t, ")"); i >= 0 && j > i {
+		for _, p := range strings.Split(t[i+1:j], ",") {
+			params = append(params, strings.TrimSpace(p))
+		}
+		t = t[:i] + " " + t[j+1:]
+	}
+	words := strings.Fields(t)
+	unsigned := false
+	for len(words) > 1 && (words[len(words)-1] == "unsigned" || words[len(words)-1] == "zerofill") {
+		unsigned = unsigned || words[len(words)-1] == "unsigned"
+		words = words[:len(words)-1]
+	}
+	base = strings.Join(words, " ")
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	switch {
+	case base == "tinyint" && len(params) == 1 && params[0] == "1":
+		return "boolean", nil
+	case base == "utinyint", unsigned && base == "tinyint":
+		return "smallint", nil
+	case base == "usmallint", unsigned && base == "smallint":
+		return "integer", nil
+	case base == "uinteger", unsigned && (base == "mediumint" || base == "integer"):
+		return "bigint", nil
+	case base == "ubigint", unsigned && base == "bigint":
+		return "numeric", []string{"20"}
+	case base == "hugeint", base == "uhugeint":
+		return "numeric", []string{"38"}
+	case base == "float":
+		// MySQL FLOAT(p) is double precision above 24 bits
+		if len(params) == 1 {
+			if p, err := strconv.Atoi(params[0]); err == nil && p > 24 {
+				return "double precision", nil
+			}
+		}
+		return "real", nil
+	}
+	return base, params
+}
+
+// typeLength returns the single length parameter of a type, or 0.
+func typeLength(params []string) int {
+	if len(params) != 1 {
+		return 0
+	}
+	n, _ := strconv.Atoi(params[0])
+	return n
+}
+
+var (
+	numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	stringPattern = regexp.MustCompile(`^'(?:[^']|'')*'$`)
+)
+
+// translateDefault maps a column default to an output-only dialect: number
+// and string constants are kept, booleans become 1 and 0, and the current
+// date and time functions become now and today. It reports false for
+// anything else, which is dropped.
+func translateDefault(def string, now, today string) (string, bool) {
+	def = strings.TrimSpace(castPattern.ReplaceAllString(def, ""))
+	for strings.HasPrefix(def, "(") && strings.HasSuffix(def, ")") {
+		def = strings.TrimSpace(def[1 : len(def)-1])
+	}
+	switch strings.ToLower(def) {
+	case "true":
+		return "1", true
+	case "false":
+		return "0", true
+	case "now()", "current_timestamp", "current_timestamp()", "localtimestamp", "transaction_timestamp()":
+		return now, true
+	case "current_date", "curdate()":
+		return today, true
+	}
+	if numberPattern.MatchString(def) || stringPattern.MatchString(def) {
+		return def, true
+	}
+	return "", false
+}
+
+// Kinds of column values whose literal depends on the column type.
+const (
+	valueOther = iota
+	valueBinary
+	valueDate
+	valueTime
+	valueTimestamp
+	valueTimestampTZ
+)
+
+// valueKind classifies a column by the type name its driver reports.
+func valueKind(dbType string) int {
+	switch strings.ToUpper(dbType) {
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY":
+		return valueBinary
+	case "DATE":
+		return valueDate
+	case "TIME", "TIMETZ":
+		return valueTime
+	case "TIMESTAMP", "DATETIME", "DATETIME2":
+		return valueTimestamp
+	case "TIMESTAMPTZ", "DATETIMEOFFSET":
+		return valueTimestampTZ
+	}
+	return valueOther
+}
+
+// temporalLayouts are the text forms drivers return date and time values in
+// when they do not parse them, as MySQL does without parseTime.
+var temporalLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+// temporalValue returns val as a time, parsing the text of drivers that
+// return dates and times as strings.
+func temporalValue(val any) (time.Time, bool) {
+	var s string
+	switch v := val.(type) {
+	case time.Time:
+		return v, true
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return time.Time{}, false
+	}
+	for _, layout := range temporalLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// uuidString renders the 16 bytes drivers such as DuckDB return for UUID
+// columns in the usual 8-4-4-4-12 form.
+func uuidString(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// portableLiteral renders values of database-specific Go types that other
+// databases have no type for, with str rendering string literals: big and
+// decimal numbers as numbers, DuckDB intervals as text and lists, maps and
+// structs as JSON text. It reports false for other values.
+func portableLiteral(val any, str func(string) string) (string, bool) {
+	switch v := val.(type) {
+	case *big.Int:
+		return v.String(), true
+	case duckdb.Decimal:
+		return v.String(), true
+	case duckdb.Interval:
+		return str(fmt.Sprintf("%d months %d days %d microseconds", v.Months, v.Days, v.Micros)), true
+	case duckdb.Map:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+		val = m
+	case []any, map[string]any:
+	default:
+		return "", false
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return str(fmt.Sprint(val)), true
+	}
+	return str(string(b)), true
+}
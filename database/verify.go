@@ -0,0 +1,317 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// KeyRange is a primary key interval: keys strictly after After and up to and
+// including UpTo. A nil bound is unbounded.
+type KeyRange struct {
+	After []string
+	UpTo  []string
+}
+
+func (r KeyRange) String() string {
+	var parts []string
+	if r.After != nil {
+		parts = append(parts, "after ("+strings.Join(r.After, ", ")+")")
+	}
+	if r.UpTo != nil {
+		parts = append(parts, "up to ("+strings.Join(r.UpTo, ", ")+")")
+	}
+	if len(parts) == 0 {
+		return "all keys"
+	}
+	return strings.Join(parts, " ")
+}
+
+// ChunkChecksum is the row count and order-independent checksum of the rows
+// of a table that fall in a key range.
+type ChunkChecksum struct {
+	Range    KeyRange
+	Rows     int64
+	Checksum uint64
+}
+
+// TableVerification is the outcome of comparing a table between two databases.
+type TableVerification struct {
+	Table          string
+	SourceRows     int64
+	TargetRows     int64
+	SourceChecksum uint64
+	TargetChecksum uint64
+	// Mismatches lists the key ranges whose rows differ.
+	Mismatches []KeyRange
+}
+
+// Matches reports whether both sides hold the same rows.
+func (v *TableVerification) Matches() bool {
+	return v.SourceRows == v.TargetRows && v.SourceChecksum == v.TargetChecksum && len(v.Mismatches) == 0
+}
+
+// keyCondition compares the key columns against values with op, using a row
+// value comparison for composite keys.
+func keyCondition(q identifierQuoter, keys []string, op string, values []string) (string, []any) {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	if len(keys) == 1 {
+		return fmt.Sprintf("%s %s ?", q.QuoteIdentifier(keys[0]), op), args
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+	return fmt.Sprintf("(%s) %s (%s)", quoteNames(q, keys), op, placeholders), args
+}
+
+// queryKeyRange selects the rows of tableName whose key falls in r, in key
+// order and at most limit of them unless limit is 0. Without keys the whole
+// table is selected. Names are quoted for the dialect of db.
+func queryKeyRange(db *gorm.DB, tableName string, keys []string, r KeyRange, limit int) (*sql.Rows, error) {
+	d, err := DialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT * FROM " + quoteTableName(d, tableName)
+	var args []any
+	if len(keys) > 0 {
+		var conds []string
+		if r.After != nil {
+			cond, condArgs := keyCondition(d, keys, ">", r.After)
+			conds, args = append(conds, cond), append(args, condArgs...)
+		}
+		if r.UpTo != nil {
+			cond, condArgs := keyCondition(d, keys, "<=", r.UpTo)
+			conds, args = append(conds, cond), append(args, condArgs...)
+		}
+		if len(conds) > 0 {
+			query += " WHERE " + strings.Join(conds, " AND ")
+		}
+		query += " ORDER BY " + quoteNames(d, keys)
+	}
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return db.Raw(query, args...).Rows()
+}
+
+// ScanKeyRange calls fn with every row of tableName whose key falls in r, in
+// key order. Without keys the whole table is scanned.
+func ScanKeyRange(db *gorm.DB, tableName string, keys []string, r KeyRange, fn func(cols []string, values []any) error) error {
+	return scanTypedKeyRange(db, tableName, keys, r, func(cols, _ []string, values []any) error {
+		return fn(cols, values)
+	})
+}
+
+// scanTypedKeyRange is ScanKeyRange also passing the database type names of
+// the columns.
+func scanTypedKeyRange(db *gorm.DB, tableName string, keys []string, r KeyRange, fn func(cols, types []string, values []any) error) error {
+	rows, err := queryKeyRange(db, tableName, keys, r, 0)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, _ := rows.Columns()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	types := make([]string, len(columnTypes))
+	for i, t := range columnTypes {
+		types[i] = t.DatabaseTypeName()
+	}
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		if err := fn(cols, types, values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`)
+
+// NormalizeValue renders a scanned value in a dialect independent form, so the
+// same row read from MySQL, PostgreSQL or SQLite produces the same text. Times
+// are rendered in UTC, whatever location the driver scanned them in.
+func NormalizeValue(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "\x00NULL"
+	case []byte:
+		return normalizeText(string(v))
+	case string:
+		return normalizeText(v)
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05.999999")
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float32:
+		return normalizeText(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return normalizeText(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// normalizeText strips insignificant trailing zeros from decimals, so that
+// DECIMAL(10,2) '2.50' and REAL 2.5 compare equal.
+func normalizeText(s string) string {
+	if decimalPattern.MatchString(s) {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	return s
+}
+
+// RowHash hashes a row independently of the column order and of the case of
+// the column names.
+func RowHash(cols []string, values []any) uint64 {
+	fields := make([]string, len(cols))
+	for i, c := range cols {
+		fields[i] = strings.ToLower(c) + "=" + NormalizeValue(values[i])
+	}
+	sort.Strings(fields)
+
+	h := fnv.New64a()
+	for _, f := range fields {
+		h.Write([]byte(f))
+		h.Write([]byte{0x1f})
+	}
+	return h.Sum64()
+}
+
+// ChecksumKeyRange counts the rows of tableName in r and sums their hashes.
+// Summing makes the checksum independent of row order.
+func ChecksumKeyRange(db *gorm.DB, tableName string, keys []string, r KeyRange) (ChunkChecksum, error) {
+	chunk := ChunkChecksum{Range: r}
+	err := ScanKeyRange(db, tableName, keys, r, func(cols []string, values []any) error {
+		chunk.Rows++
+		chunk.Checksum += RowHash(cols, values)
+		return nil
+	})
+	return chunk, err
+}
+
+// ChecksumChunks splits tableName into consecutive key ranges of chunkSize
+// rows and calls fn with the checksum of each. The ranges cover the whole key
+// space: the first is open below and a final, normally empty, range is open
+// above, so rows that only exist in another database still fall in a range.
+func ChecksumChunks(db *gorm.DB, tableName string, keys []string, chunkSize int, fn func(ChunkChecksum) error) error {
+	if len(keys) == 0 {
+		chunk, err := ChecksumKeyRange(db, tableName, nil, KeyRange{})
+		if err != nil {
+			return err
+		}
+		return fn(chunk)
+	}
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+
+	var after []string
+	for {
+		rows, err := queryKeyRange(db, tableName, keys, KeyRange{After: after}, chunkSize)
+		if err != nil {
+			return err
+		}
+
+		cols, _ := rows.Columns()
+		keyIndex := columnIndexes(cols, keys)
+		chunk := ChunkChecksum{Range: KeyRange{After: after}}
+		for rows.Next() {
+			values := make([]any, len(cols))
+			ptrs := make([]any, len(cols))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				rows.Close()
+				return err
+			}
+			chunk.Rows++
+			chunk.Checksum += RowHash(cols, values)
+			chunk.Range.UpTo = make([]string, len(keyIndex))
+			for i, idx := range keyIndex {
+				chunk.Range.UpTo[i] = keyString(values[idx])
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if chunk.Rows < int64(chunkSize) {
+			// The last chunk takes everything after the previous key
+			chunk.Range.UpTo = nil
+			return fn(chunk)
+		}
+		if err := fn(chunk); err != nil {
+			return err
+		}
+		after = chunk.Range.UpTo
+	}
+}
+
+func columnIndexes(cols []string, names []string) []int {
+	indexes := make([]int, 0, len(names))
+	for _, name := range names {
+		for i, c := range cols {
+			if strings.EqualFold(c, name) {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return indexes
+}
+
+// VerifyTable compares tableName between source and target chunk by chunk.
+// Chunk boundaries are taken from the source's primary key; tables without a
+// primary key are compared as a single chunk.
+func VerifyTable(source, target *gorm.DB, tableName string, chunkSize int) (*TableVerification, error) {
+	keys, err := PrimaryKeyColumns(source, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TableVerification{Table: tableName}
+	err = ChecksumChunks(source, tableName, keys, chunkSize, func(src ChunkChecksum) error {
+		dst, err := ChecksumKeyRange(target, tableName, keys, src.Range)
+		if err != nil {
+			return err
+		}
+		result.SourceRows += src.Rows
+		result.TargetRows += dst.Rows
+		result.SourceChecksum += src.Checksum
+		result.TargetChecksum += dst.Checksum
+		if src.Rows != dst.Rows || src.Checksum != dst.Checksum {
+			result.Mismatches = append(result.Mismatches, src.Range)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	utc := time.Date(2024, 3, 1, 10, 30, 0, 123456000, time.UTC)
+	tests := []struct {
+		name string
+		val  any
+		want string
+	}{
+		{"nil", nil, "\x00NULL"},
+		{"bytes", []byte("abc"), "abc"},
+		{"decimal", "2.50", "2.5"},
+		{"whole decimal", "3.00", "3"},
+		{"float", 2.5, "2.5"},
+		{"bool", true, "1"},
+		{"int", int64(42), "42"},
+		{"utc time", utc, "2024-03-01 10:30:00.123456"},
+		{"zoned time", utc.In(time.FixedZone("EET", 2*60*60)), "2024-03-01 10:30:00.123456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeValue(tt.val); got != tt.want {
+				t.Errorf("NormalizeValue(%v) = %q, want %q", tt.val, got, tt.want)
+			}
+		})
+	}
+}
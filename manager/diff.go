@@ -0,0 +1,254 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/semay-cli/sql-migration/config"
+	"github.com/semay-cli/sql-migration/database"
+	"github.com/spf13/cobra"
+)
+
+// loadSchemaFrom loads the schema of the "export" or "import" database, or of
+// the *_schema.sql files of any other location, treated as a directory.
+func loadSchemaFrom(cmd *cobra.Command, location string, dsnCfg *config.DSNConfig, tableName string) (*database.Schema, error) {
+	var schema *database.Schema
+	switch location {
+	case "export", "import":
+		db, err := connectLocation(location, dsnCfg)
+		if err != nil {
+			return nil, err
+		}
+		tables := []string{tableName}
+		if tableName == "" {
+			tables, err = getAllTableNames(cmd, db)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get table names: %w", err)
+			}
+		} else if exists, err := database.TableExists(db, tableName); err != nil {
+			return nil, err
+		} else if !exists {
+			return database.NewSchema(), nil
+		}
+		return database.LoadSchema(db, tables)
+	default:
+		var err error
+		schema, err = database.LoadSchemaDir(location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tableName != "" {
+		filtered := database.NewSchema()
+		if table, ok := schema.Tables[tableName]; ok {
+			filtered.Tables[tableName] = table
+		}
+		schema = filtered
+	}
+	return schema, nil
+}
+
+// runDataDiff compares the rows of the source and target databases and writes
+// the SQL patch reconciling the target. It reports whether any table differs.
+// Tables that cannot be compared make it fail, with a patch that rolls back.
+func runDataDiff(cmd *cobra.Command, dsnCfg *config.DSNConfig, sourceLocation, targetLocation, tableName string) (bool, error) {
+	patchFile, _ := cmd.Flags().GetString("patch")
+	chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+
+	for _, location := range []string{sourceLocation, targetLocation} {
+		if location != "export" && location != "import" {
+			return false, fmt.Errorf("row-level diff needs a database, not %q (use export or import)", location)
+		}
+	}
+	source, err := connectLocation(sourceLocation, dsnCfg)
+	if err != nil {
+		return false, err
+	}
+	target, err := connectLocation(targetLocation, dsnCfg)
+	if err != nil {
+		return false, err
+	}
+
+	tables := []string{tableName}
+	if tableName == "" {
+		tables, err = getAllTableNames(cmd, source)
+		if err != nil {
+			return false, fmt.Errorf("failed to get table names: %w", err)
+		}
+	}
+
+	// The patch goes to stdout unless a file is given, so the report is
+	// printed to stderr to keep the patch clean.
+	patch := io.Writer(os.Stdout)
+	report := io.Writer(os.Stdout)
+	if patchFile == "" || patchFile == "-" {
+		report = os.Stderr
+	} else {
+		file, err := os.Create(patchFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to create patch file: %w", err)
+		}
+		defer file.Close()
+		patch = file
+	}
+
+	// Rows are inserted into referenced tables first and deleted from them
+	// last, so that foreign keys hold after every statement of the patch.
+	schema, err := database.LoadSchema(source, tables)
+	if err != nil {
+		return false, err
+	}
+	tables = schema.ReferenceOrder()
+	deletes := make([]bytes.Buffer, len(tables))
+
+	differs := false
+	failed := 0
+	fmt.Fprintln(patch, "BEGIN;")
+	for i, tbl := range tables {
+		exists, err := database.TableExists(target, tbl)
+		if err != nil {
+			fmt.Fprintf(report, "%s: %v\n", tbl, err)
+			failed++
+			continue
+		}
+		if !exists {
+			fmt.Fprintf(report, "%s: table does not exist in the %s database, skipped\n", tbl, targetLocation)
+			differs = true
+			continue
+		}
+		result, err := database.DiffTableData(source, target, tbl, chunkSize, patch, &deletes[i])
+		if err != nil {
+			fmt.Fprintf(report, "%s: %v\n", tbl, err)
+			failed++
+			continue
+		}
+		if result.Empty() {
+			fmt.Fprintf(report, "%s: no differences\n", tbl)
+			continue
+		}
+		differs = true
+		fmt.Fprintf(report, "%s: %d insert(s), %d update(s), %d delete(s) in %d differing chunk(s)\n",
+			tbl, result.Inserts, result.Updates, result.Deletes, result.Chunks)
+	}
+	for i := len(deletes) - 1; i >= 0; i-- {
+		if _, err := deletes[i].WriteTo(patch); err != nil {
+			return false, err
+		}
+	}
+	// An incomplete patch must not be applied by mistake
+	if failed > 0 {
+		fmt.Fprintf(patch, "-- %d table(s) could not be compared, this patch is incomplete\nROLLBACK;\n", failed)
+	} else {
+		fmt.Fprintln(patch, "COMMIT;")
+	}
+
+	if patchFile != "" && patchFile != "-" {
+		fmt.Fprintf(report, "Patch written to %s\n", patchFile)
+	}
+	if failed > 0 {
+		return differs, fmt.Errorf("%d table(s) could not be compared", failed)
+	}
+	return differs, nil
+}
+
+// diffCmd compares two schemas and optionally writes the ALTER script that
+// brings the target in line with the source.
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the schema of two databases or an export directory",
+	Long:  "Compare the tables, columns, types, defaults, nullability, indexes and constraints of a source and a target schema. Each side is the export database, the import database, or a directory of *_schema.sql files. With --data, compare the rows of both databases by primary key instead and write a SQL patch reconciling the target.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tableName, _ := cmd.Flags().GetString("table")
+		sourceLocation, _ := cmd.Flags().GetString("source")
+		targetLocation, _ := cmd.Flags().GetString("target")
+		sqlFile, _ := cmd.Flags().GetString("sql")
+		dialect, _ := cmd.Flags().GetString("dialect")
+		exitCode, _ := cmd.Flags().GetBool("exit-code")
+		dataDiff, _ := cmd.Flags().GetBool("data")
+
+		dsnCfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if dialect == "" {
+			dialect = dsnCfg.Driver
+		}
+		sqlDialect, err := database.LookupDialect(dialect)
+		if err != nil {
+			return err
+		}
+
+		if dataDiff {
+			differs, err := runDataDiff(cmd, dsnCfg, sourceLocation, targetLocation, tableName)
+			if err != nil {
+				return err
+			}
+			if exitCode && differs {
+				return fmt.Errorf("table data differs")
+			}
+			return nil
+		}
+
+		source, err := loadSchemaFrom(cmd, sourceLocation, dsnCfg, tableName)
+		if err != nil {
+			return fmt.Errorf("failed to load source schema: %w", err)
+		}
+		target, err := loadSchemaFrom(cmd, targetLocation, dsnCfg, tableName)
+		if err != nil {
+			return fmt.Errorf("failed to load target schema: %w", err)
+		}
+
+		// The script goes to stdout with --sql -, so the report is printed
+		// to stderr to keep the script clean.
+		report := io.Writer(os.Stdout)
+		if sqlFile == "-" {
+			report = os.Stderr
+		}
+		diff := database.DiffSchemas(source, target)
+		if diff.Empty() {
+			fmt.Fprintln(report, "No schema differences found.")
+		} else {
+			diff.WriteReport(report)
+		}
+
+		if sqlFile != "" {
+			script := strings.Join(diff.AlterSQL(sqlDialect), "\n")
+			if script != "" {
+				script += "\n"
+			}
+			if sqlFile == "-" {
+				fmt.Print(script)
+			} else {
+				if err := os.WriteFile(sqlFile, []byte(script), 0644); err != nil {
+					return fmt.Errorf("failed to write ALTER script: %w", err)
+				}
+				fmt.Printf("ALTER script written to %s\n", sqlFile)
+			}
+		}
+
+		if exitCode && !diff.Empty() {
+			return fmt.Errorf("schemas differ")
+		}
+		return nil
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringP("table", "T", "", "Table name to compare (if not set, compares all tables)")
+	diffCmd.Flags().StringP("json", "j", "", "Specify json file name to load (e.g., dsn.json, defaults to dsn.json)")
+	addSchemaFlags(diffCmd)
+	diffCmd.Flags().String("source", "export", "Desired schema: export, import or a directory of *_schema.sql files")
+	diffCmd.Flags().String("target", "import", "Schema to compare: export, import or a directory of *_schema.sql files")
+	diffCmd.Flags().String("sql", "", "Write the ALTER script bringing the target in line with the source to this file (- for stdout)")
+	diffCmd.Flags().String("dialect", "", "Dialect of the ALTER script (defaults to the configured driver)")
+	diffCmd.Flags().Bool("exit-code", false, "Exit with a non-zero status when the schemas (or data) differ")
+	diffCmd.Flags().Bool("data", false, "Compare rows instead of schemas and generate an INSERT/UPDATE/DELETE patch")
+	diffCmd.Flags().String("patch", "-", "With --data, file to write the SQL patch to (- for stdout)")
+	diffCmd.Flags().Int("chunk-size", 1000, "With --data, rows per checksum chunk")
+
+	goFrame.AddCommand(diffCmd)
+}
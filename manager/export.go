@@ -2,154 +2,108 @@ package manager
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 
-	"github.com/semay-cli/sql-migration/config"
 	"github.com/semay-cli/sql-migration/database"
+	"github.com/semay-cli/sql-migration/migration"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
 )
 
-// getAllTableNames retrieves all table names for the current database connection.
-func getAllTableNames(driver string, db *gorm.DB) ([]string, error) {
-	var tables []string
-	switch driver {
-	case "mysql":
-		rows, err := db.Raw("SHOW TABLES").Rows()
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var table string
-			if err := rows.Scan(&table); err != nil {
-				return nil, err
-			}
-			tables = append(tables, table)
-		}
-	case "postgres":
-		rows, err := db.Raw(`SELECT tablename FROM pg_tables WHERE schemaname = 'public'`).Rows()
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var table string
-			if err := rows.Scan(&table); err != nil {
-				return nil, err
-			}
-			tables = append(tables, table)
-		}
-	case "sqlite":
-		rows, err := db.Raw(`SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'`).Rows()
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var table string
-			if err := rows.Scan(&table); err != nil {
-				return nil, err
-			}
-			tables = append(tables, table)
-		}
-	default:
-		return nil, fmt.Errorf("unsupported driver: %s", driver)
+// schemaSelection returns the schemas selected with --schema, --databases or
+// --all-schemas on the commands that have these flags, checked against the
+// dialect of db. No schemas means the current one.
+func schemaSelection(cmd *cobra.Command, db *gorm.DB) ([]string, bool, error) {
+	schemas, _ := cmd.Flags().GetStringSlice("schema")
+	databases, _ := cmd.Flags().GetStringSlice("databases")
+	allSchemas, _ := cmd.Flags().GetBool("all-schemas")
+
+	dialect := db.Dialector.Name()
+	switch {
+	case len(databases) > 0 && dialect != "mysql":
+		return nil, false, fmt.Errorf("--databases is for MySQL, use --schema for %s", dialect)
+	case len(schemas) > 0 && dialect == "mysql":
+		return nil, false, fmt.Errorf("MySQL has no schemas, use --databases")
+	}
+	schemas = append(schemas, databases...)
+	if allSchemas && len(schemas) > 0 {
+		return nil, false, fmt.Errorf("--all-schemas cannot be combined with --schema or --databases")
+	}
+	return schemas, allSchemas, nil
+}
+
+// getAllTableNames lists the tables of the schemas selected by
+// schemaSelection. Tables outside the current schema are schema-qualified.
+func getAllTableNames(cmd *cobra.Command, db *gorm.DB) ([]string, error) {
+	schemas, allSchemas, err := schemaSelection(cmd, db)
+	if err != nil {
+		return nil, err
 	}
-	return tables, nil
+	return database.ListTables(db, schemas, allSchemas)
+}
+
+// addSchemaFlags adds the flags selecting the schemas getAllTableNames lists.
+func addSchemaFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("schema", nil, "PostgreSQL schemas or attached SQLite databases to read tables from (default the current schema)")
+	cmd.Flags().StringSlice("databases", nil, "MySQL databases to read tables from (default the connected database)")
+	cmd.Flags().Bool("all-schemas", false, "Read tables from every user schema or database")
 }
 
 // exportCmd is the Cobra command for exporting a table's schema and data.
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "Export table schema and data to SQL files",
-	Long:  "Export the schema and data of a specified table, or all tables if not specified, to .sql files in the exported folder.",
-	Run: func(cmd *cobra.Command, args []string) {
-		// Get flags
-		tableName, _ := cmd.Flags().GetString("table")
-		outputDir, _ := cmd.Flags().GetString("output")
-		env, _ := cmd.Flags().GetString("json")
-		schemaOnly, _ := cmd.Flags().GetBool("schema-only")
-		dataOnly, _ := cmd.Flags().GetBool("data-only")
-
-		// Set default output directory if not provided
-		if outputDir == "" {
-			outputDir = "exported"
-		}
+	Long:  "Export the schema and data of a specified table, or all tables if not specified, to .sql files in the exported folder, together with the views, materialized views, triggers, routines and standalone indexes of the selected schemas and, on PostgreSQL, the extensions, types and domains. --objects narrows the export to some types of objects.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := migration.ExportOptions{Logf: printf}
+		opts.OutputDir, _ = cmd.Flags().GetString("output")
+		opts.SchemaOnly, _ = cmd.Flags().GetBool("schema-only")
+		opts.DataOnly, _ = cmd.Flags().GetBool("data-only")
+		opts.BatchSize, _ = cmd.Flags().GetInt("batch-size")
+		opts.Resume, _ = cmd.Flags().GetBool("resume")
+		opts.Checkpoint, _ = cmd.Flags().GetString("checkpoint")
+		opts.Sequences, _ = cmd.Flags().GetBool("sequences")
+		opts.TargetDialect, _ = cmd.Flags().GetString("target-dialect")
+		opts.FlattenPartitions, _ = cmd.Flags().GetBool("flatten-partitions")
 
-		// Ensure output directory exists
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			fmt.Printf("Failed to create output directory: %v\n", err)
-			return
-		}
-
-		// Load DSN config (dsn.json)
-		configPath := "dsn.json"
-		if env != "" {
-			configPath = fmt.Sprintf("%s.json", env)
-		}
-		dsnCfg, err := config.LoadDSNConfig(configPath)
+		dsnCfg, err := loadConfig(cmd)
 		if err != nil {
-			fmt.Printf("Failed to load DSN config: %v\n", err)
-			return
+			return err
 		}
+		if opts.Selection, err = tableSelection(cmd, dsnCfg); err != nil {
+			return err
+		}
+		opts.Objects, _ = cmd.Flags().GetStringSlice("objects")
 
 		// Connect to export database
-		db, err := database.ReturnSession("export", dsnCfg.Driver, dsnCfg.ExportDatabaseDSN)
+		db, err := connectLocation("export", dsnCfg)
 		if err != nil {
-			fmt.Printf("Failed to connect to database: %v\n", err)
-			return
+			return err
 		}
-
-		var tables []string
-		if tableName == "" {
-			// No table specified: export all tables
-			tables, err = getAllTableNames(dsnCfg.Driver, db)
-			if err != nil {
-				fmt.Printf("Failed to get table names: %v\n", err)
-				return
-			}
-			if len(tables) == 0 {
-				fmt.Println("No tables found in the database.")
-				return
-			}
-			fmt.Printf("Exporting all tables: %s\n", strings.Join(tables, ", "))
-		} else {
-			// Export only the specified table
-			tables = []string{tableName}
+		if opts.Schemas, opts.AllSchemas, err = schemaSelection(cmd, db); err != nil {
+			return err
 		}
 
-		// Export schema and/or data for each table
-		for _, tbl := range tables {
-			if !dataOnly {
-				schemaFile := filepath.Join(outputDir, fmt.Sprintf("%s_schema.sql", tbl))
-				if err := database.ExportSchemaSQL(db, tbl, schemaFile); err != nil {
-					fmt.Printf("Failed to export schema for table %s: %v\n", tbl, err)
-				} else {
-					fmt.Printf("Schema exported to %s\n", schemaFile)
-				}
-			}
-			if !schemaOnly {
-				dataFile := filepath.Join(outputDir, fmt.Sprintf("%s_data.sql", tbl))
-				if err := database.ExportDataSQL(db, dsnCfg.Driver, tbl, dataFile); err != nil {
-					fmt.Printf("Failed to export data for table %s: %v\n", tbl, err)
-				} else {
-					fmt.Printf("Data exported to %s\n", dataFile)
-				}
-				database.FixOrTruncateSQLFile(dataFile)
-			}
-		}
+		_, err = migration.NewExporter(db, opts).Export(cmd.Context())
+		return err
 	},
 }
 
 func init() {
-	exportCmd.Flags().StringP("table", "T", "", "Table name to export (if not set, exports all tables)")
+	exportCmd.Flags().StringSliceP("table", "T", nil, "Tables to export, comma separated or repeated (if not set, exports all tables)")
+	exportCmd.Flags().StringArray("include", nil, "Only export tables matching this glob, or regex with the re: prefix (repeatable)")
+	exportCmd.Flags().StringArray("exclude", nil, "Skip tables matching this glob, or regex with the re: prefix (repeatable)")
 	exportCmd.Flags().StringP("output", "o", "exported", "Output directory for exported files")
 	exportCmd.Flags().StringP("json", "j", "", "Specify json file  name to load (e.g., dsn.json,)")
+	addSchemaFlags(exportCmd)
+	exportCmd.Flags().StringSlice("objects", nil, "Object types to export: tables, extensions, types, views, triggers, routines, indexes (default all)")
 	exportCmd.Flags().Bool("schema-only", false, "Export only schema")
 	exportCmd.Flags().Bool("data-only", false, "Export only data")
+	exportCmd.Flags().Bool("sequences", false, "Include current sequence and auto-increment values in the schema files")
+	exportCmd.Flags().String("target-dialect", "", "Write the files for another database, such as mssql or oracle (default the export database's)")
+	exportCmd.Flags().Bool("flatten-partitions", false, "Write partitioned tables as plain tables holding the rows of all partitions")
+	exportCmd.Flags().Int("batch-size", 1000, "Rows per page and per INSERT statement when exporting data")
+	exportCmd.Flags().Bool("resume", false, "Resume an interrupted export from its checkpoint file")
+	exportCmd.Flags().String("checkpoint", "", "Checkpoint file tracking export progress (default <output>/.export_checkpoint.json)")
 
 	// Add the export command to your root command or application
 	goFrame.AddCommand(exportCmd)
@@ -2,141 +2,70 @@ package manager
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
-	"github.com/semay-cli/sql-migration/config"
 	"github.com/semay-cli/sql-migration/database"
+	"github.com/semay-cli/sql-migration/migration"
 	"github.com/spf13/cobra"
 )
 
-// getAllSQLTables scans the directory for *_schema.sql or *_data.sql files and extracts table names.
-func getAllSQLTables(dir string, suffix string) ([]string, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-
-	var tables []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		name := entry.Name()
-		if strings.HasSuffix(name, suffix) {
-			tableName := strings.TrimSuffix(name, suffix)
-			if tableName != "" {
-				tables = append(tables, tableName)
-			}
-		}
-	}
-
-	return tables, nil
-}
-
 var importCmd = &cobra.Command{
 	Use:   "import",
 	Short: "Import table schema and data from SQL files",
-	Long:  "Import the schema and data of a specified table, or all tables if not specified, from .sql files in the exported folder.",
-	Run: func(cmd *cobra.Command, args []string) {
-		tableName, _ := cmd.Flags().GetString("table")
-		inputDir, _ := cmd.Flags().GetString("input")
-		env, _ := cmd.Flags().GetString("json")
-		schemaOnly, _ := cmd.Flags().GetBool("schema-only")
-		dataOnly, _ := cmd.Flags().GetBool("data-only")
-
-		if inputDir == "" {
-			inputDir = "exported"
+	Long:  "Import the schema and data of a specified table, or all tables if not specified, from .sql files in the exported folder. Extensions, types and domains are imported first, then the schemas of all tables before any data, and views, triggers, routines and indexes after it; --schema-only, --data-only and --objects narrow the import.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := migration.ImportOptions{Logf: printf}
+		opts.InputDir, _ = cmd.Flags().GetString("input")
+		opts.SchemaOnly, _ = cmd.Flags().GetBool("schema-only")
+		opts.DataOnly, _ = cmd.Flags().GetBool("data-only")
+		opts.IfExists, _ = cmd.Flags().GetString("if-exists")
+		opts.Resume, _ = cmd.Flags().GetBool("resume")
+		opts.Checkpoint, _ = cmd.Flags().GetString("checkpoint")
+		opts.LockTimeout, _ = cmd.Flags().GetDuration("lock-timeout")
+		resetSequences, _ := cmd.Flags().GetBool("reset-sequences")
+		opts.SkipSequenceReset = !resetSequences
+
+		if opts.SchemaOnly && opts.DataOnly {
+			return fmt.Errorf("--schema-only and --data-only cannot be used together")
 		}
-
-		// Load DSN config (dsn.json)
-		configPath := "dsn.json"
-		if env != "" {
-			configPath = fmt.Sprintf("%v.json", env)
+		if !database.ValidIfExists(opts.IfExists) {
+			return fmt.Errorf("invalid --if-exists value %q (expected fail, skip, truncate or drop)", opts.IfExists)
 		}
-		dsnCfg, err := config.LoadDSNConfig(configPath)
+
+		dsnCfg, err := loadConfig(cmd)
 		if err != nil {
-			fmt.Printf("Failed to load DSN config: %v\n", err)
-			return
+			return err
+		}
+		if opts.Selection, err = tableSelection(cmd, dsnCfg); err != nil {
+			return err
 		}
+		opts.Objects, _ = cmd.Flags().GetStringSlice("objects")
 
 		// Connect to import database
-		db, err := database.ReturnSession("import", dsnCfg.Driver, dsnCfg.ImportDatabaseDSN)
+		db, err := connectLocation("import", dsnCfg)
 		if err != nil {
-			fmt.Printf("Failed to connect to database: %v\n", err)
-			return
-		}
-
-		var tables []string
-		if tableName == "" {
-			// No table specified: import all tables found in the input directory
-			if schemaOnly {
-				tables, err = getAllSQLTables(inputDir, "_schema.sql")
-				if err != nil {
-					fmt.Printf("Failed to get table names from input directory: %v\n", err)
-					return
-				}
-				if len(tables) == 0 {
-					fmt.Println("No schema SQL files found in the input directory.")
-					return
-				}
-				fmt.Printf("Importing all tables: %s\n", strings.Join(tables, ", "))
-			}
-
-			if dataOnly {
-				tables, err = getAllSQLTables(inputDir, "_data.sql")
-				if err != nil {
-					fmt.Printf("Failed to get table names from input directory: %v\n", err)
-					return
-				}
-				if len(tables) == 0 {
-					fmt.Println("No schema SQL files found in the input directory.")
-					return
-				}
-				fmt.Printf("Importing all tables: %s\n", strings.Join(tables, ", "))
-			}
-
-			// tables, err = getAllTableNames(dsnCfg.Driver, db)
-
-		} else {
-			tables = []string{tableName}
+			return err
 		}
 
-		for _, tbl := range tables {
-			if schemaOnly {
-				schemaFile := filepath.Join(inputDir, fmt.Sprintf("%s_schema.sql", tbl))
-				if _, err := os.Stat(schemaFile); err == nil {
-					fmt.Printf("Importing schema from %s\n", schemaFile)
-					if err := database.ImportSQLFile(db, schemaFile); err != nil {
-						fmt.Printf("Failed to import schema for table %s: %v\n", tbl, err)
-					} else {
-						fmt.Printf("Schema imported for table %s\n", tbl)
-					}
-				}
-			}
-			if dataOnly {
-				dataFile := filepath.Join(inputDir, fmt.Sprintf("%s_data.sql", tbl))
-				if _, err := os.Stat(dataFile); err == nil {
-					fmt.Printf("Importing data from %s\n", dataFile)
-					if err := database.ImportSQLFile(db, dataFile); err != nil {
-						fmt.Printf("Failed to import data for table %s: %v\n", tbl, err)
-					} else {
-						fmt.Printf("Data imported for table %s\n", tbl)
-					}
-				}
-			}
-		}
+		_, err = migration.NewImporter(db, opts).Import(cmd.Context())
+		return err
 	},
 }
 
 func init() {
-	importCmd.Flags().StringP("table", "T", "", "Table name to import (if not set, imports all tables found in input directory)")
+	importCmd.Flags().StringSliceP("table", "T", nil, "Tables to import, comma separated or repeated (if not set, imports all tables found in input directory)")
+	importCmd.Flags().StringArray("include", nil, "Only import tables matching this glob, or regex with the re: prefix (repeatable)")
+	importCmd.Flags().StringArray("exclude", nil, "Skip tables matching this glob, or regex with the re: prefix (repeatable)")
 	importCmd.Flags().StringP("input", "i", "exported", "Input directory for SQL files")
 	importCmd.Flags().StringP("json", "j", "", "Specify json file name to load (e.g., dsn.json, defaults to dsn.json)")
-	importCmd.Flags().Bool("schema-only", false, "Import only schema")
-	importCmd.Flags().Bool("data-only", false, "Import only data")
+	importCmd.Flags().StringSlice("objects", nil, "Object types to import: tables, extensions, types, views, triggers, routines, indexes (default all)")
+	importCmd.Flags().Bool("schema-only", false, "Import only schema (default imports schema then data)")
+	importCmd.Flags().Bool("data-only", false, "Import only data (default imports schema then data)")
+	importCmd.Flags().Bool("resume", false, "Resume an interrupted import from its checkpoint file")
+	importCmd.Flags().String("checkpoint", "", "Checkpoint file tracking import progress (default <input>/.import_checkpoint.json)")
+	importCmd.Flags().Bool("reset-sequences", true, "Move sequences and auto-increment counters past the imported ids")
+	importCmd.Flags().Duration("lock-timeout", 30*time.Second, "How long to wait for another import or migration holding the database lock")
+	importCmd.Flags().String("if-exists", database.IfExistsFail, "What to do when a target table already exists: fail, skip, truncate or drop")
 
 	// Add the import command to your root command or application
 	goFrame.AddCommand(importCmd)
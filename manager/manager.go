@@ -1,10 +1,17 @@
 package manager
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
 
+	"github.com/semay-cli/sql-migration/config"
+	"github.com/semay-cli/sql-migration/database"
+	"github.com/semay-cli/sql-migration/migration"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 )
 
 var (
@@ -19,9 +26,101 @@ var (
 )
 
 func Execute() {
-	if err := goFrame.Execute(); err != nil {
+	// Interrupting an export or import stops it after saving its checkpoint
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := goFrame.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
+
+// printf prints the progress messages of the migration package.
+func printf(format string, args ...any) {
+	fmt.Printf(format, args...)
+}
+
+// loadConfig loads the database settings of a command. --json <name> selects
+// <name>.json as before; otherwise the YAML config (--config, or
+// sql-migration.yaml when present) is loaded with the --profile settings, and
+// dsn.json is the fallback.
+func loadConfig(cmd *cobra.Command) (*config.DSNConfig, error) {
+	env, _ := cmd.Flags().GetString("json")
+	configFile, _ := cmd.Flags().GetString("config")
+	profile, _ := cmd.Flags().GetString("profile")
+
+	var (
+		dsnCfg *config.DSNConfig
+		err    error
+	)
+	switch {
+	case env != "":
+		dsnCfg, err = config.LoadDSNConfig(fmt.Sprintf("%s.json", env))
+	case configFile != "" && strings.HasSuffix(configFile, ".json"):
+		dsnCfg, err = config.LoadDSNConfig(configFile)
+	case configFile != "":
+		dsnCfg, err = config.LoadProfileConfig(configFile, profile)
+	default:
+		if _, statErr := os.Stat(config.DefaultConfigFile); statErr == nil {
+			dsnCfg, err = config.LoadProfileConfig(config.DefaultConfigFile, profile)
+		} else if profile != "" {
+			return nil, fmt.Errorf("--profile needs a YAML config file (%s or --config)", config.DefaultConfigFile)
+		} else {
+			dsnCfg, err = config.LoadDSNConfig("dsn.json")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load DSN config: %w", err)
+	}
+	return dsnCfg, nil
+}
+
+// tableSelection returns the tables a command works on: the --table list when
+// given, otherwise the tables matching the --include/--exclude flags and the
+// include/exclude lists of the config that are not marked with skip.
+func tableSelection(cmd *cobra.Command, dsnCfg *config.DSNConfig) (migration.Selection, error) {
+	tableNames, _ := cmd.Flags().GetStringSlice("table")
+	include, _ := cmd.Flags().GetStringArray("include")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
+
+	if len(tableNames) > 0 && (len(include) > 0 || len(exclude) > 0) {
+		return migration.Selection{}, fmt.Errorf("--table cannot be combined with --include or --exclude")
+	}
+	selection := migration.Selection{Tables: tableNames, Settings: dsnCfg.Tables}
+	if len(tableNames) == 0 {
+		selection.Include = append(include, dsnCfg.Include...)
+		selection.Exclude = append(exclude, dsnCfg.Exclude...)
+	}
+	return selection, nil
+}
+
+// connectLocation opens the "export" or "import" database of the config.
+func connectLocation(location string, dsnCfg *config.DSNConfig) (*gorm.DB, error) {
+	var (
+		dsn string
+		err error
+	)
+	switch location {
+	case "export":
+		dsn, err = dsnCfg.ExportDSN()
+	case "import":
+		dsn, err = dsnCfg.ImportDSN()
+	default:
+		return nil, fmt.Errorf("unknown database %q (use export or import)", location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.ReturnSession(location, dsnCfg.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s database: %w", location, err)
+	}
+	return db, nil
+}
+
+func init() {
+	goFrame.PersistentFlags().String("config", "", fmt.Sprintf("YAML config file with profiles (defaults to %s when present, else dsn.json)", config.DefaultConfigFile))
+	goFrame.PersistentFlags().String("profile", "", "Profile of the YAML config to use")
+}
@@ -0,0 +1,487 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/semay-cli/sql-migration/database"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// migrateCmd groups the versioned migration commands.
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply versioned migrations to the import database",
+	Long:  "Manage numbered NNNN_name.up.sql / NNNN_name.down.sql migration files and apply them in order to the import database, recording applied versions in the schema_migrations table.",
+}
+
+var migrateNewCmd = &cobra.Command{
+	Use:   "new <name>",
+	Short: "Create the next pair of empty up and down migration files",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+
+		m, err := database.NewMigration(dir, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to create migration: %w", err)
+		}
+		fmt.Printf("Created %s\n", m.UpFile)
+		fmt.Printf("Created %s\n", m.DownFile)
+		return nil
+	},
+}
+
+var migrateGenerateCmd = &cobra.Command{
+	Use:   "generate <name>",
+	Short: "Write a migration that brings the import database to the desired schema",
+	Long:  "Compare the live import database with the desired schema (the export database or a directory of *_schema.sql files, see --from) and write the next up/down migration pair with the CREATE, ALTER and DROP statements for the target dialect.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		from, _ := cmd.Flags().GetString("from")
+		tableName, _ := cmd.Flags().GetString("table")
+		dialect, _ := cmd.Flags().GetString("dialect")
+
+		dsnCfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if dialect == "" {
+			dialect = dsnCfg.Driver
+		}
+		sqlDialect, err := database.LookupDialect(dialect)
+		if err != nil {
+			return err
+		}
+		if from == "import" {
+			return fmt.Errorf("--from must be the export database or a schema directory, not the import database")
+		}
+
+		desired, err := loadSchemaFrom(cmd, from, dsnCfg, tableName)
+		if err != nil {
+			return fmt.Errorf("failed to load desired schema: %w", err)
+		}
+		current, err := loadSchemaFrom(cmd, "import", dsnCfg, tableName)
+		if err != nil {
+			return fmt.Errorf("failed to load import database schema: %w", err)
+		}
+		// The history table is managed by migrate itself
+		historyTable := database.AppliedMigration{}.TableName()
+		delete(desired.Tables, historyTable)
+		delete(current.Tables, historyTable)
+
+		diff := database.DiffSchemas(desired, current)
+		if diff.Empty() {
+			fmt.Println("No schema differences found, no migration written.")
+			return nil
+		}
+		diff.WriteReport(os.Stdout)
+
+		up := diff.AlterSQL(sqlDialect)
+		down := database.DiffSchemas(current, desired).AlterSQL(sqlDialect)
+		m, err := database.WriteMigration(dir, args[0], up, down)
+		if err != nil {
+			return fmt.Errorf("failed to write migration: %w", err)
+		}
+		fmt.Printf("Created %s (%d statement(s))\n", m.UpFile, len(up))
+		fmt.Printf("Created %s (%d statement(s))\n", m.DownFile, len(down))
+		return nil
+	},
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up [N]",
+	Short: "Apply pending migrations (all, or the next N)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, err := migrationCount(args, -1)
+		if err != nil {
+			return err
+		}
+		state, err := openMigrations(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer state.unlock()
+		if err := database.VerifyMigrationChecksums(state.migrations, state.applied); err != nil {
+			return err
+		}
+		return applyPending(state, func(m database.Migration, n int) bool {
+			return limit < 0 || n < limit
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Revert the last applied migration (or the last N)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, err := migrationCount(args, 1)
+		if err != nil {
+			return err
+		}
+		state, err := openMigrations(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer state.unlock()
+		if err := database.VerifyMigrationChecksums(state.migrations, state.applied); err != nil {
+			return err
+		}
+		return revertApplied(state, func(a database.AppliedMigration, n int) bool {
+			return n < limit
+		})
+	},
+}
+
+var migrateGotoCmd = &cobra.Command{
+	Use:   "goto <version>",
+	Short: "Apply or revert migrations until the given version is the latest applied",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		state, err := openMigrations(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer state.unlock()
+		if err := database.VerifyMigrationChecksums(state.migrations, state.applied); err != nil {
+			return err
+		}
+
+		known := version == 0
+		for _, m := range state.migrations {
+			if m.Version == version {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("no migration with version %d in the migrations directory", version)
+		}
+
+		if n := len(state.applied); n > 0 && state.applied[n-1].Version > version {
+			return revertApplied(state, func(a database.AppliedMigration, n int) bool {
+				return a.Version > version
+			})
+		}
+		return applyPending(state, func(m database.Migration, n int) bool {
+			return m.Version <= version
+		})
+	},
+}
+
+var migrateBaselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Adopt migrations on an existing database without running them",
+	Long:  "Snapshot the current schema of the import database into 0000_baseline.sql (kept as is when the file already exists) and record it, together with every migration up to --version, as applied without executing them.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		version, _ := cmd.Flags().GetInt64("version")
+		if version < 0 {
+			return fmt.Errorf("invalid version %d", version)
+		}
+
+		state, err := openMigrations(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer state.unlock()
+		if len(state.applied) > 0 {
+			return fmt.Errorf("the import database already has %d applied migration(s), baseline is only for databases without history", len(state.applied))
+		}
+
+		known := version == 0
+		for _, m := range state.migrations {
+			if m.Version == version {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("no migration with version %d in the migrations directory", version)
+		}
+
+		baselinePath := filepath.Join(dir, database.BaselineFile)
+		if _, err := os.Stat(baselinePath); err == nil {
+			covered, err := database.BaselineVersion(database.Migration{UpFile: baselinePath})
+			if err != nil {
+				return err
+			}
+			if covered != version {
+				return fmt.Errorf("existing %s was taken at version %d, not %d", baselinePath, covered, version)
+			}
+			fmt.Printf("Using existing %s\n", baselinePath)
+		} else {
+			tables, err := getAllTableNames(cmd, state.db)
+			if err != nil {
+				return fmt.Errorf("failed to get table names: %w", err)
+			}
+			historyTable := database.AppliedMigration{}.TableName()
+			var schemaTables []string
+			for _, tbl := range tables {
+				if tbl != historyTable {
+					schemaTables = append(schemaTables, tbl)
+				}
+			}
+			if _, err := database.WriteBaseline(state.db, dir, schemaTables, version); err != nil {
+				return fmt.Errorf("failed to write baseline: %w", err)
+			}
+			fmt.Printf("Created %s (%d table(s))\n", baselinePath, len(schemaTables))
+		}
+
+		migrations, err := database.LoadMigrations(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read migrations: %w", err)
+		}
+		var baseline []database.Migration
+		for _, m := range migrations {
+			if m.Version <= version {
+				baseline = append(baseline, m)
+			}
+		}
+		if err := database.MarkMigrationsApplied(state.db, baseline); err != nil {
+			return fmt.Errorf("failed to record baseline: %w", err)
+		}
+		for _, m := range baseline {
+			fmt.Printf("Marked %04d_%s as applied\n", m.Version, m.Name)
+		}
+		return nil
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List migrations with their applied state",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		state, err := openMigrations(cmd, false)
+		if err != nil {
+			return err
+		}
+		migrations, applied := state.migrations, state.applied
+
+		appliedByVersion := make(map[int64]database.AppliedMigration)
+		for _, a := range applied {
+			appliedByVersion[a.Version] = a
+		}
+		files := make(map[int64]bool)
+
+		pending := 0
+		fmt.Printf("%-8s %-10s %-20s %s\n", "VERSION", "STATE", "APPLIED AT", "NAME")
+		for _, m := range migrations {
+			files[m.Version] = true
+			a, ok := appliedByVersion[m.Version]
+			if !ok {
+				pending++
+				fmt.Printf("%04d     %-10s %-20s %s\n", m.Version, "pending", "", m.Name)
+				continue
+			}
+			state := "applied"
+			if sum, err := m.Checksum(); err != nil {
+				return err
+			} else if a.Checksum != "" && sum != a.Checksum {
+				state = "modified"
+			}
+			fmt.Printf("%04d     %-10s %-20s %s\n", m.Version, state, a.AppliedAt.Format("2006-01-02 15:04:05"), m.Name)
+		}
+		for _, a := range applied {
+			if !files[a.Version] {
+				fmt.Printf("%04d     %-10s %-20s %s\n", a.Version, "missing", a.AppliedAt.Format("2006-01-02 15:04:05"), a.Name)
+			}
+		}
+		fmt.Printf("%d applied, %d pending\n", len(applied), pending)
+		return nil
+	},
+}
+
+// migrationCount parses the optional [N] argument of up and down.
+func migrationCount(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid migration count %q", args[0])
+	}
+	return n, nil
+}
+
+// migrationState is the import database with its migration files and history.
+type migrationState struct {
+	db         *gorm.DB
+	migrations []database.Migration
+	applied    []database.AppliedMigration
+	// unlock releases the database lock; it does nothing for read-only commands.
+	unlock func()
+}
+
+// openMigrations loads the migration files and the history of the import
+// database. With lock set, the database lock is taken before the history is
+// read, so concurrent runs see each other's changes.
+func openMigrations(cmd *cobra.Command, lock bool) (*migrationState, error) {
+	dir, _ := cmd.Flags().GetString("dir")
+
+	dsnCfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := database.LoadMigrations(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	db, err := connectLocation("import", dsnCfg)
+	if err != nil {
+		return nil, err
+	}
+	state := &migrationState{db: db, migrations: migrations, unlock: func() {}}
+	if lock {
+		if state.unlock, err = lockImportDatabase(cmd, db); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := database.EnsureMigrationsTable(db); err != nil {
+		state.unlock()
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	state.applied, err = database.AppliedMigrations(db)
+	if err != nil {
+		state.unlock()
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return state, nil
+}
+
+// lockImportDatabase takes the migration lock on the import database, waiting
+// up to --lock-timeout, and returns the function releasing it.
+func lockImportDatabase(cmd *cobra.Command, db *gorm.DB) (func(), error) {
+	timeout, _ := cmd.Flags().GetDuration("lock-timeout")
+	if timeout < 0 {
+		return nil, fmt.Errorf("--lock-timeout must not be negative")
+	}
+
+	lock, err := database.AcquireLock(db, database.LockName, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock import database: %w", err)
+	}
+	return func() {
+		if err := lock.Release(); err != nil {
+			fmt.Printf("Failed to release lock on import database: %v\n", err)
+		}
+	}, nil
+}
+
+// applyPending applies, in ascending order, the pending migrations accepted by
+// keep, where n is the number already applied by this call.
+func applyPending(state *migrationState, keep func(m database.Migration, n int) bool) error {
+	done := make(map[int64]bool)
+	for _, a := range state.applied {
+		done[a.Version] = true
+	}
+
+	n := 0
+	for _, m := range state.migrations {
+		if done[m.Version] {
+			continue
+		}
+		if !keep(m, n) {
+			break
+		}
+		fmt.Printf("Applying %04d_%s...\n", m.Version, m.Name)
+		if err := database.ApplyMigration(state.db, m); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		n++
+
+		// The baseline already contains the migrations it was taken at
+		if m.Version == 0 {
+			if err := markBaselineCovered(state, m, done); err != nil {
+				return err
+			}
+		}
+	}
+	if n == 0 {
+		fmt.Println("No migrations to apply")
+	} else {
+		fmt.Printf("Applied %d migration(s)\n", n)
+	}
+	return nil
+}
+
+// markBaselineCovered records the migrations contained in the applied
+// baseline as applied, and adds them to done.
+func markBaselineCovered(state *migrationState, baseline database.Migration, done map[int64]bool) error {
+	covered, err := database.BaselineVersion(baseline)
+	if err != nil {
+		return err
+	}
+	var skipped []database.Migration
+	for _, m := range state.migrations {
+		if m.Version > 0 && m.Version <= covered && !done[m.Version] {
+			skipped = append(skipped, m)
+		}
+	}
+	if err := database.MarkMigrationsApplied(state.db, skipped); err != nil {
+		return fmt.Errorf("failed to record migrations contained in the baseline: %w", err)
+	}
+	for _, m := range skipped {
+		done[m.Version] = true
+		fmt.Printf("Marked %04d_%s as applied (contained in the baseline)\n", m.Version, m.Name)
+	}
+	return nil
+}
+
+// revertApplied reverts, in descending order, the applied migrations accepted
+// by keep, where n is the number already reverted by this call.
+func revertApplied(state *migrationState, keep func(a database.AppliedMigration, n int) bool) error {
+	files := make(map[int64]database.Migration)
+	for _, m := range state.migrations {
+		files[m.Version] = m
+	}
+
+	n := 0
+	for i := len(state.applied) - 1; i >= 0; i-- {
+		a := state.applied[i]
+		if !keep(a, n) {
+			break
+		}
+		m, ok := files[a.Version]
+		if !ok {
+			return fmt.Errorf("migration %04d_%s is applied but its files are missing", a.Version, a.Name)
+		}
+		fmt.Printf("Reverting %04d_%s...\n", m.Version, m.Name)
+		if err := database.RevertMigration(state.db, m); err != nil {
+			return fmt.Errorf("reverting %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		n++
+	}
+	if n > 0 {
+		fmt.Printf("Reverted %d migration(s)\n", n)
+	}
+	return nil
+}
+
+func init() {
+	migrateCmd.PersistentFlags().String("dir", "migrations", "Directory holding the NNNN_name.up.sql / .down.sql files")
+	migrateCmd.PersistentFlags().Duration("lock-timeout", 30*time.Second, "How long to wait for another import or migration holding the database lock")
+	migrateCmd.PersistentFlags().StringP("json", "j", "", "Specify json file name to load (e.g., dsn.json, defaults to dsn.json)")
+
+	migrateGenerateCmd.Flags().String("from", "export", "Desired schema: export, or a directory of *_schema.sql files")
+	migrateGenerateCmd.Flags().StringP("table", "T", "", "Only compare this table")
+	migrateGenerateCmd.Flags().String("dialect", "", "Dialect of the generated statements (defaults to the configured driver)")
+
+	migrateBaselineCmd.Flags().Int64("version", 0, "Record every migration up to this version as applied")
+
+	migrateCmd.AddCommand(migrateBaselineCmd, migrateNewCmd, migrateGenerateCmd, migrateUpCmd, migrateDownCmd, migrateGotoCmd, migrateStatusCmd)
+	goFrame.AddCommand(migrateCmd)
+}
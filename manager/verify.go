@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/semay-cli/sql-migration/database"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd compares the export and import databases table by table.
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare row counts and checksums between the export and import databases",
+	Long:  "Connect to both databases from the DSN config and, for a specified table or all tables, compare row counts and an order-independent checksum computed in primary key chunks.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tableName, _ := cmd.Flags().GetString("table")
+		chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+		showRanges, _ := cmd.Flags().GetBool("show-ranges")
+
+		dsnCfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		source, err := connectLocation("export", dsnCfg)
+		if err != nil {
+			return err
+		}
+		target, err := connectLocation("import", dsnCfg)
+		if err != nil {
+			return err
+		}
+
+		var tables []string
+		if tableName == "" {
+			tables, err = getAllTableNames(cmd, source)
+			if err != nil {
+				return fmt.Errorf("failed to get table names: %w", err)
+			}
+		} else {
+			tables = []string{tableName}
+		}
+
+		targetTables, err := getAllTableNames(cmd, target)
+		if err != nil {
+			return fmt.Errorf("failed to get table names of import database: %w", err)
+		}
+		inTarget := make(map[string]bool)
+		for _, tbl := range targetTables {
+			inTarget[tbl] = true
+		}
+
+		mismatched := 0
+		for _, tbl := range tables {
+			if !inTarget[tbl] {
+				fmt.Printf("MISSING   %s: table does not exist in the import database\n", tbl)
+				mismatched++
+				continue
+			}
+
+			result, err := database.VerifyTable(source, target, tbl, chunkSize)
+			if err != nil {
+				fmt.Printf("ERROR     %s: %v\n", tbl, err)
+				mismatched++
+				continue
+			}
+			if result.Matches() {
+				fmt.Printf("OK        %s: %d rows, checksum %016x\n", tbl, result.SourceRows, result.SourceChecksum)
+				continue
+			}
+
+			mismatched++
+			fmt.Printf("MISMATCH  %s: source %d rows (checksum %016x), target %d rows (checksum %016x), %d differing chunk(s)\n",
+				tbl, result.SourceRows, result.SourceChecksum, result.TargetRows, result.TargetChecksum, len(result.Mismatches))
+			if showRanges {
+				for _, r := range result.Mismatches {
+					fmt.Printf("          keys %s\n", r)
+				}
+			}
+		}
+
+		if tableName == "" {
+			var extra []string
+			inSource := make(map[string]bool)
+			for _, tbl := range tables {
+				inSource[tbl] = true
+			}
+			for _, tbl := range targetTables {
+				if !inSource[tbl] {
+					extra = append(extra, tbl)
+				}
+			}
+			if len(extra) > 0 {
+				fmt.Printf("Tables only in the import database: %s\n", strings.Join(extra, ", "))
+			}
+		}
+
+		fmt.Printf("Verified %d table(s), %d mismatch(es)\n", len(tables), mismatched)
+		if mismatched > 0 {
+			return fmt.Errorf("verification failed for %d table(s)", mismatched)
+		}
+		return nil
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringP("table", "T", "", "Table name to verify (if not set, verifies all tables)")
+	verifyCmd.Flags().StringP("json", "j", "", "Specify json file name to load (e.g., dsn.json, defaults to dsn.json)")
+	addSchemaFlags(verifyCmd)
+	verifyCmd.Flags().Int("chunk-size", 1000, "Rows per checksum chunk")
+	verifyCmd.Flags().Bool("show-ranges", false, "Print the primary key ranges whose rows differ")
+
+	goFrame.AddCommand(verifyCmd)
+}
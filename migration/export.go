@@ -0,0 +1,294 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/semay-cli/sql-migration/database"
+	"gorm.io/gorm"
+)
+
+// ExportOptions configures an Exporter.
+type ExportOptions struct {
+	Selection
+
+	// OutputDir receives the <table>_schema.sql and <table>_data.sql files
+	// and the object files (see ObjectFileSuffix), exported by default.
+	OutputDir string
+	// Schemas lists the schemas (MySQL databases, SQLite attached databases)
+	// to read tables from, the current one by default. AllSchemas reads every
+	// user schema.
+	Schemas    []string
+	AllSchemas bool
+	// SchemaOnly and DataOnly limit the export to one kind of file.
+	SchemaOnly bool
+	DataOnly   bool
+	// Sequences adds the current sequence and auto-increment values to the
+	// schema files.
+	Sequences bool
+	// TargetDialect writes the files in another dialect than the source's,
+	// such as the output-only mssql and oracle: schemas are rendered from the
+	// introspected tables with translated types instead of copied. Only
+	// tables are exported then, with their indexes in the schema files, as
+	// the definitions of other objects cannot be translated.
+	TargetDialect string
+	// FlattenPartitions writes partitioned tables as plain tables holding
+	// the rows of all their partitions, for databases without partitioning.
+	// Tables written for a target dialect are always plain.
+	FlattenPartitions bool
+	// BatchSize is the number of rows per page and per INSERT statement,
+	// 1000 by default. A table's BatchSize setting overrides it.
+	BatchSize int
+	// Resume continues from the checkpoint of an interrupted export instead
+	// of starting over. Checkpoint defaults to
+	// <OutputDir>/.export_checkpoint.json.
+	Resume     bool
+	Checkpoint string
+	// Logf receives progress messages.
+	Logf Logf
+}
+
+// ExportResult reports an export.
+type ExportResult struct {
+	Tables []TableResult
+	// Objects reports the object files, one per kind and name.
+	Objects []TableResult
+	// Checkpoint is the checkpoint file left behind when tables failed.
+	Checkpoint string
+}
+
+// Failed returns the number of tables and object files whose export failed.
+func (r *ExportResult) Failed() int {
+	return failed(r.Tables, r.Objects)
+}
+
+// Exporter writes the schema and data of tables to SQL files.
+type Exporter struct {
+	db   *gorm.DB
+	opts ExportOptions
+}
+
+// NewExporter returns an Exporter reading from db.
+func NewExporter(db *gorm.DB, opts ExportOptions) *Exporter {
+	if opts.OutputDir == "" {
+		opts.OutputDir = "exported"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.Checkpoint == "" {
+		opts.Checkpoint = filepath.Join(opts.OutputDir, ".export_checkpoint.json")
+	}
+	return &Exporter{db: db, opts: opts}
+}
+
+// Export exports the selected tables and objects. Tables that fail are reported in the
+// result and the remaining tables are still exported; the error is then
+// non-nil as well, and the checkpoint allows resuming. Cancelling ctx stops
+// the export after saving the progress of the current table.
+func (e *Exporter) Export(ctx context.Context) (*ExportResult, error) {
+	opts := e.opts
+	logf := opts.Logf
+	db := e.db.WithContext(ctx)
+
+	if opts.SchemaOnly && opts.DataOnly {
+		return nil, fmt.Errorf("schema-only and data-only cannot be used together")
+	}
+	if opts.TargetDialect != "" {
+		if _, err := database.LookupDialect(opts.TargetDialect); err != nil {
+			return nil, err
+		}
+		if opts.Sequences {
+			return nil, fmt.Errorf("sequences cannot be exported with a target dialect")
+		}
+		for _, t := range opts.Objects {
+			if t != database.ObjectTables {
+				return nil, fmt.Errorf("only tables can be exported with a target dialect, not %s", t)
+			}
+		}
+		opts.Objects = []string{database.ObjectTables}
+	}
+	types, err := opts.objectTypes()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	allTables, err := database.ListTables(db, opts.Schemas, opts.AllSchemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table names: %w", err)
+	}
+	var tables []string
+	if types[database.ObjectTables] {
+		if tables, err = opts.selectTables(allTables, logf); err != nil {
+			return nil, err
+		}
+	}
+	var objects []database.SchemaObject
+	if !opts.DataOnly && (len(types) > 1 || !types[database.ObjectTables]) {
+		allObjects, err := database.ListObjects(db, opts.Schemas, opts.AllSchemas)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list views, triggers, routines and indexes: %w", err)
+		}
+		objects = opts.selectObjects(allObjects, types)
+		tables = withoutObjects(tables, objects)
+	}
+	if len(tables) == 0 && len(objects) == 0 {
+		return nil, fmt.Errorf("no tables found in the database")
+	}
+	if len(tables) > 0 {
+		logf.printf("Exporting tables: %s\n", strings.Join(tables, ", "))
+	}
+
+	// Load previous progress when resuming, otherwise start from scratch
+	checkpoint := database.NewCheckpoint(opts.Checkpoint)
+	if opts.Resume {
+		checkpoint, err = database.LoadCheckpoint(opts.Checkpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		logf.printf("Resuming export from checkpoint %s\n", opts.Checkpoint)
+	} else if err := checkpoint.Remove(); err != nil {
+		return nil, fmt.Errorf("failed to remove stale checkpoint: %w", err)
+	}
+
+	result := &ExportResult{}
+	for _, tbl := range tables {
+		if err := ctx.Err(); err != nil {
+			result.Checkpoint = opts.Checkpoint
+			return result, err
+		}
+		table, err := e.exportTable(ctx, db, tbl, checkpoint)
+		result.Tables = append(result.Tables, table)
+		if err != nil {
+			result.Checkpoint = opts.Checkpoint
+			return result, err
+		}
+	}
+	if opts.TargetDialect != "" && !opts.DataOnly {
+		if err := e.exportForeignKeys(db, result.Tables); err != nil {
+			result.Checkpoint = opts.Checkpoint
+			return result, err
+		}
+	}
+	if len(objects) > 0 {
+		logf.printf("Exporting objects: %s\n", describeObjects(objects))
+		result.Objects, err = e.exportObjects(ctx, objects, checkpoint)
+		if err != nil {
+			result.Checkpoint = opts.Checkpoint
+			return result, err
+		}
+	}
+
+	if n := result.Failed(); n > 0 {
+		result.Checkpoint = opts.Checkpoint
+		logf.printf("Progress saved to %s, rerun with --resume to continue\n", opts.Checkpoint)
+		return result, fmt.Errorf("%d export(s) failed", n)
+	}
+	if err := checkpoint.Remove(); err != nil {
+		logf.printf("Failed to remove checkpoint %s: %v\n", opts.Checkpoint, err)
+	}
+	return result, nil
+}
+
+// exportForeignKeys writes the foreign keys of the tables exported for the
+// target dialect to ForeignKeysFile, as a table may reference one whose
+// schema file comes later. The error is fatal to the export.
+func (e *Exporter) exportForeignKeys(db *gorm.DB, tables []TableResult) error {
+	var names []string
+	for _, table := range tables {
+		if table.Err == nil {
+			names = append(names, table.Table)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	file := filepath.Join(e.opts.OutputDir, ForeignKeysFile)
+	written, err := database.ExportTranslatedForeignKeysSQL(db, names, file, e.opts.TargetDialect)
+	if err != nil {
+		return fmt.Errorf("failed to export foreign keys: %w", err)
+	}
+	if written {
+		e.opts.Logf.printf("Foreign keys exported to %s\n", file)
+	}
+	return nil
+}
+
+// exportTable exports the schema and/or data of tbl not yet in checkpoint.
+// Table errors are recorded in the result; the returned error is fatal to
+// the export.
+func (e *Exporter) exportTable(ctx context.Context, db *gorm.DB, tbl string, checkpoint *database.Checkpoint) (TableResult, error) {
+	opts := e.opts
+	logf := opts.Logf
+	result := TableResult{Table: tbl}
+	progress := checkpoint.Table(tbl)
+
+	if !opts.DataOnly {
+		result.SchemaFile = filepath.Join(opts.OutputDir, tbl+SchemaFileSuffix)
+		if progress.SchemaDone {
+			result.Skipped = true
+		} else {
+			var err error
+			switch {
+			case opts.TargetDialect != "":
+				err = database.ExportTranslatedSchemaSQL(db, tbl, result.SchemaFile, opts.TargetDialect)
+			case opts.FlattenPartitions:
+				err = database.ExportFlatSchemaSQL(db, tbl, result.SchemaFile)
+			default:
+				err = database.ExportSchemaSQL(db, tbl, result.SchemaFile)
+			}
+			if err == nil && opts.Sequences {
+				err = database.ExportSequencesSQL(db, tbl, result.SchemaFile)
+			}
+			if err != nil {
+				logf.printf("Failed to export schema for table %s: %v\n", tbl, err)
+				result.fail(fmt.Errorf("schema of %s: %w", tbl, err))
+			} else {
+				logf.printf("Schema exported to %s\n", result.SchemaFile)
+				progress.SchemaDone = true
+				if err := checkpoint.Save(); err != nil {
+					return result, fmt.Errorf("failed to save checkpoint: %w", err)
+				}
+			}
+		}
+	}
+
+	if !opts.SchemaOnly {
+		result.DataFile = filepath.Join(opts.OutputDir, tbl+DataFileSuffix)
+		if progress.DataDone {
+			result.Skipped = true
+			result.Rows = progress.Rows
+			return result, nil
+		}
+		if progress.Rows > 0 {
+			logf.printf("Resuming data export of table %s after %d rows\n", tbl, progress.Rows)
+		}
+		batchSize := opts.BatchSize
+		if size := opts.table(tbl).BatchSize; size > 0 {
+			batchSize = size
+		}
+		err := database.ExportDataSQL(db, opts.TargetDialect, tbl, result.DataFile, batchSize, progress, func() error {
+			if err := checkpoint.Save(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		})
+		result.Rows = progress.Rows
+		if err != nil {
+			logf.printf("Failed to export data for table %s: %v\n", tbl, err)
+			result.fail(fmt.Errorf("data of %s: %w", tbl, err))
+		} else {
+			logf.printf("Data exported to %s (%d rows)\n", result.DataFile, progress.Rows)
+			if err := checkpoint.Save(); err != nil {
+				return result, fmt.Errorf("failed to save checkpoint: %w", err)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package migration
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/semay-cli/sql-migration/database"
+)
+
+// TestExportCheckpointSaveError checks that an export stops when its
+// checkpoint cannot be saved, rather than carrying on with progress it
+// could not resume from.
+func TestExportCheckpointSaveError(t *testing.T) {
+	tmp := t.TempDir()
+	db := openTestDB(t, "sqlite", filepath.Join(tmp, "source.db"),
+		"CREATE TABLE empty (id INTEGER PRIMARY KEY)",
+		"CREATE VIEW empty_ids AS SELECT id FROM empty",
+	)
+
+	tests := []struct {
+		name string
+		opts ExportOptions
+	}{
+		{"schema", ExportOptions{SchemaOnly: true, Selection: Selection{Objects: []string{database.ObjectTables}}}},
+		{"data", ExportOptions{DataOnly: true}},
+		{"objects", ExportOptions{Selection: Selection{Objects: []string{database.ObjectViews}}}},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.OutputDir = filepath.Join(tmp, tt.name)
+		opts.Checkpoint = filepath.Join(tmp, "missing", tt.name+".json")
+		result, err := NewExporter(db, opts).Export(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "failed to save checkpoint") {
+			t.Errorf("%s: error %v, want the checkpoint save failure", tt.name, err)
+			continue
+		}
+		if result == nil || result.Checkpoint != opts.Checkpoint {
+			t.Errorf("%s: result %+v, want the checkpoint path reported", tt.name, result)
+		}
+	}
+}
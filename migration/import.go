@@ -0,0 +1,473 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/semay-cli/sql-migration/database"
+	"gorm.io/gorm"
+)
+
+// ImportOptions configures an Importer.
+type ImportOptions struct {
+	Selection
+
+	// InputDir holds the <table>_schema.sql and <table>_data.sql files and
+	// the object files (see ObjectFileSuffix), exported by default.
+	InputDir string
+	// SchemaOnly and DataOnly limit the import to one kind of file.
+	SchemaOnly bool
+	DataOnly   bool
+	// IfExists decides what happens to target tables that already exist:
+	// database.IfExistsFail (the default), IfExistsSkip, IfExistsTruncate or
+	// IfExistsDrop. A table's IfExists setting overrides it. Existing views,
+	// triggers, routines and indexes are dropped by both truncate and drop.
+	IfExists string
+	// SkipSequenceReset leaves sequences and auto-increment counters where
+	// the data files put them instead of moving them past the imported ids.
+	SkipSequenceReset bool
+	// LockTimeout is how long to wait for another import or migration
+	// holding the database lock. Zero tries once.
+	LockTimeout time.Duration
+	// Resume continues from the checkpoint of an interrupted import instead
+	// of starting over. Checkpoint defaults to
+	// <InputDir>/.import_checkpoint.json.
+	Resume     bool
+	Checkpoint string
+	// Logf receives progress messages.
+	Logf Logf
+}
+
+// ImportResult reports an import.
+type ImportResult struct {
+	Tables []TableResult
+	// Objects reports the object files, one per kind and name.
+	Objects []TableResult
+	// SchemasImported, DataImported and ObjectsImported count the files
+	// imported by this run.
+	SchemasImported int
+	DataImported    int
+	ObjectsImported int
+	// Checkpoint is the checkpoint file left behind when tables failed.
+	Checkpoint string
+}
+
+// Failed returns the number of tables and object files whose import failed.
+func (r *ImportResult) Failed() int {
+	return failed(r.Tables, r.Objects)
+}
+
+// Skipped returns the number of tables and object files left alone.
+func (r *ImportResult) Skipped() int {
+	n := 0
+	for _, results := range [][]TableResult{r.Tables, r.Objects} {
+		for _, t := range results {
+			if t.Skipped {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Importer loads SQL files written by an Exporter into a database.
+type Importer struct {
+	db   *gorm.DB
+	opts ImportOptions
+}
+
+// NewImporter returns an Importer writing to db.
+func NewImporter(db *gorm.DB, opts ImportOptions) *Importer {
+	if opts.InputDir == "" {
+		opts.InputDir = "exported"
+	}
+	if opts.IfExists == "" {
+		opts.IfExists = database.IfExistsFail
+	}
+	if opts.Checkpoint == "" {
+		opts.Checkpoint = filepath.Join(opts.InputDir, ".import_checkpoint.json")
+	}
+	return &Importer{db: db, opts: opts}
+}
+
+// ifExists is the IfExists mode of tbl.
+func (i *Importer) ifExists(tbl string) string {
+	if mode := i.opts.table(tbl).IfExists; mode != "" {
+		return mode
+	}
+	return i.opts.IfExists
+}
+
+// Import imports the selected tables and objects while holding the database
+// lock. The schemas of all tables are imported before any data so data files
+// can reference each other, and a table failing on a foreign key to one not
+// imported yet is retried after the others. Extensions, types and domains
+// come before the tables, and the other objects last, so triggers do not
+// fire on the imported rows; both are retried the same way. Tables that fail
+// are reported in the result and the error is then non-nil as well; the
+// checkpoint allows
+// resuming. Cancelling ctx stops the import after saving the progress of the
+// current table.
+func (i *Importer) Import(ctx context.Context) (*ImportResult, error) {
+	opts := i.opts
+	logf := opts.Logf
+	db := i.db.WithContext(ctx)
+
+	if opts.SchemaOnly && opts.DataOnly {
+		return nil, fmt.Errorf("schema-only and data-only cannot be used together")
+	}
+	if !database.ValidIfExists(opts.IfExists) {
+		return nil, fmt.Errorf("invalid if-exists value %q (expected fail, skip, truncate or drop)", opts.IfExists)
+	}
+	for tbl, settings := range opts.Settings {
+		if settings.IfExists != "" && !database.ValidIfExists(settings.IfExists) {
+			return nil, fmt.Errorf("invalid if_exists value %q for table %s in the config", settings.IfExists, tbl)
+		}
+	}
+	if opts.LockTimeout < 0 {
+		return nil, fmt.Errorf("lock timeout must not be negative")
+	}
+	types, err := opts.objectTypes()
+	if err != nil {
+		return nil, err
+	}
+	withSchema, withData := !opts.DataOnly, !opts.SchemaOnly
+
+	lock, err := database.AcquireLock(db, database.LockName, opts.LockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock import database: %w", err)
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			logf.printf("Failed to release lock on import database: %v\n", err)
+		}
+	}()
+
+	allTables, err := ImportTables(opts.InputDir, withSchema, withData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table names from input directory: %w", err)
+	}
+	var tables []string
+	if types[database.ObjectTables] {
+		if tables, err = opts.selectTables(allTables, logf); err != nil {
+			return nil, err
+		}
+	}
+	var objects []database.SchemaObject
+	if withSchema {
+		allObjects, err := ImportObjects(opts.InputDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get objects from input directory: %w", err)
+		}
+		objects = opts.selectObjects(allObjects, types)
+		tables = withoutObjects(tables, objects)
+	}
+	if len(tables) == 0 && len(objects) == 0 {
+		return nil, fmt.Errorf("no SQL files of the selected tables found in the input directory %s", opts.InputDir)
+	}
+	if len(tables) > 0 {
+		logf.printf("Importing tables: %s\n", strings.Join(tables, ", "))
+	}
+
+	// Load previous progress when resuming, otherwise start from scratch
+	checkpoint := database.NewCheckpoint(opts.Checkpoint)
+	if opts.Resume {
+		checkpoint, err = database.LoadCheckpoint(opts.Checkpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		logf.printf("Resuming import from checkpoint %s\n", opts.Checkpoint)
+	} else if err := checkpoint.Remove(); err != nil {
+		return nil, fmt.Errorf("failed to remove stale checkpoint: %w", err)
+	}
+
+	result := &ImportResult{Tables: make([]TableResult, len(tables))}
+	for n, tbl := range tables {
+		result.Tables[n].Table = tbl
+	}
+	for _, f := range groupObjects(objects) {
+		result.Objects = append(result.Objects, TableResult{Table: f.name, Kind: f.kind})
+	}
+
+	// Existing tables are emptied or dropped before anything is loaded
+	if err := i.prepareTables(db, result.Tables, checkpoint, withSchema, withData); err != nil {
+		return result, err
+	}
+
+	// Extensions, types and domains sort first, and go before the tables
+	prelude := 0
+	for prelude < len(result.Objects) && database.BeforeTables(result.Objects[prelude].Kind) {
+		prelude++
+	}
+	if err := i.importObjects(ctx, db, result, result.Objects[:prelude], checkpoint); err != nil {
+		return result, err
+	}
+
+	// Create every table first so data files can reference each other
+	if withSchema {
+		err := i.retryFailed(ctx, result.Tables, "schema", func(table *TableResult) error {
+			if err := i.importSchema(db, table, checkpoint); err != nil {
+				return err
+			}
+			if table.SchemaFile != "" && table.Err == nil && !table.Skipped {
+				result.SchemasImported++
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				result.Checkpoint = opts.Checkpoint
+			}
+			return result, err
+		}
+	}
+
+	if withData {
+		err := i.retryFailed(ctx, result.Tables, "data", func(table *TableResult) error {
+			imported, err := i.importData(ctx, db, table, checkpoint)
+			if err != nil {
+				return err
+			}
+			if imported {
+				result.DataImported++
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				result.Checkpoint = opts.Checkpoint
+			}
+			return result, err
+		}
+	}
+
+	// The other objects once the tables hold their data
+	if err := i.importObjects(ctx, db, result, result.Objects[prelude:], checkpoint); err != nil {
+		return result, err
+	}
+
+	logf.printf("Import finished: %d schema(s), %d data file(s) and %d object file(s) imported, %d skipped, %d failure(s)\n",
+		result.SchemasImported, result.DataImported, result.ObjectsImported, result.Skipped(), result.Failed())
+	if n := result.Failed(); n > 0 {
+		result.Checkpoint = opts.Checkpoint
+		logf.printf("Progress saved to %s, rerun with --resume to continue\n", opts.Checkpoint)
+		return result, fmt.Errorf("%d import(s) failed", n)
+	}
+	if err := checkpoint.Remove(); err != nil {
+		logf.printf("Failed to remove checkpoint %s: %v\n", opts.Checkpoint, err)
+	}
+	// A resumed import whose checkpoint already covered everything is done
+	if result.SchemasImported+result.DataImported+result.ObjectsImported == 0 && !(opts.Resume && result.Skipped() > 0) {
+		return result, fmt.Errorf("nothing was imported")
+	}
+	return result, nil
+}
+
+// importObjects imports the object files of objects, which are part of
+// result.Objects. The error is fatal to the import.
+func (i *Importer) importObjects(ctx context.Context, db *gorm.DB, result *ImportResult, objects []TableResult, checkpoint *database.Checkpoint) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	var names []string
+	for n := range objects {
+		names = append(names, objects[n].name())
+	}
+	i.opts.Logf.printf("Importing objects: %s\n", strings.Join(names, ", "))
+	// Listed now, as dropping tables may have dropped their objects
+	existing, err := database.ListObjects(db, nil, true)
+	if err != nil {
+		return fmt.Errorf("failed to list the objects of the import database: %w", err)
+	}
+	err = i.retryFailed(ctx, objects, "definition", func(object *TableResult) error {
+		if err := i.importObject(db, object, existing, checkpoint); err != nil {
+			return err
+		}
+		if object.Err == nil && !object.Skipped {
+			result.ObjectsImported++
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() != nil {
+		result.Checkpoint = i.opts.Checkpoint
+	}
+	return err
+}
+
+// retryFailed runs step on every table, then again on the tables it failed
+// for as long as each round gets further. Databases that check foreign keys
+// when a table is created or a row inserted, such as DuckDB, then accept
+// tables in any order. The returned error is fatal to the import.
+func (i *Importer) retryFailed(ctx context.Context, tables []TableResult, what string, step func(*TableResult) error) error {
+	pending := make([]int, len(tables))
+	for n := range tables {
+		pending[n] = n
+	}
+	for round := 0; len(pending) > 0; round++ {
+		var failed []int
+		for _, n := range pending {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// Tables that failed an earlier step keep that error
+			retry := tables[n].Err == nil
+			if round > 0 {
+				i.opts.Logf.printf("Retrying %s of %s\n", what, tables[n].name())
+				tables[n].Err = nil
+			}
+			if err := step(&tables[n]); err != nil {
+				return err
+			}
+			if retry && tables[n].Err != nil {
+				failed = append(failed, n)
+			}
+		}
+		if len(failed) == len(pending) {
+			return nil
+		}
+		pending = failed
+	}
+	return nil
+}
+
+// prepareTables truncates or drops the existing tables of the import whose
+// IfExists mode asks for it, all at once and children first. Without a
+// schema file to recreate a table from, drop behaves like truncate. Tables
+// with progress in the checkpoint hold what an earlier run imported and are
+// left alone. The error is fatal to the import.
+func (i *Importer) prepareTables(db *gorm.DB, tables []TableResult, checkpoint *database.Checkpoint, withSchema, withData bool) error {
+	var truncate, drop []string
+	for _, table := range tables {
+		tbl := table.Table
+		mode := i.ifExists(tbl)
+		if mode != database.IfExistsTruncate && mode != database.IfExistsDrop {
+			continue
+		}
+		progress := checkpoint.Table(tbl)
+		if progress.SchemaDone || progress.DataDone || progress.Statements > 0 {
+			continue
+		}
+		_, schemaErr := os.Stat(filepath.Join(i.opts.InputDir, tbl+SchemaFileSuffix))
+		_, dataErr := os.Stat(filepath.Join(i.opts.InputDir, tbl+DataFileSuffix))
+		hasSchema, hasData := withSchema && schemaErr == nil, withData && dataErr == nil
+		switch {
+		case hasSchema && mode == database.IfExistsDrop:
+			drop = append(drop, tbl)
+		case hasSchema || hasData:
+			truncate = append(truncate, tbl)
+		}
+	}
+	if err := database.PrepareTables(db, truncate, drop, i.opts.Logf); err != nil {
+		return fmt.Errorf("failed to prepare import tables: %w", err)
+	}
+	return nil
+}
+
+// importSchema imports the schema file of table.Table, if any. Table errors
+// are recorded in table; the returned error is fatal to the import.
+func (i *Importer) importSchema(db *gorm.DB, table *TableResult, checkpoint *database.Checkpoint) error {
+	logf := i.opts.Logf
+	tbl := table.Table
+	schemaFile := filepath.Join(i.opts.InputDir, tbl+SchemaFileSuffix)
+	if _, err := os.Stat(schemaFile); err != nil {
+		return nil
+	}
+	table.SchemaFile = schemaFile
+
+	progress := checkpoint.Table(tbl)
+	if progress.SchemaDone {
+		logf.printf("Schema for table %s already imported, skipping\n", tbl)
+		table.Skipped = true
+		return nil
+	}
+	ok, err := database.PrepareSchemaImport(db, tbl, i.ifExists(tbl), logf)
+	if err != nil {
+		logf.printf("Failed to prepare table %s: %v\n", tbl, err)
+		table.fail(fmt.Errorf("prepare %s: %w", tbl, err))
+		return nil
+	}
+	if !ok {
+		table.Skipped = true
+		return nil
+	}
+	logf.printf("Importing schema from %s\n", schemaFile)
+	if err := database.ImportSQLFile(db, schemaFile); err != nil {
+		logf.printf("Failed to import schema for table %s: %v\n", tbl, err)
+		table.fail(fmt.Errorf("schema of %s: %w", tbl, err))
+		return nil
+	}
+	logf.printf("Schema imported for table %s\n", tbl)
+	progress.SchemaDone = true
+	if err := checkpoint.Save(); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// importData imports the data file of table.Table, if any, and reports
+// whether it did. Table errors are recorded in table; the returned error is
+// fatal to the import.
+func (i *Importer) importData(ctx context.Context, db *gorm.DB, table *TableResult, checkpoint *database.Checkpoint) (bool, error) {
+	logf := i.opts.Logf
+	tbl := table.Table
+	dataFile := filepath.Join(i.opts.InputDir, tbl+DataFileSuffix)
+	if _, err := os.Stat(dataFile); err != nil {
+		return false, nil
+	}
+	table.DataFile = dataFile
+
+	progress := checkpoint.Table(tbl)
+	if progress.DataDone {
+		logf.printf("Data for table %s already imported, skipping\n", tbl)
+		table.Skipped = true
+		table.Statements = progress.Statements
+		return false, nil
+	}
+
+	// A partially loaded table holds our own rows, so it must not be
+	// prepared (and possibly truncated) again.
+	if progress.Statements == 0 {
+		ok, err := database.PrepareDataImport(db, tbl, i.ifExists(tbl), logf)
+		if err != nil {
+			logf.printf("Failed to prepare table %s: %v\n", tbl, err)
+			table.fail(fmt.Errorf("prepare %s: %w", tbl, err))
+			return false, nil
+		}
+		if !ok {
+			table.Skipped = true
+			return false, nil
+		}
+		logf.printf("Importing data from %s\n", dataFile)
+	} else {
+		logf.printf("Resuming data import from %s after statement %d\n", dataFile, progress.Statements)
+	}
+
+	_, err := database.ImportSQLStatements(db, dataFile, progress.Statements, func(done int) error {
+		progress.Statements = done
+		if err := checkpoint.Save(); err != nil {
+			return err
+		}
+		return ctx.Err()
+	})
+	table.Statements = progress.Statements
+	if err != nil {
+		logf.printf("Failed to import data for table %s: %v\n", tbl, err)
+		table.fail(fmt.Errorf("data of %s: %w", tbl, err))
+		return false, nil
+	}
+	logf.printf("Data imported for table %s\n", tbl)
+	if !i.opts.SkipSequenceReset {
+		if err := database.ResetSequences(db, tbl); err != nil {
+			logf.printf("Failed to reset sequences for table %s: %v\n", tbl, err)
+		}
+	}
+	progress.DataDone = true
+	if err := checkpoint.Save(); err != nil {
+		return true, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return true, nil
+}
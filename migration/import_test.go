@@ -0,0 +1,132 @@
+package migration
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/semay-cli/sql-migration/database"
+	"gorm.io/gorm"
+)
+
+// blogSchema creates a posts table and a comments table referencing it.
+var blogSchema = []string{
+	`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT NOT NULL)`,
+	`CREATE TABLE comments (id INTEGER PRIMARY KEY, post_id INTEGER NOT NULL REFERENCES posts (id), body TEXT)`,
+}
+
+// exportBlog exports a blog database of driver to a new directory.
+func exportBlog(t *testing.T, driver, dsn string) string {
+	t.Helper()
+	source := openTestDB(t, driver, dsn, append(blogSchema,
+		`INSERT INTO posts VALUES (1, 'first'), (2, 'second')`,
+		`INSERT INTO comments VALUES (1, 1, 'nice'), (2, 2, 'agreed')`,
+	)...)
+	dir, _ := exportFiles(t, source)
+	return dir
+}
+
+func TestImportIfExists(t *testing.T) {
+	tmp := t.TempDir()
+	dir := exportBlog(t, "sqlite", "file:"+filepath.Join(tmp, "source.db")+"?_foreign_keys=1")
+	sourceRows := [][]string{{"1", "first"}, {"2", "second"}}
+	sourceComments := [][]string{{"1", "1", "nice"}, {"2", "2", "agreed"}}
+	oldRows := [][]string{{"1", "old one"}, {"2", "old two"}, {"3", "old three"}}
+
+	tests := []struct {
+		mode       string
+		dataOnly   bool
+		wantErr    string
+		wantPosts  [][]string
+		wantLegacy bool
+	}{
+		{mode: database.IfExistsFail, wantErr: "already exists", wantPosts: oldRows, wantLegacy: true},
+		{mode: database.IfExistsSkip, wantPosts: oldRows, wantLegacy: true},
+		{mode: database.IfExistsTruncate, wantPosts: sourceRows, wantLegacy: true},
+		{mode: database.IfExistsDrop, wantPosts: sourceRows},
+		{mode: database.IfExistsDrop, dataOnly: true, wantPosts: sourceRows, wantLegacy: true},
+	}
+	for _, tt := range tests {
+		name := tt.mode
+		if tt.dataOnly {
+			name += " data only"
+		}
+		t.Run(name, func(t *testing.T) {
+			// The target posts table has an extra column and rows of its
+			// own; its comments table is empty
+			target := openTestDB(t, "sqlite", "file:"+filepath.Join(tmp, name+".db")+"?_foreign_keys=1",
+				`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT NOT NULL, legacy TEXT)`,
+				blogSchema[1],
+				`INSERT INTO posts (id, title) VALUES (1, 'old one'), (2, 'old two'), (3, 'old three')`,
+			)
+			opts := ImportOptions{InputDir: dir, IfExists: tt.mode, DataOnly: tt.dataOnly,
+				Checkpoint: filepath.Join(tmp, name+".json")}
+			result, err := NewImporter(target, opts).Import(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(result.Tables[1].Err.Error(), tt.wantErr) {
+					t.Fatalf("import error = %v, want posts failing with %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := queryStrings(t, target, `SELECT id, title FROM posts ORDER BY id`); !reflect.DeepEqual(got, tt.wantPosts) {
+				t.Errorf("posts = %q, want %q", got, tt.wantPosts)
+			}
+			// The empty comments table is loaded in every mode
+			if got := queryStrings(t, target, `SELECT id, post_id, body FROM comments ORDER BY id`); !reflect.DeepEqual(got, sourceComments) {
+				t.Errorf("comments = %q, want %q", got, sourceComments)
+			}
+			if legacy := hasColumn(t, target, "posts", "legacy"); legacy != tt.wantLegacy {
+				t.Errorf("legacy column kept = %v, want %v", legacy, tt.wantLegacy)
+			}
+		})
+	}
+}
+
+// TestImportIfExistsReferenceOrder imports into DuckDB, which always checks
+// foreign keys: the comments already imported must survive posts being
+// prepared, whatever order the tables are imported in.
+func TestImportIfExistsReferenceOrder(t *testing.T) {
+	dir := exportBlog(t, "duckdb", "")
+	for _, tt := range []struct {
+		mode     string
+		dataOnly bool
+	}{
+		{database.IfExistsTruncate, true},
+		{database.IfExistsTruncate, false},
+		{database.IfExistsDrop, false},
+	} {
+		name := tt.mode
+		if tt.dataOnly {
+			name += " data only"
+		}
+		t.Run(name, func(t *testing.T) {
+			target := openTestDB(t, "duckdb", "", append(blogSchema,
+				`INSERT INTO posts VALUES (1, 'old one'), (3, 'old three')`,
+				`INSERT INTO comments VALUES (7, 3, 'old')`,
+			)...)
+			opts := ImportOptions{InputDir: dir, IfExists: tt.mode, DataOnly: tt.dataOnly,
+				Checkpoint: filepath.Join(t.TempDir(), "checkpoint.json")}
+			if _, err := NewImporter(target, opts).Import(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got := queryStrings(t, target, `SELECT c.id, p.title, c.body FROM comments c JOIN posts p ON p.id = c.post_id ORDER BY c.id`)
+			if want := [][]string{{"1", "first", "nice"}, {"2", "second", "agreed"}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("imported comments = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// hasColumn reports whether tableName has a column called column.
+func hasColumn(t *testing.T, db *gorm.DB, tableName, column string) bool {
+	t.Helper()
+	table, err := database.IntrospectTable(db, tableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table.Column(column) != nil
+}
@@ -0,0 +1,180 @@
+// Package migration exports tables of a database to SQL files and imports
+// them into another, for programs that embed sql-migration instead of
+// running the CLI. Exporter and Importer work on an open *gorm.DB, such as
+// one returned by database.ReturnSession, and report what they did per table.
+package migration
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/semay-cli/sql-migration/config"
+	"github.com/semay-cli/sql-migration/database"
+)
+
+// Logf receives progress messages. A nil Logf discards them.
+type Logf func(format string, args ...any)
+
+func (l Logf) printf(format string, args ...any) {
+	if l != nil {
+		l(format, args...)
+	}
+}
+
+// Selection narrows the tables an export or import works on.
+type Selection struct {
+	// Tables lists the tables explicitly. It cannot be combined with Include
+	// or Exclude.
+	Tables []string
+	// Include and Exclude filter the tables with globs, or regular
+	// expressions prefixed with "re:". Exclusions win.
+	Include []string
+	Exclude []string
+	// Settings holds per-table settings; tables with Skip set are left out
+	// unless listed in Tables.
+	Settings map[string]config.TableConfig
+	// Objects lists the object types to work on (database.ObjectTables,
+	// ObjectViews and so on), every type by default. Views and routines are
+	// selected by name like tables, indexes and triggers with their table;
+	// extensions, types and domains are kept whatever the tables.
+	Objects []string
+}
+
+// table returns the settings of tbl.
+func (s Selection) table(tbl string) config.TableConfig {
+	return s.Settings[tbl]
+}
+
+// selectTables returns the tables of all the selection keeps.
+func (s Selection) selectTables(all []string, logf Logf) ([]string, error) {
+	if len(s.Tables) > 0 {
+		if len(s.Include) > 0 || len(s.Exclude) > 0 {
+			return nil, fmt.Errorf("an explicit table list cannot be combined with include or exclude patterns")
+		}
+		return s.Tables, nil
+	}
+
+	filter, err := database.NewTableFilter(s.Include, s.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	var kept []string
+	for _, tbl := range filter.Filter(all) {
+		if s.table(tbl).Skip {
+			logf.printf("Skipping table %s (skip is set in the config)\n", tbl)
+			continue
+		}
+		kept = append(kept, tbl)
+	}
+	return kept, nil
+}
+
+// TableResult reports what happened to one table, or to the objects of one
+// kind and name.
+type TableResult struct {
+	Table string
+	// Kind is the kind of database.SchemaObject of an object result, whose
+	// Table is the object name. It is empty for tables.
+	Kind string
+	// SchemaFile and DataFile are the files written or read, empty when the
+	// schema or data of the table was not part of the run.
+	SchemaFile string
+	DataFile   string
+	// Rows counts the exported rows, Statements the imported data statements.
+	Rows       int64
+	Statements int
+	// Skipped is set when the table was left alone, because an earlier run
+	// already completed it or the target table existed with if_exists skip.
+	Skipped bool
+	// Err is the first error the table ran into.
+	Err error
+}
+
+// name describes the table or object in messages.
+func (t *TableResult) name() string {
+	if t.Kind != "" {
+		return t.Kind + " " + t.Table
+	}
+	return "table " + t.Table
+}
+
+// fail records err unless the table already failed.
+func (t *TableResult) fail(err error) {
+	if t.Err == nil {
+		t.Err = err
+	}
+}
+
+// failed counts the results holding an error.
+func failed(results ...[]TableResult) int {
+	n := 0
+	for _, tables := range results {
+		for _, t := range tables {
+			if t.Err != nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// sqlFileTables scans dir for files ending in suffix and returns the table
+// names they belong to.
+func sqlFileTables(dir string, suffix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if tableName := strings.TrimSuffix(entry.Name(), suffix); tableName != entry.Name() && tableName != "" {
+			tables = append(tables, tableName)
+		}
+	}
+	return tables, nil
+}
+
+// ImportTables returns the sorted union of the tables that have a schema
+// file and/or a data file in dir, limited to the kinds being imported.
+func ImportTables(dir string, withSchema, withData bool) ([]string, error) {
+	var suffixes []string
+	if withSchema {
+		suffixes = append(suffixes, SchemaFileSuffix)
+	}
+	if withData {
+		suffixes = append(suffixes, DataFileSuffix)
+	}
+
+	seen := make(map[string]bool)
+	var tables []string
+	for _, suffix := range suffixes {
+		found, err := sqlFileTables(dir, suffix)
+		if err != nil {
+			return nil, err
+		}
+		for _, tbl := range found {
+			if !seen[tbl] {
+				seen[tbl] = true
+				tables = append(tables, tbl)
+			}
+		}
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// File name suffixes of the schema and data files of a table.
+const (
+	SchemaFileSuffix = "_schema.sql"
+	DataFileSuffix   = "_data.sql"
+)
+
+// ForeignKeysFile holds the foreign keys of an export for a target dialect
+// that adds them with ALTER TABLE, to run after the schema and data files.
+const ForeignKeysFile = "foreign_keys.sql"
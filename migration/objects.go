@@ -0,0 +1,286 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/semay-cli/sql-migration/database"
+	"gorm.io/gorm"
+)
+
+// Views, triggers, routines and standalone indexes are exported to
+// <name>_<kind>.sql files, such as active_users_view.sql and
+// orders_audit_trigger.sql, one per name: overloaded PostgreSQL routines, or
+// triggers of the same name on several tables, share a file. They are
+// imported after the tables and their data, in the order of
+// database.SortObjects. Extensions, types and domains are exported the same
+// way, as pgcrypto_extension.sql or mood_type.sql, and imported before the
+// tables.
+
+// ObjectFileSuffix returns the file name suffix of objects of kind, such as
+// "_materialized_view.sql".
+func ObjectFileSuffix(kind string) string {
+	return "_" + strings.ReplaceAll(kind, " ", "_") + ".sql"
+}
+
+// objectTypes returns the object types the selection keeps, every type by
+// default.
+func (s Selection) objectTypes() (map[string]bool, error) {
+	if err := database.ValidObjectTypes(s.Objects); err != nil {
+		return nil, err
+	}
+	types := s.Objects
+	if len(types) == 0 {
+		types = database.ObjectTypes
+	}
+	kept := map[string]bool{}
+	for _, t := range types {
+		kept[t] = true
+	}
+	return kept, nil
+}
+
+// selectObjects returns the objects of all the selection keeps: indexes and
+// triggers of the tables it keeps, views and routines it keeps by name, as
+// if they were tables, and every extension, type and domain, as the tables
+// may use them.
+func (s Selection) selectObjects(all []database.SchemaObject, types map[string]bool) []database.SchemaObject {
+	var names, tables []string
+	for _, obj := range all {
+		if obj.Table != "" {
+			tables = append(tables, obj.Table)
+		} else {
+			names = append(names, obj.Name)
+		}
+	}
+	keptNames := s.keeps(names)
+	keptTables := s.keeps(tables)
+
+	var kept []database.SchemaObject
+	for _, obj := range all {
+		if !types[database.ObjectType(obj.Kind)] {
+			continue
+		}
+		if database.BeforeTables(obj.Kind) || (obj.Table != "" && keptTables[obj.Table]) || (obj.Table == "" && keptNames[obj.Name]) {
+			kept = append(kept, obj)
+		}
+	}
+	return kept
+}
+
+// withoutObjects drops the names of views and routines in objects from an
+// explicit table list, which may name them.
+func withoutObjects(tables []string, objects []database.SchemaObject) []string {
+	named := map[string]bool{}
+	for _, obj := range objects {
+		if obj.Table == "" {
+			named[obj.Name] = true
+		}
+	}
+	var kept []string
+	for _, tbl := range tables {
+		if !named[tbl] {
+			kept = append(kept, tbl)
+		}
+	}
+	return kept
+}
+
+// keeps returns which of names the selection keeps, quietly.
+func (s Selection) keeps(names []string) map[string]bool {
+	kept := map[string]bool{}
+	selected, err := s.selectTables(names, nil)
+	if err != nil {
+		return kept
+	}
+	for _, name := range selected {
+		kept[name] = true
+	}
+	return kept
+}
+
+// objectFile is the file of the objects of one kind and name.
+type objectFile struct {
+	kind, name string
+	objects    []database.SchemaObject
+}
+
+// groupObjects groups objects by file, in the order their first object
+// comes in.
+func groupObjects(objects []database.SchemaObject) []*objectFile {
+	var files []*objectFile
+	byName := map[string]*objectFile{}
+	for _, obj := range objects {
+		key := obj.Kind + "\x00" + obj.Name
+		f, ok := byName[key]
+		if !ok {
+			f = &objectFile{kind: obj.Kind, name: obj.Name}
+			byName[key] = f
+			files = append(files, f)
+		}
+		f.objects = append(f.objects, obj)
+	}
+	return files
+}
+
+// exportObjects writes the object files not yet in checkpoint. Object errors
+// are recorded in the results; the returned error is fatal to the export.
+func (e *Exporter) exportObjects(ctx context.Context, objects []database.SchemaObject, checkpoint *database.Checkpoint) ([]TableResult, error) {
+	logf := e.opts.Logf
+	var results []TableResult
+	for _, f := range groupObjects(objects) {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		file := f.name + ObjectFileSuffix(f.kind)
+		result := TableResult{Table: f.name, Kind: f.kind, SchemaFile: filepath.Join(e.opts.OutputDir, file)}
+		progress := checkpoint.Table(file)
+		if progress.SchemaDone {
+			result.Skipped = true
+		} else if err := database.ExportObjectsSQL(e.db, f.objects, result.SchemaFile); err != nil {
+			logf.printf("Failed to export %s %s: %v\n", f.kind, f.name, err)
+			result.fail(fmt.Errorf("%s %s: %w", f.kind, f.name, err))
+		} else {
+			logf.printf("%s %s exported to %s\n", capitalize(f.kind), f.name, result.SchemaFile)
+			progress.SchemaDone = true
+			if err := checkpoint.Save(); err != nil {
+				return append(results, result), fmt.Errorf("failed to save checkpoint: %w", err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// describeObjects lists objects by kind and name.
+func describeObjects(objects []database.SchemaObject) string {
+	var names []string
+	for _, f := range groupObjects(objects) {
+		names = append(names, f.kind+" "+f.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// ImportObjects returns the objects of the object files in dir, ordered for
+// import. Each holds the content of its file as definition. The table of an
+// index or trigger is the one its definition is created on, in the schema
+// of the object unless the definition names one.
+func ImportObjects(dir string) ([]database.SchemaObject, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Longer suffixes first, so _materialized_view.sql is not read as _view.sql
+	kinds := database.ObjectKinds()
+	sort.SliceStable(kinds, func(i, j int) bool {
+		return len(ObjectFileSuffix(kinds[i])) > len(ObjectFileSuffix(kinds[j]))
+	})
+
+	var objects []database.SchemaObject
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, kind := range kinds {
+			name := strings.TrimSuffix(entry.Name(), ObjectFileSuffix(kind))
+			if name == entry.Name() || name == "" {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			obj := database.SchemaObject{Kind: kind, Name: name, Definition: string(content)}
+			if database.ObjectType(kind) == database.ObjectIndexes || database.ObjectType(kind) == database.ObjectTriggers {
+				obj.Table = database.ObjectTable(obj.Definition)
+				if schema, _ := database.SplitTableName(name); schema != "" {
+					if tableSchema, table := database.SplitTableName(obj.Table); tableSchema == "" {
+						obj.Table = database.QualifyTableName(schema, table)
+					}
+				}
+			}
+			objects = append(objects, obj)
+			break
+		}
+	}
+	return database.SortObjects(objects), nil
+}
+
+// importObject imports the object file of object, first dropping the objects
+// of the same kind and name in existing when IfExists allows. Existing
+// extensions, types and domains are never dropped, as tables may use them,
+// but kept unless IfExists is fail; existing extensions are always kept, as
+// they are created IF NOT EXISTS. Object errors are recorded in object; the
+// returned error is fatal to the import.
+func (i *Importer) importObject(db *gorm.DB, object *TableResult, existing []database.SchemaObject, checkpoint *database.Checkpoint) error {
+	logf := i.opts.Logf
+	file := object.Table + ObjectFileSuffix(object.Kind)
+	object.SchemaFile = filepath.Join(i.opts.InputDir, file)
+
+	progress := checkpoint.Table(file)
+	if progress.SchemaDone {
+		logf.printf("%s %s already imported, skipping\n", capitalize(object.Kind), object.Table)
+		object.Skipped = true
+		return nil
+	}
+
+	var found []database.SchemaObject
+	for _, obj := range existing {
+		if obj.Kind == object.Kind && obj.Name == object.Table {
+			found = append(found, obj)
+		}
+	}
+	if len(found) > 0 {
+		mode := i.ifExists(object.Table)
+		if object.Kind == database.KindExtension || (database.BeforeTables(object.Kind) && mode != database.IfExistsFail) {
+			mode = database.IfExistsSkip
+		}
+		switch mode {
+		case database.IfExistsSkip:
+			logf.printf("%s %s already exists, skipping\n", capitalize(object.Kind), object.Table)
+			object.Skipped = true
+			return nil
+		case database.IfExistsTruncate, database.IfExistsDrop:
+			for _, obj := range found {
+				stmt, err := database.DropObjectSQL(db, obj)
+				if err == nil {
+					err = db.Exec(stmt).Error
+				}
+				if err != nil {
+					logf.printf("Failed to drop %s %s: %v\n", object.Kind, object.Table, err)
+					object.fail(fmt.Errorf("drop %s %s: %w", object.Kind, object.Table, err))
+					return nil
+				}
+			}
+		default:
+			object.fail(fmt.Errorf("%s %s already exists (use --if-exists skip or drop)", object.Kind, object.Table))
+			logf.printf("Failed to import %s %s: %v\n", object.Kind, object.Table, object.Err)
+			return nil
+		}
+	}
+
+	logf.printf("Importing %s from %s\n", object.Kind, object.SchemaFile)
+	if err := database.ImportSQLFile(db, object.SchemaFile); err != nil {
+		logf.printf("Failed to import %s %s: %v\n", object.Kind, object.Table, err)
+		object.fail(fmt.Errorf("%s %s: %w", object.Kind, object.Table, err))
+		return nil
+	}
+	logf.printf("%s %s imported\n", capitalize(object.Kind), object.Table)
+	progress.SchemaDone = true
+	if err := checkpoint.Save(); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
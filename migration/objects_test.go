@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semay-cli/sql-migration/database"
+)
+
+func TestImportObjects(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sales_totals_materialized_view.sql": "CREATE MATERIALIZED VIEW sales_totals AS SELECT * FROM recent_orders",
+		"recent_orders_view.sql":             "CREATE VIEW recent_orders AS SELECT * FROM orders",
+		"orders_audit_trigger.sql":           "CREATE TRIGGER orders_audit AFTER INSERT ON orders BEGIN SELECT 1; END",
+		"app.idx_lines_index.sql":            "CREATE INDEX idx_lines ON lines (order_id)",
+		"idx_items_index.sql":                "CREATE INDEX idx_items ON other.items (sku)",
+		"mood_type.sql":                      "CREATE TYPE mood AS ENUM ('ok')",
+		"orders_schema.sql":                  "CREATE TABLE orders (id int)",
+		"orders_data.sql":                    "INSERT INTO orders VALUES (1)",
+		"_view.sql":                          "CREATE VIEW x AS SELECT 1",
+		"notes.txt":                          "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objects, err := ImportObjects(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []database.SchemaObject{
+		{Kind: database.KindType, Name: "mood"},
+		{Kind: database.KindView, Name: "recent_orders"},
+		{Kind: database.KindMaterializedView, Name: "sales_totals"},
+		{Kind: database.KindIndex, Name: "app.idx_lines", Table: "app.lines"},
+		{Kind: database.KindIndex, Name: "idx_items", Table: "other.items"},
+		{Kind: database.KindTrigger, Name: "orders_audit", Table: "orders"},
+	}
+	if len(objects) != len(want) {
+		t.Fatalf("ImportObjects returned %d objects, want %d: %+v", len(objects), len(want), objects)
+	}
+	for i, obj := range objects {
+		if obj.Kind != want[i].Kind || obj.Name != want[i].Name || obj.Table != want[i].Table {
+			t.Errorf("object %d = %s %s on %q, want %s %s on %q", i, obj.Kind, obj.Name, obj.Table, want[i].Kind, want[i].Name, want[i].Table)
+		}
+		file := obj.Name + ObjectFileSuffix(obj.Kind)
+		if obj.Definition != files[file] {
+			t.Errorf("%s: definition %q, want the content of %s", obj.Name, obj.Definition, file)
+		}
+	}
+}
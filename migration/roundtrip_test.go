@@ -0,0 +1,219 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/semay-cli/sql-migration/database"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens a database of driver and runs stmts on it.
+func openTestDB(t *testing.T, driver, dsn string, stmts ...string) *gorm.DB {
+	t.Helper()
+	d, err := database.LookupDialect(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := d.Open(dsn, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+// exportFiles exports every table of db to a new directory and returns the
+// directory and the contents of the files written, keyed by name.
+func exportFiles(t *testing.T, db *gorm.DB) (string, map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := NewExporter(db, ExportOptions{OutputDir: dir, Sequences: true}).Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Base(path)] = string(content)
+	}
+	return dir, files
+}
+
+// queryStrings returns the rows of query with every value formatted.
+func queryStrings(t *testing.T, db *gorm.DB, query string) [][]string {
+	t.Helper()
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, _ := rows.Columns()
+	var result [][]string
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatal(err)
+		}
+		row := make([]string, len(values))
+		for i, val := range values {
+			row[i] = fmt.Sprintf("%v", val)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// testRoundTrip exports source, imports the files into target, and checks
+// that query returns the same rows from both databases and that exporting
+// target writes the same files again. The lock table the import leaves in
+// target must be neither exported nor still hold the lock.
+func testRoundTrip(t *testing.T, source, target *gorm.DB, queries ...string) {
+	t.Helper()
+	dir, exported := exportFiles(t, source)
+	if len(exported) == 0 {
+		t.Fatal("nothing exported")
+	}
+
+	result, err := NewImporter(target, ImportOptions{InputDir: dir}).Import(context.Background())
+	if err != nil {
+		t.Fatalf("import: %v (result %+v)", err, result)
+	}
+	for _, query := range queries {
+		want := queryStrings(t, source, query)
+		if got := queryStrings(t, target, query); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s\nimported %q\nwant %q", query, got, want)
+		}
+	}
+
+	var held int64
+	if err := target.Table(database.SQLiteLockTable).Count(&held).Error; err != nil {
+		t.Fatalf("lock table: %v", err)
+	}
+	if held != 0 {
+		t.Errorf("%d lock row(s) left after the import", held)
+	}
+	// The lock can be taken again once released
+	lock, err := database.AcquireLock(target, database.LockName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, again := exportFiles(t, target)
+	if names, want := fileNames(again), fileNames(exported); !reflect.DeepEqual(names, want) {
+		t.Fatalf("re-exported files %q, want %q", names, want)
+	}
+	for name, content := range exported {
+		if again[name] != content {
+			t.Errorf("re-exported %s differs:\n%s\nwant\n%s", name, again[name], content)
+		}
+	}
+}
+
+func fileNames(files map[string]string) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TestDuckDBRoundTrip imports the export of an in-memory DuckDB database into
+// another, with values of the types only DuckDB has written as literals.
+func TestDuckDBRoundTrip(t *testing.T) {
+	source := openTestDB(t, "duckdb", "",
+		`CREATE TABLE devices (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL)`,
+		`CREATE TABLE readings (
+			id INTEGER PRIMARY KEY,
+			device_id INTEGER REFERENCES devices (id),
+			tags VARCHAR[],
+			samples INTEGER[][],
+			point STRUCT(x DOUBLE, label VARCHAR),
+			attrs MAP(VARCHAR, INTEGER),
+			total HUGEINT,
+			taken_at TIMESTAMP
+		)`,
+		`INSERT INTO devices VALUES (1, 'probe ''A'''), (2, 'probe B')`,
+		`INSERT INTO readings VALUES
+			(1, 1, ['a', 'b''c', NULL], [[1, 2], [], NULL], {'x': 1.5, 'label': 'it''s'}, MAP {'k': 1, 'q''t': NULL},
+				170141183460469231731687303715884105727, TIMESTAMP '2024-02-29 23:59:59.123456'),
+			(2, 2, [], [], {'x': -0.25, 'label': NULL}, MAP {}, -170141183460469231731687303715884105727, NULL),
+			(3, NULL, NULL, NULL, NULL, NULL, NULL, NULL)`,
+	)
+	target := openTestDB(t, "duckdb", "")
+	testRoundTrip(t, source, target,
+		`SELECT id, name FROM devices ORDER BY id`,
+		`SELECT id, device_id, CAST(tags AS VARCHAR), CAST(samples AS VARCHAR), CAST(point AS VARCHAR),
+			CAST(attrs AS VARCHAR), CAST(total AS VARCHAR), CAST(taken_at AS VARCHAR) FROM readings ORDER BY id`,
+		`SELECT typeof(tags), typeof(samples), typeof(point), typeof(attrs), typeof(total) FROM readings LIMIT 1`,
+	)
+}
+
+// TestSQLiteRoundTrip imports the export of a SQLite database into another
+// one. The source holds the lock table of an earlier import, which is not
+// exported.
+func TestSQLiteRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	source := openTestDB(t, "sqlite", "file:"+filepath.Join(dir, "source.db")+"?_foreign_keys=1",
+		`CREATE TABLE customers (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, avatar BLOB)`,
+		`CREATE TABLE orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id INTEGER NOT NULL REFERENCES customers (id),
+			total REAL CHECK (total >= 0),
+			note TEXT,
+			created_at DATETIME
+		)`,
+		`CREATE INDEX idx_orders_customer ON orders (customer_id)`,
+		`INSERT INTO customers (name, avatar) VALUES ('O''Brien', X'00ff10'), ('line
+break', NULL)`,
+		`INSERT INTO orders (customer_id, total, note, created_at) VALUES
+			(1, 12.5, 'semi; colon', '2024-02-29 23:59:59'), (2, 0, NULL, NULL), (1, 1e10, '-- not a comment', '2024-01-01 00:00:00')`,
+		`DELETE FROM orders WHERE id = 3`,
+	)
+	lock, err := database.AcquireLock(source, database.LockName, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	target := openTestDB(t, "sqlite", "file:"+filepath.Join(dir, "target.db")+"?_foreign_keys=1")
+	testRoundTrip(t, source, target,
+		`SELECT id, name, hex(avatar) FROM customers ORDER BY id`,
+		`SELECT id, customer_id, total, note, created_at FROM orders ORDER BY id`,
+		`SELECT name, seq FROM sqlite_sequence ORDER BY name`,
+	)
+}